@@ -0,0 +1,469 @@
+package paperless
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultBatchConcurrency int           = 4
+	defaultBatchTaskTimeout time.Duration = 30 * time.Minute
+	// prefix of manifest csv columns holding custom field values
+	manifestCustomFieldPrefix string = "custom_fields."
+)
+
+// ManifestEntry describes a file to upload. Tags, correspondent, document
+// type and custom fields are given by name (or ID).
+type ManifestEntry struct {
+	Path          string            `json:"path"`
+	Title         string            `json:"title,omitempty"`
+	Created       string            `json:"created,omitempty"`
+	Tags          []string          `json:"tags,omitempty"`
+	Correspondent string            `json:"correspondent,omitempty"`
+	DocumentType  string            `json:"document_type,omitempty"`
+	CustomFields  map[string]string `json:"custom_fields,omitempty"`
+}
+
+// LoadManifest reads a manifest as JSONL (one ManifestEntry per line) or, for
+// files ending in .csv, as CSV with the columns path, title, created, tags
+// (separated by ';'), correspondent, document_type and custom_fields.<name>.
+// Relative paths are resolved against the directory of the manifest.
+func LoadManifest(path string) ([]ManifestEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	defer file.Close()
+	var entries []ManifestEntry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = parseCSVManifest(file)
+	} else {
+		entries, err = parseJSONLManifest(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest '%s': %w", path, err)
+	}
+	base := filepath.Dir(path)
+	for idx := range entries {
+		if entries[idx].Path == "" {
+			return nil, fmt.Errorf("manifest entry %d without path", idx+1)
+		}
+		if !filepath.IsAbs(entries[idx].Path) {
+			entries[idx].Path = filepath.Join(base, entries[idx].Path)
+		}
+	}
+	return entries, nil
+}
+
+func parseJSONLManifest(r io.Reader) ([]ManifestEntry, error) {
+	entries := make([]ManifestEntry, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry ManifestEntry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func parseCSVManifest(r io.Reader) ([]ManifestEntry, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ManifestEntry, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := ManifestEntry{}
+		for idx, column := range header {
+			value := strings.TrimSpace(record[idx])
+			switch {
+			case column == "path":
+				entry.Path = value
+			case column == "title":
+				entry.Title = value
+			case column == "created":
+				entry.Created = value
+			case column == "tags":
+				for _, tag := range strings.Split(value, ";") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						entry.Tags = append(entry.Tags, tag)
+					}
+				}
+			case column == "correspondent":
+				entry.Correspondent = value
+			case column == "document_type":
+				entry.DocumentType = value
+			case strings.HasPrefix(column, manifestCustomFieldPrefix):
+				if value == "" {
+					continue
+				}
+				if entry.CustomFields == nil {
+					entry.CustomFields = make(map[string]string)
+				}
+				entry.CustomFields[strings.TrimPrefix(column, manifestCustomFieldPrefix)] = value
+			default:
+				return nil, fmt.Errorf("unknown column '%s'", column)
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+type BatchState string
+
+const (
+	// BatchUploading is journaled before the upload, a crash leaves it
+	// unknown whether paperless received the file.
+	BatchUploading BatchState = "uploading"
+	BatchUploaded  BatchState = "uploaded"
+	BatchDone      BatchState = "done"
+	BatchSkipped   BatchState = "skipped"
+	BatchFailed    BatchState = "failed"
+)
+
+// BatchResult is the state of one manifest entry, as journaled.
+type BatchResult struct {
+	Path       string     `json:"path"`
+	State      BatchState `json:"state"`
+	TaskID     string     `json:"task_id,omitempty"`
+	DocumentID int        `json:"document_id,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Time       time.Time  `json:"time"`
+}
+
+// Finished tells whether the entry needs no further work.
+func (r BatchResult) Finished() bool {
+	return r.State == BatchDone || r.State == BatchSkipped || r.State == BatchFailed
+}
+
+type BatchOptions struct {
+	// Concurrency of uploads, defaults to 4. Every upload is tracked until
+	// its consumption task finished.
+	Concurrency int
+	// JournalPath records progress, a run with the same journal resumes
+	// where a crashed run stopped.
+	JournalPath string
+	// SkipDuplicates skips files paperless has already, see FindByChecksum.
+	SkipDuplicates bool
+	// RetryFailed retries entries journaled as failed.
+	RetryFailed bool
+	// TaskTimeout limits the wait for a single consumption task, defaults
+	// to 30m.
+	TaskTimeout time.Duration
+	// OnProgress is called for every state change.
+	OnProgress func(result BatchResult)
+}
+
+// batchJournal is an append-only JSONL file, the last record of a path wins.
+type batchJournal struct {
+	mu    sync.Mutex
+	file  *os.File
+	state map[string]BatchResult
+}
+
+func openBatchJournal(path string) (*batchJournal, error) {
+	journal := &batchJournal{state: make(map[string]BatchResult)}
+	if path == "" {
+		return journal, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var result BatchResult
+		// a crash may leave a truncated last line
+		if json.Unmarshal(scanner.Bytes(), &result) == nil && result.Path != "" {
+			journal.state[result.Path] = result
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	// terminate a truncated last line, records must start on a new line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte{'\n'})
+		}
+	}
+	journal.file = file
+	return journal, nil
+}
+
+func (j *batchJournal) get(path string) (BatchResult, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	result, ok := j.state[path]
+	return result, ok
+}
+
+func (j *batchJournal) record(result BatchResult) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state[result.Path] = result
+	if j.file == nil {
+		return nil
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(raw, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+func (j *batchJournal) close() error {
+	if j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// UploadBatch uploads all manifest entries and waits for their consumption.
+// It returns the result of every entry in manifest order; failures of single
+// entries are reported in the results, not as error.
+func (x XClient) UploadBatch(ctx context.Context, entries []ManifestEntry, opts BatchOptions) ([]BatchResult, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultBatchConcurrency
+	}
+	if opts.TaskTimeout <= 0 {
+		opts.TaskTimeout = defaultBatchTaskTimeout
+	}
+	journal, err := openBatchJournal(opts.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+	defer journal.close()
+	live, err := x.fetchLiveState(ctx, []ObjectKind{KindTags, KindCorrespondents, KindDocumentTypes, KindCustomFields})
+	if err != nil {
+		return nil, err
+	}
+
+	record := func(result BatchResult) error {
+		result.Time = time.Now()
+		if err := journal.record(result); err != nil {
+			return err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(result)
+		}
+		return nil
+	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(opts.Concurrency)
+	for _, entry := range entries {
+		previous, journaled := journal.get(entry.Path)
+		if journaled && previous.Finished() && !(opts.RetryFailed && previous.State == BatchFailed) {
+			continue
+		}
+		group.Go(func() error {
+			// only journal errors abort the batch, a journal out of sync
+			// with the server would cause duplicates on resume
+			return x.uploadBatchEntry(groupCtx, entry, previous, journaled, live, opts, record)
+		})
+	}
+	err = group.Wait()
+
+	results := make([]BatchResult, 0, len(entries))
+	for _, entry := range entries {
+		result, ok := journal.get(entry.Path)
+		if !ok {
+			result = BatchResult{Path: entry.Path}
+		}
+		results = append(results, result)
+	}
+	return results, err
+}
+
+func (x XClient) uploadBatchEntry(ctx context.Context, entry ManifestEntry, previous BatchResult, journaled bool, live *liveState, opts BatchOptions, record func(BatchResult) error) error {
+	fail := func(taskID string, err error) error {
+		if ctx.Err() != nil {
+			// interrupted, the entry is resumed by the next run
+			return nil
+		}
+		return record(BatchResult{Path: entry.Path, State: BatchFailed, TaskID: taskID, Error: err.Error()})
+	}
+	metadata, customFields, err := entry.resolve(live)
+	if err != nil {
+		return fail("", err)
+	}
+
+	taskID := ""
+	if journaled && previous.State == BatchUploaded && previous.TaskID != "" {
+		// uploaded by a crashed run, only the task is left to track
+		taskID = previous.TaskID
+	} else {
+		// a crashed run may have uploaded the file without journaling the
+		// task, the checksum tells
+		skipDuplicates := opts.SkipDuplicates || (journaled && previous.State == BatchUploading)
+		if err := record(BatchResult{Path: entry.Path, State: BatchUploading}); err != nil {
+			return err
+		}
+		upload, err := x.UploadDocumentWithOptions(ctx, entry.Path, UploadOptions{Metadata: metadata, SkipDuplicates: skipDuplicates})
+		if err != nil {
+			return fail("", err)
+		}
+		if upload.Skipped() {
+			return record(BatchResult{Path: entry.Path, State: BatchSkipped, DocumentID: upload.DuplicateOf})
+		}
+		taskID = upload.TaskID
+		if err := record(BatchResult{Path: entry.Path, State: BatchUploaded, TaskID: taskID}); err != nil {
+			return err
+		}
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, opts.TaskTimeout)
+	documentID, err := x.WaitForTaskDocument(taskCtx, taskID, 0)
+	cancel()
+	if err != nil {
+		if task, fetchErr := x.FetchTask(ctx, taskID); fetchErr == nil && task.Result != nil && *task.Result != "" {
+			err = errors.New(*task.Result)
+		}
+		return fail(taskID, err)
+	}
+	if len(customFields) > 0 {
+		err = x.SetCustomFields(ctx, documentID, customFields)
+		if err != nil {
+			return fail(taskID, fmt.Errorf("document %d: %w", documentID, err))
+		}
+	}
+	return record(BatchResult{Path: entry.Path, State: BatchDone, TaskID: taskID, DocumentID: documentID})
+}
+
+// resolve maps names to IDs, numeric values are taken as IDs.
+func (e ManifestEntry) resolve(live *liveState) (*DocumentCreate, map[int]string, error) {
+	lookup := func(kind ObjectKind, value string) (int, error) {
+		if id, ok := live.byName[kind][value]; ok {
+			return id, nil
+		}
+		if id, err := strconv.Atoi(value); err == nil {
+			return id, nil
+		}
+		return 0, fmt.Errorf("unknown %s '%s'", kind, value)
+	}
+	metadata := &DocumentCreate{}
+	if e.Title != "" {
+		metadata.Title = P(e.Title)
+	}
+	if e.Created != "" {
+		created, err := parseManifestTime(e.Created)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata.Created = &created
+	}
+	for _, tag := range e.Tags {
+		id, err := lookup(KindTags, tag)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata.Tags = append(metadata.Tags, strconv.Itoa(id))
+	}
+	if e.Correspondent != "" {
+		id, err := lookup(KindCorrespondents, e.Correspondent)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata.Correspondent = P(id)
+	}
+	if e.DocumentType != "" {
+		id, err := lookup(KindDocumentTypes, e.DocumentType)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata.DocumentType = P(id)
+	}
+	customFields := make(map[int]string)
+	for name, value := range e.CustomFields {
+		id, err := lookup(KindCustomFields, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		customFields[id] = value
+	}
+	return metadata, customFields, nil
+}
+
+func parseManifestTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid created date '%s'", value)
+}
+
+// SetCustomFields sets custom field values of a document, values are sent
+// as strings and converted by paperless.
+func (x XClient) SetCustomFields(ctx context.Context, id int, values map[int]string) error {
+	fields := make([]map[string]interface{}, 0, len(values))
+	for field, value := range values {
+		fields = append(fields, map[string]interface{}{"field": field, "value": value})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i]["field"].(int) < fields[j]["field"].(int)
+	})
+	body, err := json.Marshal(map[string]interface{}{"custom_fields": fields})
+	if err != nil {
+		return err
+	}
+	resp, err := x.DocumentsPartialUpdateWithBodyWithResponse(ctx, id, "application/json", jsonBody(body))
+	if err != nil {
+		return fmt.Errorf("failed to set custom fields: %w", err)
+	}
+	if resp.StatusCode() != 200 {
+		return apiError("set custom fields", resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+// WriteBatchResults writes the results as CSV with the columns path, state,
+// document_id and error.
+func WriteBatchResults(w io.Writer, results []BatchResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"path", "state", "document_id", "error"})
+	for _, result := range results {
+		documentID := ""
+		if result.DocumentID != 0 {
+			documentID = strconv.Itoa(result.DocumentID)
+		}
+		writer.Write([]string{result.Path, string(result.State), documentID, result.Error})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,249 @@
+package paperless
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const defaultBulkDownloadChunkSize int = 100
+
+type BulkDownloadOptions struct {
+	// Content defaults to the archive versions, paperless falls back to the
+	// original for documents without one.
+	Content ContentEnum
+	// Compression of the zip entries, defaults to none.
+	Compression CompressionEnum
+	// FollowFormatting names the files after the storage path formatting
+	// instead of the public filenames.
+	FollowFormatting bool
+	// ChunkSize is the number of documents per request, defaults to 100.
+	ChunkSize int
+	// Dir is a directory the files are extracted to.
+	Dir string
+	// Manifest is a path the manifest is written to as json.
+	Manifest string
+}
+
+// BulkDownloadFile maps a file of a bulk download to its document. Files
+// whose checksum is unknown have no document.
+type BulkDownloadFile struct {
+	Path       string `json:"path"`
+	DocumentID int    `json:"document,omitempty"`
+	// Version is either "original" or "archive".
+	Version  string `json:"version,omitempty"`
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+}
+
+type BulkDownloadManifest struct {
+	Documents []int              `json:"documents"`
+	Files     []BulkDownloadFile `json:"files"`
+}
+
+// bulkDownloadState is shared by the chunks of a bulk download.
+type bulkDownloadState struct {
+	opts     BulkDownloadOptions
+	archive  *zip.Writer
+	used     map[string]bool
+	manifest *BulkDownloadManifest
+}
+
+// BulkDownload downloads the documents as zip archive. Large selections are
+// requested in chunks, which are merged into a single archive written to w.
+// w may be nil if the files are only extracted to opts.Dir. Files are
+// matched to their documents by checksum.
+func (x XClient) BulkDownload(ctx context.Context, ids []int, opts BulkDownloadOptions, w io.Writer) (*BulkDownloadManifest, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no documents to download")
+	}
+	if w == nil && opts.Dir == "" {
+		return nil, fmt.Errorf("missing writer or directory for the bulk download")
+	}
+	if opts.Content == "" {
+		opts.Content = ContentEnumArchive
+	}
+	if opts.Compression == "" {
+		opts.Compression = CompressionEnumNone
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultBulkDownloadChunkSize
+	}
+
+	state := &bulkDownloadState{
+		opts:     opts,
+		used:     make(map[string]bool),
+		manifest: &BulkDownloadManifest{Documents: ids, Files: make([]BulkDownloadFile, 0)},
+	}
+	if w != nil {
+		state.archive = zip.NewWriter(w)
+	}
+	for start := 0; start < len(ids); start += opts.ChunkSize {
+		chunk := ids[start:min(start+opts.ChunkSize, len(ids))]
+		if err := x.bulkDownloadChunk(ctx, chunk, state); err != nil {
+			return state.manifest, err
+		}
+	}
+	if state.archive != nil {
+		if err := state.archive.Close(); err != nil {
+			return state.manifest, fmt.Errorf("failed to write bulk download: %w", err)
+		}
+	}
+	if opts.Manifest != "" {
+		content, err := json.MarshalIndent(state.manifest, "", "  ")
+		if err != nil {
+			return state.manifest, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		if err := os.WriteFile(opts.Manifest, content, 0o644); err != nil {
+			return state.manifest, fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	return state.manifest, nil
+}
+
+// bulkDownloadChunk spools the archive of a chunk to a temporary file and
+// copies its entries to the merged archive and the directory.
+func (x XClient) bulkDownloadChunk(ctx context.Context, ids []int, state *bulkDownloadState) error {
+	checksums := make(map[string]BulkDownloadFile)
+	for _, id := range ids {
+		resp, err := x.DocumentsMetadataRetrieveWithResponse(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to fetch metadata of document %d: %w", id, err)
+		}
+		if resp.JSON200 == nil {
+			return apiError("fetch metadata", resp.StatusCode(), resp.Body)
+		}
+		checksums[resp.JSON200.OriginalChecksum] = BulkDownloadFile{DocumentID: id, Version: "original"}
+		if resp.JSON200.ArchiveChecksum != "" {
+			checksums[resp.JSON200.ArchiveChecksum] = BulkDownloadFile{DocumentID: id, Version: "archive"}
+		}
+	}
+
+	body, err := json.Marshal(BulkDownloadRequest{
+		Documents:        ids,
+		Content:          P(state.opts.Content),
+		Compression:      P(state.opts.Compression),
+		FollowFormatting: P(state.opts.FollowFormatting),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode bulk download: %w", err)
+	}
+	req, err := x.newRawRequest(ctx, "POST", "/api/documents/bulk_download/", jsonBody(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to bulk download documents: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		raw, _ := io.ReadAll(resp.Body)
+		return apiError("bulk download", resp.StatusCode, raw)
+	}
+	spool, err := os.CreateTemp("", "paperless-bulk-download-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	size, err := io.Copy(spool, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to bulk download documents: %w", err)
+	}
+	reader, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf("failed to read bulk download: %w", err)
+	}
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := state.uniqueName(file.Name)
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("unsafe path '%s' in bulk download", file.Name)
+		}
+		entry, err := state.extract(file, name)
+		if err != nil {
+			return err
+		}
+		if known, ok := checksums[entry.Checksum]; ok {
+			entry.DocumentID = known.DocumentID
+			entry.Version = known.Version
+		}
+		state.manifest.Files = append(state.manifest.Files, entry)
+		if state.archive == nil {
+			continue
+		}
+		header := file.FileHeader
+		header.Name = name
+		raw, err := file.OpenRaw()
+		if err != nil {
+			return fmt.Errorf("failed to read '%s' of bulk download: %w", file.Name, err)
+		}
+		target, err := state.archive.CreateRaw(&header)
+		if err != nil {
+			return fmt.Errorf("failed to write bulk download: %w", err)
+		}
+		if _, err := io.Copy(target, raw); err != nil {
+			return fmt.Errorf("failed to write bulk download: %w", err)
+		}
+	}
+	return nil
+}
+
+// uniqueName appends a counter like paperless does for files of different
+// chunks with the same name.
+func (s *bulkDownloadState) uniqueName(name string) string {
+	output := name
+	ext := path.Ext(name)
+	for counter := 1; s.used[output]; counter++ {
+		output = fmt.Sprintf("%s_%02d%s", strings.TrimSuffix(name, ext), counter, ext)
+	}
+	s.used[output] = true
+	return output
+}
+
+// extract checksums a file and writes it to the directory, if any.
+func (s *bulkDownloadState) extract(file *zip.File, name string) (BulkDownloadFile, error) {
+	entry := BulkDownloadFile{Path: name}
+	content, err := file.Open()
+	if err != nil {
+		return entry, fmt.Errorf("failed to read '%s' of bulk download: %w", file.Name, err)
+	}
+	defer content.Close()
+	hash := md5.New()
+	if s.opts.Dir == "" {
+		entry.Size, err = io.Copy(hash, content)
+	} else {
+		filename := filepath.Join(s.opts.Dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return entry, fmt.Errorf("failed to create directory for '%s': %w", name, err)
+		}
+		var output *os.File
+		output, err = os.Create(filename)
+		if err != nil {
+			return entry, fmt.Errorf("failed to extract '%s': %w", name, err)
+		}
+		entry.Size, err = io.Copy(io.MultiWriter(output, hash), content)
+		// a failed close may lose the written content
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return entry, fmt.Errorf("failed to extract '%s': %w", name, err)
+	}
+	entry.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return entry, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runBatchUpload(args []string) error {
+	var (
+		conn           connectionFlags
+		manifestPath   string
+		journalPath    string
+		resultsPath    string
+		concurrency    int
+		skipDuplicates bool
+		retryFailed    bool
+		quiet          bool
+		taskTimeout    time.Duration
+	)
+	fs := flag.NewFlagSet("batch-upload", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&manifestPath, "manifest", "", "manifest of files to upload (csv or jsonl)")
+	fs.StringVar(&journalPath, "journal", "", "journal to record progress in and resume from (default: <manifest>.journal)")
+	fs.StringVar(&resultsPath, "results", "", "csv file mapping paths to document ids or failures (default: stdout)")
+	fs.IntVar(&concurrency, "concurrency", 4, "parallel uploads")
+	fs.BoolVar(&skipDuplicates, "skip-duplicates", false, "skip files paperless has already")
+	fs.BoolVar(&retryFailed, "retry-failed", false, "retry entries which failed in a previous run")
+	fs.BoolVar(&quiet, "q", false, "do not print progress")
+	fs.DurationVar(&taskTimeout, "task-timeout", 30*time.Minute, "timeout for a single consumption task")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if manifestPath == "" {
+		return fmt.Errorf("missing manifest (-manifest)")
+	}
+	if journalPath == "" {
+		journalPath = manifestPath + ".journal"
+	}
+	entries, err := paperless.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	opts := paperless.BatchOptions{
+		Concurrency:    concurrency,
+		JournalPath:    journalPath,
+		SkipDuplicates: skipDuplicates,
+		RetryFailed:    retryFailed,
+		TaskTimeout:    taskTimeout,
+	}
+	if !quiet {
+		opts.OnProgress = func(result paperless.BatchResult) {
+			fmt.Fprintf(os.Stderr, "%-9s %s %s\n", result.State, result.Path, result.Error)
+		}
+	}
+	results, err := client.UploadBatch(context.Background(), entries, opts)
+	if err != nil {
+		return err
+	}
+
+	output := os.Stdout
+	if resultsPath != "" {
+		output, err = os.Create(resultsPath)
+		if err != nil {
+			return fmt.Errorf("failed to create results: %w", err)
+		}
+		defer output.Close()
+	}
+	if err := paperless.WriteBatchResults(output, results); err != nil {
+		return err
+	}
+	failed := 0
+	for _, result := range results {
+		if result.State == paperless.BatchFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return exitError{code: 3, msg: fmt.Sprintf("❌ %d of %d uploads failed", failed, len(results))}
+	}
+	return nil
+}
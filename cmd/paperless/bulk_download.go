@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runBulkDownload(args []string) error {
+	var (
+		conn             connectionFlags
+		documents        intList
+		output           string
+		dir              string
+		manifest         string
+		content          string
+		compression      string
+		followFormatting bool
+		chunkSize        int
+		timeout          time.Duration
+	)
+	fs := flag.NewFlagSet("bulk-download", flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&documents, "doc", "document id, repeatable or comma separated")
+	fs.StringVar(&output, "o", "", "zip file to write to")
+	fs.StringVar(&dir, "dir", "", "directory to extract the files to")
+	fs.StringVar(&manifest, "manifest", "", "file to write the json manifest to")
+	fs.StringVar(&content, "content", string(paperless.ContentEnumArchive), "archive, originals or both")
+	fs.StringVar(&compression, "compression", string(paperless.CompressionEnumNone), "none, deflated, bzip2 or lzma")
+	fs.BoolVar(&followFormatting, "follow-formatting", false, "name files after the storage path formatting")
+	fs.IntVar(&chunkSize, "chunk", 100, "documents per request")
+	fs.DurationVar(&timeout, "timeout", 30*time.Minute, "timeout for the whole download")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(documents) == 0 {
+		return fmt.Errorf("missing documents (-doc)")
+	}
+	if output == "" && dir == "" {
+		return fmt.Errorf("missing zip file (-o) or directory (-dir)")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	var w io.Writer
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result, err := client.BulkDownload(ctx, documents, paperless.BulkDownloadOptions{
+		Content:          paperless.ContentEnum(content),
+		Compression:      paperless.CompressionEnum(compression),
+		FollowFormatting: followFormatting,
+		ChunkSize:        chunkSize,
+		Dir:              dir,
+		Manifest:         manifest,
+	}, w)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("downloaded %d files of %d documents\n", len(result.Files), len(documents))
+	return nil
+}
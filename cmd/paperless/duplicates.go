@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runDuplicates(args []string) error {
+	var (
+		conn        connectionFlags
+		files       stringList
+		concurrency int
+		asJSON      bool
+		timeout     time.Duration
+	)
+	fs := flag.NewFlagSet("duplicates", flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&files, "file", "local file to look up instead of scanning the archive, repeatable")
+	fs.IntVar(&concurrency, "concurrency", 4, "parallel metadata requests when scanning the archive")
+	fs.BoolVar(&asJSON, "json", false, "print the report as json")
+	fs.DurationVar(&timeout, "timeout", time.Hour, "timeout for the whole run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if len(files) > 0 {
+		found := make(map[string]*int)
+		for _, file := range files {
+			document, err := client.FindByChecksum(ctx, file)
+			if err != nil {
+				return err
+			}
+			found[file] = nil
+			if document != nil {
+				found[file] = document.Id
+			}
+			if asJSON {
+				continue
+			}
+			if document != nil {
+				fmt.Printf("%s: document %d\n", file, *document.Id)
+			} else {
+				fmt.Printf("%s: not in paperless\n", file)
+			}
+		}
+		if asJSON {
+			return json.NewEncoder(os.Stdout).Encode(found)
+		}
+		return nil
+	}
+
+	report, err := client.FindDuplicates(ctx, paperless.DuplicateOptions{Concurrency: concurrency})
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	fmt.Println(report)
+	return nil
+}
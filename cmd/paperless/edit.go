@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runEdit(args []string) error {
+	var (
+		conn            connectionFlags
+		documents       intList
+		pages           string
+		operations      string
+		degrees         int
+		deleteOriginals bool
+		inPlace         bool
+		carry           stringList
+		timeout         time.Duration
+	)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing action: merge, split, rotate, delete-pages or edit-pdf")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("edit "+action, flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&documents, "doc", "document id, repeatable or comma separated")
+	fs.StringVar(&pages, "pages", "", "page ranges to split into or pages to delete, e.g. 1-2,3")
+	fs.StringVar(&operations, "ops", "", "edit-pdf operations page[:doc[:rotate]], comma separated, e.g. 1:0,2:0:90,3:1")
+	fs.IntVar(&degrees, "degrees", 90, "rotation in degrees")
+	fs.BoolVar(&deleteOriginals, "delete-originals", false, "delete the source documents")
+	fs.BoolVar(&inPlace, "in-place", false, "edit-pdf modifies the document instead of creating new ones")
+	fs.Var(&carry, "carry", "metadata to copy to new documents: tags, custom-fields or notes, repeatable")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "timeout for the edit and the consumption of new documents")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if len(documents) == 0 {
+		return fmt.Errorf("missing documents (-doc)")
+	}
+	opts := paperless.DocumentEditorOptions{DeleteOriginals: deleteOriginals, UpdateDocument: inPlace}
+	for _, value := range carry {
+		switch value {
+		case "tags":
+			opts.CarryTags = true
+		case "custom-fields":
+			opts.CarryCustomFields = true
+		case "notes":
+			opts.CarryNotes = true
+		default:
+			return fmt.Errorf("unknown metadata '%s' to carry: tags, custom-fields or notes", value)
+		}
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	editor := client.NewDocumentEditor(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var ids []int
+	switch action {
+	case "merge":
+		var id int
+		id, err = editor.Merge(ctx, documents)
+		ids = []int{id}
+	case "split":
+		ranges, parseErr := paperless.ParsePageRanges(pages)
+		if parseErr != nil {
+			return parseErr
+		}
+		ids, err = editor.Split(ctx, documents[0], ranges)
+	case "rotate":
+		ids, err = editor.Rotate(ctx, documents, degrees)
+	case "delete-pages":
+		numbers := make([]int, 0)
+		for _, part := range strings.Split(pages, ",") {
+			number, parseErr := strconv.Atoi(strings.TrimSpace(part))
+			if parseErr != nil {
+				return fmt.Errorf("invalid page '%s'", part)
+			}
+			numbers = append(numbers, number)
+		}
+		ids, err = editor.DeletePages(ctx, documents[0], numbers)
+	case "edit-pdf":
+		parsed, parseErr := parsePageOperations(operations)
+		if parseErr != nil {
+			return parseErr
+		}
+		ids, err = editor.EditPDF(ctx, documents[0], parsed)
+	default:
+		return fmt.Errorf("unknown action '%s': merge, split, rotate, delete-pages or edit-pdf", action)
+	}
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+// parsePageOperations parses "page[:doc[:rotate]]" lists.
+func parsePageOperations(value string) ([]paperless.PageOperation, error) {
+	output := make([]paperless.PageOperation, 0)
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("invalid operation '%s'", part)
+		}
+		numbers := make([]int, 3)
+		for i, field := range fields {
+			number, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid operation '%s'", part)
+			}
+			numbers[i] = number
+		}
+		output = append(output, paperless.PageOperation{Page: numbers[0], Document: numbers[1], Rotate: numbers[2]})
+	}
+	return output, nil
+}
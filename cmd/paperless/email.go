@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runEmail(args []string) error {
+	var (
+		conn      connectionFlags
+		documents intList
+		to        stringList
+		subject   string
+		message   string
+		archive   bool
+		timeout   time.Duration
+	)
+	fs := flag.NewFlagSet("email", flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&documents, "doc", "document id, repeatable or comma separated")
+	fs.Var(&to, "to", "recipient address, repeatable")
+	fs.StringVar(&subject, "subject", "", "email subject")
+	fs.StringVar(&message, "message", "", "email message")
+	fs.BoolVar(&archive, "archive", false, "attach the archive versions instead of the originals")
+	fs.DurationVar(&timeout, "timeout", 2*time.Minute, "timeout for sending the email")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(documents) == 0 {
+		return fmt.Errorf("missing documents (-doc)")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err = client.EmailDocuments(ctx, documents, paperless.EmailOptions{
+		To:                to,
+		Subject:           subject,
+		Message:           message,
+		UseArchiveVersion: archive,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("emailed %d documents to %d recipients\n", len(documents), len(to))
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runExtract(args []string) error {
+	var (
+		conn           connectionFlags
+		patternsPath   string
+		upload         bool
+		asJSON         bool
+		journalPath    string
+		concurrency    int
+		skipDuplicates bool
+		taskTimeout    time.Duration
+	)
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&patternsPath, "patterns", "", "yaml file of filename patterns")
+	fs.BoolVar(&upload, "upload", false, "upload the files instead of previewing the extracted metadata")
+	fs.BoolVar(&asJSON, "json", false, "print the preview as json")
+	fs.StringVar(&journalPath, "journal", "", "journal to record upload progress in and resume from")
+	fs.IntVar(&concurrency, "concurrency", 4, "parallel uploads")
+	fs.BoolVar(&skipDuplicates, "skip-duplicates", false, "skip files paperless has already")
+	fs.DurationVar(&taskTimeout, "task-timeout", 30*time.Minute, "timeout for a single consumption task")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if patternsPath == "" {
+		return fmt.Errorf("missing filename patterns (-patterns)")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing files")
+	}
+	extractor, err := paperless.LoadFilenameExtractor(patternsPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if !upload {
+		previews, err := client.PreviewExtraction(ctx, extractor, fs.Args())
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(previews)
+		}
+		failed := 0
+		for _, preview := range previews {
+			fmt.Println(preview.String())
+			if preview.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return exitError{code: 3, msg: fmt.Sprintf("❌ %d of %d files failed", failed, len(previews))}
+		}
+		return nil
+	}
+
+	entries, err := extractor.ManifestEntries(fs.Args())
+	if err != nil {
+		return err
+	}
+	results, err := client.UploadBatch(ctx, entries, paperless.BatchOptions{
+		Concurrency:    concurrency,
+		JournalPath:    journalPath,
+		SkipDuplicates: skipDuplicates,
+		TaskTimeout:    taskTimeout,
+		OnProgress: func(result paperless.BatchResult) {
+			fmt.Fprintf(os.Stderr, "%-9s %s %s\n", result.State, result.Path, result.Error)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := paperless.WriteBatchResults(os.Stdout, results); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.State == paperless.BatchFailed {
+			return exitError{code: 3, msg: "❌ some uploads failed"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runHistory(args []string) error {
+	var (
+		conn      connectionFlags
+		documents intList
+		format    string
+		output    string
+		timeline  bool
+		timeout   time.Duration
+	)
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&documents, "doc", "document id, repeatable or comma separated")
+	fs.StringVar(&format, "format", "csv", "export format: csv or jsonl")
+	fs.StringVar(&output, "o", "", "file to write to (default: stdout)")
+	fs.BoolVar(&timeline, "timeline", false, "print history, notes and share links as jsonl timeline")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "timeout for fetching the history")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(documents) == 0 {
+		return fmt.Errorf("missing documents (-doc)")
+	}
+	if format != "csv" && format != "jsonl" {
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if timeline {
+		encoder := json.NewEncoder(w)
+		for _, id := range documents {
+			events, err := client.DocumentTimeline(ctx, id)
+			if err != nil {
+				return err
+			}
+			for _, event := range events {
+				if err := encoder.Encode(event); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	entries, err := client.DocumentsHistory(ctx, documents)
+	if err != nil {
+		return err
+	}
+	if format == "jsonl" {
+		return paperless.WriteHistoryJSONL(w, entries)
+	}
+	return paperless.WriteHistoryCSV(w, entries)
+}
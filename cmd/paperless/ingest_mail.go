@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+var (
+	mailTitleFrom = map[string]paperless.AssignTitleFromEnum{
+		"subject":  paperless.AssignTitleFromEnumN1,
+		"filename": paperless.AssignTitleFromEnumN2,
+		"none":     paperless.AssignTitleFromEnumN3,
+	}
+	mailCorrespondentFrom = map[string]paperless.AssignCorrespondentFromEnum{
+		"none":    paperless.AssignCorrespondentFromEnumN1,
+		"address": paperless.AssignCorrespondentFromEnumN2,
+		"name":    paperless.AssignCorrespondentFromEnumN3,
+	}
+)
+
+func runIngestMail(args []string) error {
+	var (
+		conn              connectionFlags
+		mimeTypes         stringList
+		tags              intList
+		include           string
+		exclude           string
+		inline            bool
+		titleFrom         string
+		correspondentFrom string
+		correspondent     int
+		skipDuplicates    bool
+		noNote            bool
+		taskTimeout       time.Duration
+	)
+	fs := flag.NewFlagSet("ingest-mail", flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&mimeTypes, "mime", "mime type of attachments to upload, e.g. application/pdf or image/*, repeatable")
+	fs.Var(&tags, "tag", "tag id to assign, repeatable")
+	fs.StringVar(&include, "include", "", "filename pattern of attachments to upload, e.g. *.pdf")
+	fs.StringVar(&exclude, "exclude", "", "filename pattern of attachments to skip")
+	fs.BoolVar(&inline, "inline", false, "upload inline parts too")
+	fs.StringVar(&titleFrom, "title-from", "subject", "title source: subject, filename or none")
+	fs.StringVar(&correspondentFrom, "correspondent-from", "none", "correspondent source: none, address or name")
+	fs.IntVar(&correspondent, "correspondent", 0, "correspondent id to assign")
+	fs.BoolVar(&skipDuplicates, "skip-duplicates", false, "skip attachments paperless has already")
+	fs.BoolVar(&noNote, "no-note", false, "do not add the original message as a note")
+	fs.DurationVar(&taskTimeout, "task-timeout", 30*time.Minute, "timeout for a single consumption task")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing .eml or .mbox files")
+	}
+	opts := paperless.MailIngestOptions{
+		AttachmentType:  paperless.AttachmentTypeEnumN1,
+		MimeTypes:       mimeTypes,
+		FilenameInclude: include,
+		FilenameExclude: exclude,
+		Tags:            tags,
+		SkipDuplicates:  skipDuplicates,
+		OriginalNote:    !noNote,
+		TaskTimeout:     taskTimeout,
+	}
+	if inline {
+		opts.AttachmentType = paperless.AttachmentTypeEnumN2
+	}
+	var ok bool
+	if opts.AssignTitleFrom, ok = mailTitleFrom[titleFrom]; !ok {
+		return fmt.Errorf("invalid -title-from '%s'", titleFrom)
+	}
+	if opts.AssignCorrespondentFrom, ok = mailCorrespondentFrom[correspondentFrom]; !ok {
+		return fmt.Errorf("invalid -correspondent-from '%s'", correspondentFrom)
+	}
+	if correspondent != 0 {
+		opts.AssignCorrespondentFrom = paperless.AssignCorrespondentFromEnumN4
+		opts.Correspondent = correspondent
+	}
+
+	messages := make([]*paperless.MailMessage, 0)
+	for _, path := range fs.Args() {
+		loaded, err := paperless.LoadMail(path)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, loaded...)
+	}
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	results, err := client.IngestMail(context.Background(), messages, opts)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, result := range results {
+		fmt.Println(result.String())
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return exitError{code: 3, msg: fmt.Sprintf("❌ %d of %d attachments failed", failed, len(results))}
+	}
+	return nil
+}
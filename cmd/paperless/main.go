@@ -0,0 +1,170 @@
+// Command paperless bundles maintenance tooling built upon the paperless-ngx-go
+// client library.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"batch-upload": {
+		usage: "upload the files of a manifest, resumable via a journal",
+		run:   runBatchUpload,
+	},
+	"bulk-download": {
+		usage: "download documents as zip or directory tree with a manifest of document ids and checksums",
+		run:   runBulkDownload,
+	},
+	"duplicates": {
+		usage: "find duplicate documents in the archive or look up local files by checksum",
+		run:   runDuplicates,
+	},
+	"edit": {
+		usage: "merge, split, rotate or delete pages of documents and print the resulting document ids",
+		run:   runEdit,
+	},
+	"email": {
+		usage: "email documents as attachments via the SMTP server of paperless",
+		run:   runEmail,
+	},
+	"extract": {
+		usage: "preview or upload files with metadata extracted from their filenames",
+		run:   runExtract,
+	},
+	"history": {
+		usage: "export the audit trail of documents as csv or jsonl",
+		run:   runHistory,
+	},
+	"ingest-mail": {
+		usage: "upload attachments of .eml files and .mbox archives with metadata from the headers",
+		run:   runIngestMail,
+	},
+	"matching-suite": {
+		usage: "score matching rules or server suggestions against labeled documents",
+		run:   runMatchingSuite,
+	},
+	"notes": {
+		usage: "list, add, edit or delete notes, or export and import all notes by checksum",
+		run:   runNotes,
+	},
+	"queue": {
+		usage: "queue uploads on disk and deliver them once paperless is healthy",
+		run:   runQueue,
+	},
+	"reconcile": {
+		usage: "plan or apply a declarative description of taxonomy and workflows",
+		run:   runReconcile,
+	},
+	"what-if": {
+		usage: "show which objects matching rules assign to documents, optionally with a changed rule",
+		run:   runWhatIf,
+	},
+	"retention": {
+		usage: "move documents past their retention period to the trash, honoring legal holds",
+		run:   runRetention,
+	},
+	"trash": {
+		usage: "list, restore or empty the trash, or purge documents past a retention period",
+		run:   runTrash,
+	},
+	"share": {
+		usage: "create share links, list them, sweep expired or orphaned ones or report shared documents",
+		run:   runShare,
+	},
+	"spec-drift": {
+		usage: "compare the schema served by paperless with the embedded api.yaml",
+		run:   runSpecDrift,
+	},
+}
+
+// exitError makes main exit with a specific code, e.g. to fail CI pipelines
+// on findings rather than on errors.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e exitError) Error() string {
+	return e.msg
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	err := cmd.run(os.Args[2:])
+	if err == nil {
+		return
+	}
+	var exitErr exitError
+	if errors.As(err, &exitErr) {
+		if exitErr.msg != "" {
+			fmt.Fprintln(os.Stderr, exitErr.msg)
+		}
+		os.Exit(exitErr.code)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	os.Exit(1)
+}
+
+// connectionFlags registers the flags needed to talk to a paperless
+// instance, defaulting to PAPERLESS_URL, PAPERLESS_TOKEN, PAPERLESS_USER and
+// PAPERLESS_PASSWORD.
+type connectionFlags struct {
+	url      string
+	token    string
+	user     string
+	password string
+}
+
+func (c *connectionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.url, "url", os.Getenv("PAPERLESS_URL"), "paperless base url")
+	fs.StringVar(&c.token, "token", os.Getenv("PAPERLESS_TOKEN"), "api token")
+	fs.StringVar(&c.user, "user", os.Getenv("PAPERLESS_USER"), "user for basic auth")
+	fs.StringVar(&c.password, "password", os.Getenv("PAPERLESS_PASSWORD"), "password for basic auth")
+}
+
+func (c *connectionFlags) client() (paperless.XClient, error) {
+	if c.url == "" {
+		return paperless.XClient{}, fmt.Errorf("missing paperless url (-url or PAPERLESS_URL)")
+	}
+	if c.token != "" {
+		return paperless.NewXClientWithToken(c.url, c.token)
+	}
+	if c.user != "" {
+		return paperless.NewXClientWithCredentials(c.url, c.user, c.password)
+	}
+	return paperless.XClient{}, fmt.Errorf("missing credentials (-token or -user/-password)")
+}
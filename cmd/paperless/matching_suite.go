@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runMatchingSuite(args []string) error {
+	var (
+		conn          connectionFlags
+		corpusPath    string
+		source        string
+		rulesPath     string
+		asJSON        bool
+		minF1         float64
+		baselinePath  string
+		maxDrop       float64
+		writeBaseline string
+		timeout       time.Duration
+	)
+	fs := flag.NewFlagSet("matching-suite", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&corpusPath, "corpus", "corpus.yaml", "labeled documents (yaml or json)")
+	fs.StringVar(&source, "source", "local", "what to evaluate: suggestions (server) or local (matcher)")
+	fs.StringVar(&rulesPath, "rules", "", "desired state file (see reconcile) whose rules the local matcher uses instead of the server's; no server needed for local files")
+	fs.BoolVar(&asJSON, "json", false, "print the report as json")
+	fs.Float64Var(&minF1, "min-f1", 0, "exit with code 3 if the total f1 score is below")
+	fs.StringVar(&baselinePath, "baseline", "", "json report to compare with")
+	fs.Float64Var(&maxDrop, "max-drop", 0.01, "exit with code 3 if any f1 score dropped by more than this compared to the baseline")
+	fs.StringVar(&writeBaseline, "write-baseline", "", "write the json report to this path")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "timeout for the whole suite")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	corpus, err := paperless.LoadMatchingCorpus(corpusPath)
+	if err != nil {
+		return err
+	}
+	opts := paperless.MatchingSuiteOptions{Source: paperless.SuiteSource(source)}
+	if rulesPath != "" {
+		state, err := paperless.LoadDesiredState(rulesPath)
+		if err != nil {
+			return err
+		}
+		opts.Matcher = state.Matcher()
+	}
+
+	var report *paperless.SuiteReport
+	if opts.Source == paperless.SuiteLocal && opts.Matcher != nil && conn.url == "" {
+		report, err = paperless.RunLocalMatchingSuite(corpus, opts.Matcher)
+	} else {
+		client, clientErr := conn.client()
+		if clientErr != nil {
+			return clientErr
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		report, err = client.RunMatchingSuite(ctx, corpus, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(report)
+	}
+	if writeBaseline != "" {
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(writeBaseline, raw, 0o644); err != nil {
+			return fmt.Errorf("failed to write baseline: %w", err)
+		}
+	}
+
+	failures := make([]string, 0)
+	if total := report.Total(); total.F1() < minF1 {
+		failures = append(failures, fmt.Sprintf("total f1 %.3f below %.3f", total.F1(), minF1))
+	}
+	if baselinePath != "" {
+		baseline, err := paperless.LoadSuiteReport(baselinePath)
+		if err != nil {
+			return err
+		}
+		for _, regression := range report.Regressions(baseline, maxDrop) {
+			failures = append(failures, regression.String())
+		}
+	}
+	if len(failures) > 0 {
+		return exitError{code: 3, msg: "⚠️ matching regressed:\n  " + strings.Join(failures, "\n  ")}
+	}
+	return nil
+}
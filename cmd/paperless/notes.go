@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runNotes(args []string) error {
+	var (
+		conn     connectionFlags
+		document int
+		noteID   int
+		text     string
+		format   string
+		file     string
+		dryRun   bool
+		timeout  time.Duration
+	)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing action: list, add, edit, delete, export or import")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("notes "+action, flag.ContinueOnError)
+	conn.register(fs)
+	fs.IntVar(&document, "doc", 0, "document id")
+	fs.IntVar(&noteID, "id", 0, "note id for edit and delete")
+	fs.StringVar(&text, "text", "", "note text for add and edit")
+	fs.StringVar(&format, "format", "jsonl", "export format: jsonl or markdown")
+	fs.StringVar(&file, "f", "", "file to export to or import from (default: stdout/stdin)")
+	fs.BoolVar(&dryRun, "dry-run", false, "report what import would add without changing anything")
+	fs.DurationVar(&timeout, "timeout", time.Hour, "timeout for the whole operation")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	needsDocument := action == "list" || action == "add" || action == "edit" || action == "delete"
+	if needsDocument && document == 0 {
+		return fmt.Errorf("missing document (-doc)")
+	}
+	if (action == "edit" || action == "delete") && noteID == 0 {
+		return fmt.Errorf("missing note (-id)")
+	}
+	if (action == "add" || action == "edit") && text == "" {
+		return fmt.Errorf("missing note text (-text)")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch action {
+	case "list":
+		notes, err := client.ListNotes(ctx, document)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(notes)
+	case "add":
+		note, err := client.AddNote(ctx, document, text)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✔ note %d added\n", note.ID)
+		return nil
+	case "edit":
+		_, err := client.UpdateNote(ctx, document, noteID, text)
+		return err
+	case "delete":
+		return client.DeleteNote(ctx, document, noteID)
+	case "export":
+		if format != "jsonl" && format != "markdown" {
+			return fmt.Errorf("unknown format '%s'", format)
+		}
+		notes, err := client.ExportNotes(ctx)
+		if err != nil {
+			return err
+		}
+		var w io.Writer = os.Stdout
+		if file != "" {
+			output, err := os.Create(file)
+			if err != nil {
+				return fmt.Errorf("failed to create export: %w", err)
+			}
+			defer output.Close()
+			w = output
+		}
+		if format == "markdown" {
+			return paperless.WriteNotesMarkdown(w, notes)
+		}
+		return paperless.WriteNotesJSONL(w, notes)
+	case "import":
+		var r io.Reader = os.Stdin
+		if file != "" {
+			input, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("failed to open import: %w", err)
+			}
+			defer input.Close()
+			r = input
+		}
+		notes, err := paperless.ReadNotesJSONL(r)
+		if err != nil {
+			return err
+		}
+		report, err := client.ImportNotes(ctx, notes, paperless.NotesImportOptions{DryRun: dryRun})
+		if err != nil {
+			return err
+		}
+		fmt.Println(report.String())
+		for _, checksum := range report.Missing {
+			fmt.Printf("  missing: %s\n", checksum)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action '%s': list, add, edit, delete, export or import", action)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runQueue(args []string) error {
+	var (
+		conn         connectionFlags
+		dir          string
+		title        string
+		created      string
+		tags         intList
+		pollInterval time.Duration
+		maxAttempts  int
+		asJSON       bool
+		timeout      time.Duration
+	)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing action: add, status, run, flush or retry")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("queue "+action, flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&dir, "dir", os.Getenv("PAPERLESS_QUEUE_DIR"), "queue directory")
+	fs.StringVar(&title, "title", "", "title of the queued files")
+	fs.StringVar(&created, "created", "", "created date of the queued files, e.g. 2024-03-15")
+	fs.Var(&tags, "tag", "tag id to assign, repeatable or comma separated")
+	fs.DurationVar(&pollInterval, "poll-interval", 30*time.Second, "interval of health checks while paperless is down")
+	fs.IntVar(&maxAttempts, "max-attempts", 10, "attempts before an upload is moved to failed")
+	fs.BoolVar(&asJSON, "json", false, "print status as json")
+	fs.DurationVar(&timeout, "timeout", time.Hour, "timeout for flush")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if dir == "" {
+		return fmt.Errorf("missing queue directory (-dir or PAPERLESS_QUEUE_DIR)")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	queue, err := client.OpenUploadQueue(dir, paperless.UploadQueueOptions{
+		PollInterval: pollInterval,
+		MaxAttempts:  maxAttempts,
+		OnDelivered: func(item paperless.QueuedUpload, taskID string) {
+			fmt.Fprintf(os.Stderr, "✔ %s delivered (task %s)\n", item.Filename, taskID)
+		},
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "add":
+		if fs.NArg() == 0 {
+			return fmt.Errorf("missing files")
+		}
+		metadata := &paperless.DocumentCreate{}
+		if title != "" {
+			metadata.Title = paperless.P(title)
+		}
+		if created != "" {
+			date, err := time.ParseInLocation("2006-01-02", created, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid created date '%s'", created)
+			}
+			metadata.Created = &date
+		}
+		for _, tag := range tags {
+			metadata.Tags = append(metadata.Tags, strconv.Itoa(tag))
+		}
+		for _, path := range fs.Args() {
+			item, err := queue.Enqueue(path, metadata)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s %s\n", item.ID, path)
+		}
+		return nil
+	case "status":
+		metrics, err := queue.Metrics()
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return json.NewEncoder(os.Stdout).Encode(metrics)
+		}
+		fmt.Printf("pending: %d (oldest %s)\nfailed:  %d\n", metrics.Depth, metrics.OldestAge.Round(time.Second), metrics.Failed)
+		return nil
+	case "run":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := queue.Run(ctx); err != context.Canceled {
+			return err
+		}
+		return nil
+	case "flush":
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return queue.Flush(ctx)
+	case "retry":
+		return queue.Retry()
+	default:
+		return fmt.Errorf("unknown action '%s': add, status, run, flush or retry", action)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runReconcile(args []string) error {
+	var (
+		conn        connectionFlags
+		statePath   string
+		apply       bool
+		prune       bool
+		asJSON      bool
+		failOnDrift bool
+		timeout     time.Duration
+	)
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&statePath, "f", "paperless.yaml", "desired state (yaml or json)")
+	fs.BoolVar(&apply, "apply", false, "apply the plan, otherwise only print it")
+	fs.BoolVar(&prune, "prune", false, "delete objects missing from the desired state")
+	fs.BoolVar(&asJSON, "json", false, "print the plan as json")
+	fs.BoolVar(&failOnDrift, "fail-on-drift", false, "exit with code 3 if the live state differs from the desired state")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "timeout for planning and applying")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	state, err := paperless.LoadDesiredState(statePath)
+	if err != nil {
+		return err
+	}
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	plan, err := client.PlanReconcile(ctx, state, paperless.ReconcileOptions{Prune: prune})
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(plan)
+	}
+
+	if apply && plan.HasChanges() {
+		result, err := client.ApplyPlan(ctx, plan)
+		if result != nil {
+			fmt.Fprintf(os.Stderr, "applied %d changes\n", len(result.Applied))
+		}
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+	if failOnDrift && plan.HasDrift() {
+		return exitError{code: 3, msg: "⚠️ live state differs from desired state"}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runRetention(args []string) error {
+	var (
+		conn       connectionFlags
+		policyPath string
+		reportDir  string
+		dryRun     bool
+		asJSON     bool
+		timeout    time.Duration
+	)
+	fs := flag.NewFlagSet("retention", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&policyPath, "policy", "", "retention policy (yaml or json)")
+	fs.StringVar(&reportDir, "report-dir", "", "directory receiving a json report of the run")
+	fs.BoolVar(&dryRun, "dry-run", false, "report expired documents without deleting them")
+	fs.BoolVar(&asJSON, "json", false, "print the report as json")
+	fs.DurationVar(&timeout, "timeout", 30*time.Minute, "timeout for the whole run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if policyPath == "" {
+		return fmt.Errorf("missing retention policy (-policy)")
+	}
+	policy, err := paperless.LoadRetentionPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	report, err := client.ApplyRetention(ctx, policy, paperless.RetentionOptions{DryRun: dryRun, ReportDir: reportDir})
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(report)
+	}
+	if len(report.Failed) > 0 {
+		return exitError{code: 1, msg: fmt.Sprintf("❌ %d expired documents could not be deleted", len(report.Failed))}
+	}
+	return nil
+}
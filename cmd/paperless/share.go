@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runShare(args []string) error {
+	var (
+		conn     connectionFlags
+		document int
+		expires  string
+		original bool
+		expired  bool
+		active   bool
+		dryRun   bool
+		trashed  bool
+		asJSON   bool
+		timeout  time.Duration
+	)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing action: create, list, sweep or report")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("share "+action, flag.ContinueOnError)
+	conn.register(fs)
+	fs.IntVar(&document, "doc", 0, "document id")
+	fs.StringVar(&expires, "expires", "", "lifetime of a created link, e.g. 7d or 12h (default: never expires)")
+	fs.BoolVar(&original, "original", false, "share the original instead of the archive version")
+	fs.BoolVar(&expired, "expired", false, "list expired links only")
+	fs.BoolVar(&active, "active", false, "list active links only")
+	fs.BoolVar(&dryRun, "dry-run", false, "report what sweep would delete without deleting")
+	fs.BoolVar(&trashed, "trashed", false, "sweep links to documents in the trash as well")
+	fs.BoolVar(&asJSON, "json", false, "print json")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "timeout for the whole operation")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	write := func(value interface{}, text string) error {
+		if asJSON {
+			return json.NewEncoder(os.Stdout).Encode(value)
+		}
+		fmt.Println(text)
+		return nil
+	}
+
+	switch action {
+	case "create":
+		if document == 0 {
+			return fmt.Errorf("missing document (-doc)")
+		}
+		opts := paperless.ShareOptions{FileVersion: paperless.FileVersionEnumArchive}
+		if original {
+			opts.FileVersion = paperless.FileVersionEnumOriginal
+		}
+		if expires != "" {
+			age, err := parseAge(expires)
+			if err != nil {
+				return err
+			}
+			opts.Expiration = paperless.P(time.Now().Add(age))
+		}
+		link, err := client.ShareDocument(ctx, document, opts)
+		if err != nil {
+			return err
+		}
+		return write(link, link.URL)
+	case "list":
+		filter := paperless.ShareLinkFilter{Document: document}
+		if expired && active {
+			return fmt.Errorf("-expired and -active are exclusive")
+		}
+		if expired || active {
+			filter.Expired = paperless.P(expired)
+		}
+		links, err := client.ListShareLinks(ctx, filter)
+		if err != nil {
+			return err
+		}
+		lines := make([]string, 0, len(links))
+		for _, link := range links {
+			expiration := "never"
+			if link.Expiration != nil {
+				expiration = link.Expiration.Format(time.RFC3339)
+			}
+			lines = append(lines, fmt.Sprintf("%d document %d expires %s %s", link.ID, link.DocumentID, expiration, link.URL))
+		}
+		return write(links, strings.Join(lines, "\n"))
+	case "sweep":
+		report, err := client.SweepShareLinks(ctx, paperless.ShareSweepOptions{DryRun: dryRun, Trashed: trashed})
+		if report != nil {
+			if writeErr := write(report, report.String()); writeErr != nil {
+				return writeErr
+			}
+		}
+		return err
+	case "report":
+		shared, err := client.SharedDocuments(ctx)
+		if err != nil {
+			return err
+		}
+		lines := []string{fmt.Sprintf("%d documents shared externally", len(shared))}
+		for _, document := range shared {
+			lines = append(lines, fmt.Sprintf("%d %q", document.DocumentID, document.Title))
+			for _, link := range document.Links {
+				lines = append(lines, "  "+link.URL)
+			}
+		}
+		return write(shared, strings.Join(lines, "\n"))
+	default:
+		return fmt.Errorf("unknown action '%s': create, list, sweep or report", action)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+func runSpecDrift(args []string) error {
+	var (
+		conn           connectionFlags
+		basePath       string
+		asJSON         bool
+		failOnBreaking bool
+		timeout        time.Duration
+	)
+	fs := flag.NewFlagSet("spec-drift", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&basePath, "base", "", "spec to compare with (default: embedded api.yaml)")
+	fs.BoolVar(&asJSON, "json", false, "print the diffs as json")
+	fs.BoolVar(&failOnBreaking, "fail-on-breaking", true, "exit with code 3 if breaking changes are found")
+	fs.DurationVar(&timeout, "timeout", 30*time.Second, "timeout for fetching the schema")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	live, err := client.FetchServerSpec(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	base := paperless.EmbeddedSpec()
+	if basePath != "" {
+		base, err = os.ReadFile(basePath)
+		if err != nil {
+			return fmt.Errorf("failed to read base spec: %w", err)
+		}
+	}
+	diff, err := paperless.DiffSpecs(base, live)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(diff)
+	}
+
+	if failOnBreaking && diff.HasBreakingChanges() {
+		return exitError{code: 3, msg: "⚠️ server spec contains breaking changes"}
+	}
+	return nil
+}
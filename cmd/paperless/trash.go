@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+// parseAge accepts time.Duration values as well as days, e.g. "30d".
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age '%s'", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func runTrash(args []string) error {
+	var (
+		conn      connectionFlags
+		documents intList
+		olderThan string
+		all       bool
+		dryRun    bool
+		asJSON    bool
+		timeout   time.Duration
+	)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("missing action: list, restore, empty or sweep")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("trash "+action, flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&documents, "doc", "document id, repeatable or comma separated")
+	fs.BoolVar(&all, "all", false, "empty the whole trash")
+	fs.StringVar(&olderThan, "older-than", "", "retention period for sweep, e.g. 30d or 720h")
+	fs.BoolVar(&dryRun, "dry-run", false, "report what would be done without changing anything")
+	fs.BoolVar(&asJSON, "json", false, "print the report as json")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "timeout for the whole operation")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	opts := paperless.TrashOptions{DryRun: dryRun}
+
+	var report *paperless.TrashReport
+	switch action {
+	case "list":
+		trashed, err := client.ListTrash(ctx)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return json.NewEncoder(os.Stdout).Encode(trashed)
+		}
+		for _, document := range trashed {
+			title := ""
+			if document.Title != nil {
+				title = *document.Title
+			}
+			deleted := ""
+			if document.DeletedAt != nil {
+				deleted = document.DeletedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d\t%s\t%s\n", *document.Id, deleted, title)
+		}
+		return nil
+	case "restore":
+		report, err = client.RestoreFromTrash(ctx, documents, opts)
+	case "empty":
+		if len(documents) == 0 && !all {
+			return fmt.Errorf("missing documents (-doc), use -all to empty the whole trash")
+		}
+		report, err = client.EmptyTrash(ctx, documents, opts)
+	case "sweep":
+		if olderThan == "" {
+			return fmt.Errorf("missing retention period (-older-than)")
+		}
+		var age time.Duration
+		age, err = parseAge(olderThan)
+		if err != nil {
+			return err
+		}
+		report, err = client.SweepTrash(ctx, paperless.TrashSweepOptions{TrashOptions: opts, OlderThan: age})
+	default:
+		return fmt.Errorf("unknown action '%s'", action)
+	}
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	fmt.Println(report)
+	return nil
+}
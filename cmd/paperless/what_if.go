@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+)
+
+// intList collects repeated or comma separated ints.
+type intList []int
+
+func (l *intList) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *intList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("invalid id '%s'", part)
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
+
+// stringList collects repeated flags.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func parseMatchingAlgorithm(value string) (paperless.MatchingAlgorithm, error) {
+	for algorithm := paperless.MatchingNone; algorithm <= paperless.MatchingAuto; algorithm++ {
+		if value == algorithm.String() || value == strconv.Itoa(int(algorithm)) {
+			return algorithm, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown matching algorithm '%s'", value)
+}
+
+func runWhatIf(args []string) error {
+	var (
+		conn        connectionFlags
+		documents   intList
+		files       stringList
+		kinds       string
+		ruleKind    string
+		ruleName    string
+		match       string
+		algorithm   string
+		insensitive bool
+		verbose     bool
+		timeout     time.Duration
+	)
+	fs := flag.NewFlagSet("what-if", flag.ContinueOnError)
+	conn.register(fs)
+	fs.Var(&documents, "doc", "id(s) of documents whose content to match, repeatable")
+	fs.Var(&files, "file", "local text file to match, repeatable")
+	fs.StringVar(&kinds, "kinds", "tags,correspondents,document_types,storage_paths", "kinds whose rules to apply, add workflows for triggers")
+	fs.StringVar(&ruleKind, "kind", "tags", "kind of the rule to change")
+	fs.StringVar(&ruleName, "name", "", "name of the rule to change (or add), omit to only show current matches")
+	fs.StringVar(&match, "match", "", "changed match")
+	fs.StringVar(&algorithm, "algorithm", "", "changed matching algorithm (none, any, all, literal, regex, fuzzy or 0-6), default: unchanged")
+	fs.BoolVar(&insensitive, "insensitive", true, "changed rule matches case insensitive, default: unchanged")
+	fs.BoolVar(&verbose, "v", false, "show rules not matching as well")
+	fs.DurationVar(&timeout, "timeout", 2*time.Minute, "timeout for fetching rules and documents")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(documents) == 0 && len(files) == 0 {
+		return fmt.Errorf("nothing to match, use -doc or -file")
+	}
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	selected := make([]paperless.ObjectKind, 0)
+	for _, kind := range strings.Split(kinds, ",") {
+		selected = append(selected, paperless.ObjectKind(strings.TrimSpace(kind)))
+	}
+	current, err := client.FetchMatcher(ctx, selected...)
+	if err != nil {
+		return err
+	}
+
+	changed := current
+	if ruleName != "" {
+		// only an explicit -insensitive overrides the rule
+		var changedInsensitive *bool
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "insensitive" {
+				changedInsensitive = &insensitive
+			}
+		})
+		changed, err = changeRule(current, paperless.ObjectKind(ruleKind), ruleName, match, algorithm, changedInsensitive)
+		if err != nil {
+			return err
+		}
+	}
+
+	contents := make(map[string]string)
+	names := make([]string, 0)
+	for _, id := range documents {
+		content, err := client.FetchDocumentContent(ctx, id)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("document %d", id)
+		contents[name] = content
+		names = append(names, name)
+	}
+	for _, path := range files {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read '%s': %w", path, err)
+		}
+		contents[path] = string(raw)
+		names = append(names, path)
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+		before := current.Evaluate(contents[name])
+		after := changed.Evaluate(contents[name])
+		for idx, result := range after {
+			flipped := ruleName != "" && (idx >= len(before) && result.Matched ||
+				idx < len(before) && before[idx].Matched != result.Matched)
+			marker := " "
+			switch {
+			case flipped && result.Matched:
+				marker = "+"
+			case flipped:
+				marker = "-"
+			case !result.Matched && !verbose:
+				continue
+			}
+			simulated := ""
+			if !result.Simulated {
+				simulated = " (not simulated)"
+			}
+			fmt.Printf("  %s %s: %s%s\n", marker, result.Rule, result.Reason, simulated)
+		}
+	}
+	return nil
+}
+
+// changeRule returns a copy of the matcher with the named rule changed, or
+// added if missing. A nil insensitive keeps the case sensitivity of the rule.
+func changeRule(matcher *paperless.Matcher, kind paperless.ObjectKind, name, match, algorithm string, insensitive *bool) (*paperless.Matcher, error) {
+	output := &paperless.Matcher{Rules: append([]paperless.MatchingRule{}, matcher.Rules...)}
+	idx := -1
+	for ruleIdx, rule := range output.Rules {
+		if rule.Kind == kind && rule.Name == name {
+			idx = ruleIdx
+			break
+		}
+	}
+	if idx < 0 {
+		output.Rules = append(output.Rules, paperless.MatchingRule{Kind: kind, Name: name, Algorithm: paperless.MatchingAny, IsInsensitive: true})
+		idx = len(output.Rules) - 1
+	}
+	rule := &output.Rules[idx]
+	if match != "" {
+		rule.Match = match
+	}
+	if algorithm != "" {
+		parsed, err := parseMatchingAlgorithm(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		rule.Algorithm = parsed
+	}
+	if insensitive != nil {
+		rule.IsInsensitive = *insensitive
+	}
+	return output, nil
+}
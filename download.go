@@ -0,0 +1,64 @@
+package paperless
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DownloadedFile is a document file as served by paperless.
+type DownloadedFile struct {
+	// Filename is taken from the Content-Disposition header.
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// DownloadDocument fetches the archived version of a document, or the
+// original if original is set or no archived version exists.
+func (x XClient) DownloadDocument(ctx context.Context, id int, original bool) (*DownloadedFile, error) {
+	resp, err := x.DocumentsDownloadRetrieveWithResponse(ctx, id, &DocumentsDownloadRetrieveParams{Original: P(original)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download document %d: %w", id, err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, apiError("download document", resp.StatusCode(), resp.Body)
+	}
+	file := &DownloadedFile{
+		ContentType: resp.HTTPResponse.Header.Get("Content-Type"),
+		Content:     resp.Body,
+	}
+	_, params, err := mime.ParseMediaType(resp.HTTPResponse.Header.Get("Content-Disposition"))
+	if err == nil {
+		file.Filename = filepath.Base(params["filename"])
+	}
+	if file.Filename == "" || file.Filename == "." || file.Filename == "/" {
+		file.Filename = fmt.Sprintf("%d", id)
+		if extensions, _ := mime.ExtensionsByType(file.ContentType); len(extensions) > 0 {
+			file.Filename += extensions[0]
+		}
+	}
+	return file, nil
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^\p{L}\p{N}._ -]+`)
+
+// DownloadDocumentTo stores a document in dir as "<id>_<filename>" and
+// returns the path.
+func (x XClient) DownloadDocumentTo(ctx context.Context, id int, original bool, dir string) (string, error) {
+	file, err := x.DownloadDocument(ctx, id, original)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(unsafeFilenameChars.ReplaceAllString(file.Filename, "_"))
+	path := filepath.Join(dir, fmt.Sprintf("%d_%s", id, name))
+	err = os.WriteFile(path, file.Content, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to store document %d: %w", id, err)
+	}
+	return path, nil
+}
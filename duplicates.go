@@ -0,0 +1,187 @@
+package paperless
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultDuplicateConcurrency int = 4
+
+// FileChecksum returns the MD5 checksum paperless stores for originals.
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash '%s': %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FindByChecksum returns the document whose original equals the local file,
+// nil if there is none.
+func (x XClient) FindByChecksum(ctx context.Context, path string) (*Document, error) {
+	checksum, err := FileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := x.DocumentsListWithResponse(ctx, &DocumentsListParams{
+		ChecksumIexact: P(checksum),
+		PageSize:       P(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return nil, apiError("list documents", resp.StatusCode(), resp.Body)
+	}
+	if len(resp.JSON200.Results) == 0 {
+		return nil, nil
+	}
+	return &resp.JSON200.Results[0], nil
+}
+
+type UploadOptions struct {
+	Metadata *DocumentCreate
+	// SkipDuplicates looks the file up by checksum first and skips the
+	// upload if paperless has it already.
+	SkipDuplicates bool
+	// Filename sent to paperless, defaults to the name of the file.
+	Filename string
+}
+
+type UploadResult struct {
+	// TaskID of the consumption task, empty if the upload was skipped.
+	TaskID string
+	// DuplicateOf is the ID of the existing document if the upload was
+	// skipped.
+	DuplicateOf int
+}
+
+func (r UploadResult) Skipped() bool {
+	return r.TaskID == "" && r.DuplicateOf != 0
+}
+
+// UploadDocumentWithOptions uploads a file and returns the consumption task.
+func (x XClient) UploadDocumentWithOptions(ctx context.Context, filepath string, opts UploadOptions) (*UploadResult, error) {
+	if opts.SkipDuplicates {
+		existing, err := x.FindByChecksum(ctx, filepath)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.Id != nil {
+			return &UploadResult{DuplicateOf: *existing.Id}, nil
+		}
+	}
+	body, contentType, err := newFileMultipartBody("document", filepath, opts.Filename, opts.Metadata.Params())
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload document: %w", err)
+	}
+	docResp, err := x.ClientWithResponsesInterface.DocumentsPostDocumentCreateWithBodyWithResponse(ctx, contentType, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload document: %w", err)
+	}
+	if docResp.JSON200 == nil {
+		return nil, apiError("upload document", docResp.StatusCode(), docResp.Body)
+	}
+	return &UploadResult{TaskID: *docResp.JSON200}, nil
+}
+
+type DuplicateDocument struct {
+	ID               int    `json:"id"`
+	Title            string `json:"title"`
+	OriginalFilename string `json:"original_filename"`
+}
+
+type DuplicateGroup struct {
+	Checksum  string              `json:"checksum"`
+	Documents []DuplicateDocument `json:"documents"`
+}
+
+type DuplicateReport struct {
+	Scanned int              `json:"scanned"`
+	Groups  []DuplicateGroup `json:"groups"`
+}
+
+func (r *DuplicateReport) String() string {
+	lines := []string{fmt.Sprintf("%d documents scanned, %d sets of duplicates", r.Scanned, len(r.Groups))}
+	for _, group := range r.Groups {
+		lines = append(lines, group.Checksum)
+		for _, document := range group.Documents {
+			lines = append(lines, fmt.Sprintf("  %d %q (%s)", document.ID, document.Title, document.OriginalFilename))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type DuplicateOptions struct {
+	// Concurrency of metadata requests, defaults to 4.
+	Concurrency int
+}
+
+// FindDuplicates groups all documents by the checksum of their original. The
+// checksum is only part of the metadata, which is fetched per document.
+func (x XClient) FindDuplicates(ctx context.Context, opts DuplicateOptions) (*DuplicateReport, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultDuplicateConcurrency
+	}
+	documents, err := x.listDocuments(ctx, &DocumentsListParams{Fields: []string{"id", "title"}})
+	if err != nil {
+		return nil, err
+	}
+
+	mu := sync.Mutex{}
+	byChecksum := make(map[string][]DuplicateDocument)
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(opts.Concurrency)
+	for _, document := range documents {
+		group.Go(func() error {
+			resp, err := x.DocumentsMetadataRetrieveWithResponse(groupCtx, *document.Id)
+			if err != nil {
+				return fmt.Errorf("failed to fetch metadata of document %d: %w", *document.Id, err)
+			}
+			if resp.JSON200 == nil {
+				return apiError("fetch metadata", resp.StatusCode(), resp.Body)
+			}
+			entry := DuplicateDocument{ID: *document.Id, OriginalFilename: resp.JSON200.OriginalFilename}
+			if document.Title != nil {
+				entry.Title = *document.Title
+			}
+			mu.Lock()
+			byChecksum[resp.JSON200.OriginalChecksum] = append(byChecksum[resp.JSON200.OriginalChecksum], entry)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	report := &DuplicateReport{Scanned: len(documents), Groups: make([]DuplicateGroup, 0)}
+	for checksum, entries := range byChecksum {
+		if len(entries) < 2 || checksum == "" {
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].ID < entries[j].ID
+		})
+		report.Groups = append(report.Groups, DuplicateGroup{Checksum: checksum, Documents: entries})
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Documents[0].ID < report.Groups[j].Documents[0].ID
+	})
+	return report, nil
+}
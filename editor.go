@@ -0,0 +1,598 @@
+package paperless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEditorPollInterval time.Duration = 2 * time.Second
+	defaultEditorTimeout      time.Duration = 30 * time.Minute
+	pdfMimeType               string        = "application/pdf"
+)
+
+type DocumentEditorOptions struct {
+	// DeleteOriginals deletes the source documents once merge, split or
+	// edit_pdf created the new documents.
+	DeleteOriginals bool
+	// UpdateDocument makes edit_pdf modify the document in place instead of
+	// creating new documents.
+	UpdateDocument bool
+	// CarryTags, CarryCustomFields and CarryNotes copy the metadata of the
+	// source documents to the new documents.
+	CarryTags         bool
+	CarryCustomFields bool
+	CarryNotes        bool
+	// PollInterval of the task list, defaults to 2s.
+	PollInterval time.Duration
+	// Timeout limits the wait for the new documents or archive versions of
+	// an edit, defaults to 30m.
+	Timeout time.Duration
+}
+
+// DocumentEditor edits the PDFs of documents. paperless queues the new
+// documents as consumption tasks, the editor waits for them and returns
+// their IDs. In place edits return the IDs of the edited documents once
+// their archive versions are regenerated.
+type DocumentEditor struct {
+	client XClient
+	opts   DocumentEditorOptions
+}
+
+func (x XClient) NewDocumentEditor(opts DocumentEditorOptions) *DocumentEditor {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultEditorPollInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultEditorTimeout
+	}
+	return &DocumentEditor{client: x, opts: opts}
+}
+
+// PageRange is an inclusive range of 1-based pages.
+type PageRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (r PageRange) String() string {
+	if r.From == r.To {
+		return strconv.Itoa(r.From)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// ParsePageRanges parses ranges like "1-3,4,5-7".
+func ParsePageRanges(value string) ([]PageRange, error) {
+	output := make([]PageRange, 0)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid page range '%s'", part)
+		}
+		last, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return nil, fmt.Errorf("invalid page range '%s'", part)
+		}
+		output = append(output, PageRange{From: first, To: last})
+	}
+	return output, nil
+}
+
+// PageOperation places a 1-based page of the source into the new document
+// with the 0-based index Document, optionally rotated.
+type PageOperation struct {
+	Page     int `json:"page"`
+	Rotate   int `json:"rotate,omitempty"`
+	Document int `json:"doc"`
+}
+
+// editSource is a source document with the metadata to carry over, fetched
+// before the originals may be deleted.
+type editSource struct {
+	document Document
+	notes    []Note
+}
+
+// splitFilename matches the files paperless consumes for split and edited
+// documents, "<id>_<n>.pdf" and "<id>_edit_<n>.pdf".
+var splitFilename = regexp.MustCompile(`^(\d+)_(?:edit_)?(\d+)\.pdf$`)
+
+// Merge merges the documents in the given order into a new document.
+func (e *DocumentEditor) Merge(ctx context.Context, ids []int) (int, error) {
+	if err := e.client.RequireMethod(MethodEnumMerge); err != nil {
+		return 0, err
+	}
+	if len(ids) < 2 {
+		return 0, fmt.Errorf("merging requires at least two documents")
+	}
+	sources, err := e.sources(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	joined := strings.Join(parts, "_")
+	merged := joined[:min(len(joined), 100)] + "_merged.pdf"
+	created, err := e.submit(ctx, MethodEnumMerge, ids, map[string]interface{}{
+		"delete_originals": e.opts.DeleteOriginals,
+	}, 1, func(filename string) bool {
+		return filename == merged
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created[0], e.carryOver(ctx, sources, created)
+}
+
+// Split splits a document into a new document per page range.
+func (e *DocumentEditor) Split(ctx context.Context, id int, ranges []PageRange) ([]int, error) {
+	if err := e.client.RequireMethod(MethodEnumSplit); err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("missing page ranges to split document %d", id)
+	}
+	sources, err := e.sources(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, 0, len(ranges))
+	for _, pages := range ranges {
+		if pages.To < pages.From {
+			return nil, fmt.Errorf("invalid page range %s of document %d", pages, id)
+		}
+		if err := checkPage(sources[0].document, pages.From); err != nil {
+			return nil, err
+		}
+		if err := checkPage(sources[0].document, pages.To); err != nil {
+			return nil, err
+		}
+		parts = append(parts, pages.String())
+	}
+	created, err := e.submit(ctx, MethodEnumSplit, []int{id}, map[string]interface{}{
+		"pages":            strings.Join(parts, ","),
+		"delete_originals": e.opts.DeleteOriginals,
+	}, len(ranges), splitOf(id))
+	if err != nil {
+		return nil, err
+	}
+	return created, e.carryOver(ctx, sources, created)
+}
+
+// Rotate rotates all pages of the documents in place by a multiple of 90
+// degrees and returns the rotated documents. paperless rotates PDFs only,
+// other documents are skipped.
+func (e *DocumentEditor) Rotate(ctx context.Context, ids []int, degrees int) ([]int, error) {
+	if err := e.client.RequireMethod(MethodEnumRotate); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no documents to rotate")
+	}
+	if degrees%90 != 0 {
+		return nil, fmt.Errorf("invalid rotation of %d degrees, must be a multiple of 90", degrees)
+	}
+	pdfs := make([]int, 0, len(ids))
+	for _, id := range ids {
+		resp, err := e.client.DocumentsRetrieveWithResponse(ctx, id, &DocumentsRetrieveParams{Fields: []string{"id", "mime_type"}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch document %d: %w", id, err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("fetch document", resp.StatusCode(), resp.Body)
+		}
+		if isPDF(*resp.JSON200) {
+			pdfs = append(pdfs, id)
+		}
+	}
+	if len(pdfs) == 0 {
+		return nil, fmt.Errorf("none of the documents %v is a PDF", ids)
+	}
+	err := e.editInPlace(ctx, MethodEnumRotate, pdfs, map[string]interface{}{"degrees": degrees})
+	if err != nil {
+		return nil, err
+	}
+	return pdfs, nil
+}
+
+// DeletePages deletes 1-based pages of a document in place.
+func (e *DocumentEditor) DeletePages(ctx context.Context, id int, pages []int) ([]int, error) {
+	if err := e.client.RequireMethod(MethodEnumDeletePages); err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages to delete from document %d", id)
+	}
+	sources, err := e.sources(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	if !isPDF(sources[0].document) {
+		return nil, fmt.Errorf("cannot delete pages of document %d, it is not a PDF", id)
+	}
+	unique := make(map[int]bool)
+	for _, page := range pages {
+		if err := checkPage(sources[0].document, page); err != nil {
+			return nil, err
+		}
+		unique[page] = true
+	}
+	if count := sources[0].document.PageCount; count != nil && len(unique) >= *count {
+		return nil, fmt.Errorf("cannot delete all %d pages of document %d", *count, id)
+	}
+	err = e.editInPlace(ctx, MethodEnumDeletePages, []int{id}, map[string]interface{}{"pages": pages})
+	if err != nil {
+		return nil, err
+	}
+	return []int{id}, nil
+}
+
+// EditPDF rearranges, rotates and drops pages of a document. Pages without
+// an operation are dropped, each Document index becomes a new document
+// unless opts.UpdateDocument is set.
+func (e *DocumentEditor) EditPDF(ctx context.Context, id int, operations []PageOperation) ([]int, error) {
+	if err := e.client.RequireMethod(MethodEnumEditPdf); err != nil {
+		return nil, err
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("no operations to edit document %d", id)
+	}
+	sources, err := e.sources(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	documents := 0
+	for _, operation := range operations {
+		if err := checkPage(sources[0].document, operation.Page); err != nil {
+			return nil, err
+		}
+		if operation.Rotate%90 != 0 {
+			return nil, fmt.Errorf("invalid rotation of %d degrees for page %d, must be a multiple of 90", operation.Rotate, operation.Page)
+		}
+		if operation.Document < 0 {
+			return nil, fmt.Errorf("invalid document index %d for page %d", operation.Document, operation.Page)
+		}
+		documents = max(documents, operation.Document+1)
+	}
+	parameters := map[string]interface{}{
+		"operations":      operations,
+		"delete_original": e.opts.DeleteOriginals,
+		"update_document": e.opts.UpdateDocument,
+	}
+	if e.opts.UpdateDocument {
+		if !isPDF(sources[0].document) {
+			return nil, fmt.Errorf("cannot edit document %d in place, it is not a PDF", id)
+		}
+		if documents > 1 {
+			return nil, fmt.Errorf("editing document %d in place allows a single document only", id)
+		}
+		err = e.editInPlace(ctx, MethodEnumEditPdf, []int{id}, parameters)
+		if err != nil {
+			return nil, err
+		}
+		return []int{id}, nil
+	}
+	created, err := e.submit(ctx, MethodEnumEditPdf, []int{id}, parameters, documents, splitOf(id))
+	if err != nil {
+		return nil, err
+	}
+	return created, e.carryOver(ctx, sources, created)
+}
+
+func splitOf(id int) func(string) bool {
+	return func(filename string) bool {
+		match := splitFilename.FindStringSubmatch(filename)
+		return match != nil && match[1] == strconv.Itoa(id)
+	}
+}
+
+// isPDF reports whether the original of a document is a PDF, documents
+// without known mime type are assumed to be.
+func isPDF(document Document) bool {
+	return document.MimeType == nil || *document.MimeType == pdfMimeType
+}
+
+// checkPage validates a 1-based page against the page count, which is
+// unknown for documents paperless could not count the pages of.
+func checkPage(document Document, page int) error {
+	id := 0
+	if document.Id != nil {
+		id = *document.Id
+	}
+	if page < 1 {
+		return fmt.Errorf("invalid page %d of document %d", page, id)
+	}
+	if document.PageCount != nil && page > *document.PageCount {
+		return fmt.Errorf("page %d out of range, document %d has %d pages", page, id, *document.PageCount)
+	}
+	return nil
+}
+
+func (e *DocumentEditor) sources(ctx context.Context, ids []int) ([]editSource, error) {
+	output := make([]editSource, 0, len(ids))
+	for _, id := range ids {
+		resp, err := e.client.DocumentsRetrieveWithResponse(ctx, id, &DocumentsRetrieveParams{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch document %d: %w", id, err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("fetch document", resp.StatusCode(), resp.Body)
+		}
+		source := editSource{document: *resp.JSON200}
+		if source.document.Id == nil {
+			source.document.Id = P(id)
+		}
+		if e.opts.CarryNotes {
+			source.notes, err = e.client.ListNotes(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+		}
+		output = append(output, source)
+	}
+	return output, nil
+}
+
+// submit runs the bulk edit and waits for count consumption tasks of files
+// matching filename, which were not in the task list before.
+func (e *DocumentEditor) submit(ctx context.Context, method MethodEnum, ids []int, parameters map[string]interface{}, count int, filename func(string) bool) ([]int, error) {
+	known := make(map[string]bool)
+	if count > 0 {
+		tasks, err := e.consumeTasks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			known[task.TaskId] = true
+		}
+	}
+	resp, err := e.client.BulkEditWithResponse(ctx, BulkEditRequest{
+		Documents:  ids,
+		Method:     P(method),
+		Parameters: parameters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s documents: %w", method, err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, apiError(string(method), resp.StatusCode(), resp.Body)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(e.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		tasks, err := e.consumeTasks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]TasksView, 0, count)
+		done := 0
+		for _, task := range tasks {
+			if known[task.TaskId] || task.TaskFileName == nil || !filename(*task.TaskFileName) {
+				continue
+			}
+			matched = append(matched, task)
+			switch {
+			case task.Status == nil:
+			case *task.Status == StatusEnumFAILURE || *task.Status == StatusEnumREVOKED:
+				result := ""
+				if task.Result != nil {
+					result = *task.Result
+				}
+				return nil, fmt.Errorf("%s of documents %v failed: task '%s' has status %s: %s", method, ids, task.TaskId, *task.Status, result)
+			case *task.Status == StatusEnumSUCCESS:
+				done++
+			}
+		}
+		if done >= count && len(matched) >= count {
+			return createdDocuments(matched)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s of documents %v timed out: %d of %d documents consumed", method, ids, done, count)
+		case <-ticker.C:
+		}
+	}
+}
+
+// editInPlace runs a bulk edit modifying the documents and waits for their
+// archive versions. paperless saves the edited document, and so its modified
+// time, right away and regenerates content and archive version in a task
+// missing in the task list, so the archive checksum is polled instead.
+// Documents without archive version are not waited for.
+func (e *DocumentEditor) editInPlace(ctx context.Context, method MethodEnum, ids []int, parameters map[string]interface{}) error {
+	checksums := make(map[int]string, len(ids))
+	for _, id := range ids {
+		checksum, err := e.archiveChecksum(ctx, id)
+		if err != nil {
+			return err
+		}
+		if checksum != "" {
+			checksums[id] = checksum
+		}
+	}
+	if _, err := e.submit(ctx, method, ids, parameters, 0, nil); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(e.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		for id, previous := range checksums {
+			checksum, err := e.archiveChecksum(ctx, id)
+			if err != nil && ctx.Err() == nil {
+				return err
+			}
+			if err == nil && checksum != previous {
+				delete(checksums, id)
+			}
+		}
+		if len(checksums) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s of documents %v timed out: %d archive versions not updated", method, ids, len(checksums))
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *DocumentEditor) archiveChecksum(ctx context.Context, id int) (string, error) {
+	resp, err := e.client.DocumentsMetadataRetrieveWithResponse(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch metadata of document %d: %w", id, err)
+	}
+	if resp.JSON200 == nil {
+		return "", apiError("fetch metadata", resp.StatusCode(), resp.Body)
+	}
+	return resp.JSON200.ArchiveChecksum, nil
+}
+
+func (e *DocumentEditor) consumeTasks(ctx context.Context) ([]TasksView, error) {
+	resp, err := e.client.TasksListWithResponse(ctx, &TasksListParams{TaskName: P(TasksListParamsTaskNameConsumeFile)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return nil, apiError("list tasks", resp.StatusCode(), resp.Body)
+	}
+	return resp.JSON200, nil
+}
+
+// createdDocuments returns the related documents of the tasks, ordered by the
+// part number of their files.
+func createdDocuments(tasks []TasksView) ([]int, error) {
+	part := func(task TasksView) int {
+		match := splitFilename.FindStringSubmatch(*task.TaskFileName)
+		if match == nil {
+			return 0
+		}
+		number, _ := strconv.Atoi(match[2])
+		return number
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return part(tasks[i]) < part(tasks[j])
+	})
+	output := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		if task.RelatedDocument == nil {
+			return nil, fmt.Errorf("task with id '%s' has no related document", task.TaskId)
+		}
+		id, err := strconv.Atoi(*task.RelatedDocument)
+		if err != nil {
+			return nil, fmt.Errorf("task with id '%s' has invalid related document '%s'", task.TaskId, *task.RelatedDocument)
+		}
+		output = append(output, id)
+	}
+	return output, nil
+}
+
+// carryOver adds the tags, custom fields and notes of the sources to the new
+// documents. Values already set on a new document are kept, custom fields of
+// earlier sources win.
+func (e *DocumentEditor) carryOver(ctx context.Context, sources []editSource, ids []int) error {
+	if !e.opts.CarryTags && !e.opts.CarryCustomFields && !e.opts.CarryNotes {
+		return nil
+	}
+	for _, id := range ids {
+		resp, err := e.client.DocumentsRetrieveWithResponse(ctx, id, &DocumentsRetrieveParams{})
+		if err != nil {
+			return fmt.Errorf("failed to fetch document %d: %w", id, err)
+		}
+		if resp.JSON200 == nil {
+			return apiError("fetch document", resp.StatusCode(), resp.Body)
+		}
+		target := resp.JSON200
+
+		patch := make(map[string]interface{})
+		if e.opts.CarryTags {
+			tags := append([]int{}, target.Tags...)
+			for _, source := range sources {
+				for _, tag := range source.document.Tags {
+					if !containsID(tags, tag) {
+						tags = append(tags, tag)
+					}
+				}
+			}
+			patch["tags"] = tags
+		}
+		if e.opts.CarryCustomFields {
+			fields := append([]CustomFieldInstance{}, target.CustomFields...)
+			set := make(map[int]bool)
+			for _, field := range fields {
+				set[field.Field] = true
+			}
+			for _, source := range sources {
+				for _, field := range source.document.CustomFields {
+					if !set[field.Field] {
+						set[field.Field] = true
+						fields = append(fields, field)
+					}
+				}
+			}
+			sort.Slice(fields, func(i, j int) bool {
+				return fields[i].Field < fields[j].Field
+			})
+			patch["custom_fields"] = fields
+		}
+		if len(patch) > 0 {
+			body, err := json.Marshal(patch)
+			if err != nil {
+				return err
+			}
+			patchResp, err := e.client.DocumentsPartialUpdateWithBodyWithResponse(ctx, id, "application/json", jsonBody(body))
+			if err != nil {
+				return fmt.Errorf("failed to update document %d: %w", id, err)
+			}
+			if patchResp.StatusCode() != 200 {
+				return apiError("update document", patchResp.StatusCode(), patchResp.Body)
+			}
+		}
+
+		if !e.opts.CarryNotes {
+			continue
+		}
+		existing, err := e.client.ListNotes(ctx, id)
+		if err != nil {
+			return err
+		}
+		texts := make(map[string]bool, len(existing))
+		for _, note := range existing {
+			texts[note.Text] = true
+		}
+		for _, source := range sources {
+			for _, note := range source.notes {
+				if texts[note.Text] {
+					continue
+				}
+				texts[note.Text] = true
+				if _, err := e.client.AddNote(ctx, id, note.Text); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
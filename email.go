@@ -0,0 +1,108 @@
+package paperless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// ErrEmailFailed is returned when paperless failed to send an email, mostly
+// because no SMTP server is configured (PAPERLESS_EMAIL_HOST).
+var ErrEmailFailed = errors.New("paperless failed to send the email, check its SMTP settings (PAPERLESS_EMAIL_HOST)")
+
+type EmailOptions struct {
+	// To are the recipients, either plain addresses or "Name <address>".
+	To      []string
+	Subject string
+	Message string
+	// UseArchiveVersion attaches the archive versions instead of the
+	// originals where available.
+	UseArchiveVersion bool
+}
+
+// addresses validates the recipients and returns them in the comma separated
+// form paperless expects.
+func (o EmailOptions) addresses() (string, error) {
+	if len(o.To) == 0 {
+		return "", fmt.Errorf("missing email recipients")
+	}
+	seen := make(map[string]bool)
+	addresses := make([]string, 0, len(o.To))
+	for _, recipient := range o.To {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(recipient))
+		if err != nil {
+			return "", fmt.Errorf("invalid email recipient '%s': %w", recipient, err)
+		}
+		address := strings.ToLower(parsed.Address)
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		addresses = append(addresses, parsed.Address)
+	}
+	return strings.Join(addresses, ","), nil
+}
+
+// EmailDocuments sends the documents as attachments of a single email.
+// Servers without the endpoint for several documents can only email single
+// documents.
+func (x XClient) EmailDocuments(ctx context.Context, ids []int, opts EmailOptions) error {
+	if err := x.RequireFeature(FeatureDocumentEmail); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no documents to email")
+	}
+	addresses, err := opts.addresses()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(opts.Subject) == "" {
+		return fmt.Errorf("missing email subject")
+	}
+	if strings.TrimSpace(opts.Message) == "" {
+		return fmt.Errorf("missing email message")
+	}
+
+	resp, err := x.EmailDocumentsWithResponse(ctx, EmailRequest{
+		Addresses:         addresses,
+		Documents:         ids,
+		Subject:           opts.Subject,
+		Message:           opts.Message,
+		UseArchiveVersion: P(opts.UseArchiveVersion),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to email documents: %w", err)
+	}
+	status := resp.StatusCode()
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		if len(ids) > 1 {
+			return fmt.Errorf("server can only email single documents: %w", ErrUnsupportedByServer)
+		}
+		single, err := x.DocumentsEmailCreateWithResponse(ctx, ids[0], EmailDocumentRequestRequest{
+			Addresses:         addresses,
+			Subject:           opts.Subject,
+			Message:           opts.Message,
+			UseArchiveVersion: P(opts.UseArchiveVersion),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to email document %d: %w", ids[0], err)
+		}
+		return emailError(single.StatusCode(), single.Body)
+	}
+	return emailError(status, resp.Body)
+}
+
+func emailError(status int, body []byte) error {
+	switch {
+	case status == http.StatusOK:
+		return nil
+	case status >= 500:
+		return fmt.Errorf("%w: %s", ErrEmailFailed, apiError("email documents", status, body))
+	default:
+		return apiError("email documents", status, body)
+	}
+}
@@ -0,0 +1,280 @@
+package paperless
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// named groups with a meaning of their own, groups prefixed with cf_ hold
+// custom field values
+const (
+	groupTitle         string = "title"
+	groupCreated       string = "created"
+	groupCorrespondent string = "correspondent"
+	groupDocumentType  string = "document_type"
+	groupTags          string = "tags"
+	groupCustomField   string = "cf_"
+)
+
+var defaultDateLayouts = []string{"2006-01-02", "20060102", "2006-01-02T15:04:05"}
+
+// FilenamePattern extracts metadata from filenames (without directory and
+// extension), e.g.
+//
+//	name: scanner
+//	pattern: '^(?P<created>\d{4}-\d{2}-\d{2})_(?P<correspondent>[^_]+)_(?P<document_type>[^_]+)_(?P<number>.+)$'
+//	title: '{document_type} {number}'
+//	tags: [scanned]
+//
+// Templates refer to named groups as {group}, fields without template take
+// the group of the same name.
+type FilenamePattern struct {
+	Name          string            `yaml:"name" json:"name"`
+	Pattern       string            `yaml:"pattern" json:"pattern"`
+	Title         string            `yaml:"title,omitempty" json:"title,omitempty"`
+	Correspondent string            `yaml:"correspondent,omitempty" json:"correspondent,omitempty"`
+	DocumentType  string            `yaml:"document_type,omitempty" json:"document_type,omitempty"`
+	Tags          []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	CustomFields  map[string]string `yaml:"custom_fields,omitempty" json:"custom_fields,omitempty"`
+	// DateLayouts parse the created group, defaults to 2006-01-02, 20060102
+	// and 2006-01-02T15:04:05.
+	DateLayouts []string `yaml:"date_layouts,omitempty" json:"date_layouts,omitempty"`
+	// Timezone of created dates, defaults to the local one.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// TagSeparator splits the tags group, defaults to ",".
+	TagSeparator string `yaml:"tag_separator,omitempty" json:"tag_separator,omitempty"`
+
+	regexp   *regexp.Regexp
+	location *time.Location
+}
+
+// FilenameExtractor applies the first matching pattern.
+type FilenameExtractor struct {
+	Patterns []FilenamePattern `yaml:"patterns" json:"patterns"`
+}
+
+func LoadFilenameExtractor(path string) (*FilenameExtractor, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filename patterns: %w", err)
+	}
+	extractor := &FilenameExtractor{}
+	err = yaml.Unmarshal(raw, extractor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode filename patterns: %w", err)
+	}
+	return NewFilenameExtractor(extractor.Patterns...)
+}
+
+func NewFilenameExtractor(patterns ...FilenamePattern) (*FilenameExtractor, error) {
+	extractor := &FilenameExtractor{Patterns: make([]FilenamePattern, 0, len(patterns))}
+	for idx, pattern := range patterns {
+		if pattern.Name == "" {
+			pattern.Name = fmt.Sprintf("pattern %d", idx+1)
+		}
+		compiled, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filename pattern '%s': %w", pattern.Name, err)
+		}
+		pattern.regexp = compiled
+		pattern.location = time.Local
+		if pattern.Timezone != "" {
+			pattern.location, err = time.LoadLocation(pattern.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timezone of filename pattern '%s': %w", pattern.Name, err)
+			}
+		}
+		if len(pattern.DateLayouts) == 0 {
+			pattern.DateLayouts = defaultDateLayouts
+		}
+		if pattern.TagSeparator == "" {
+			pattern.TagSeparator = ","
+		}
+		extractor.Patterns = append(extractor.Patterns, pattern)
+	}
+	return extractor, nil
+}
+
+// ExtractedMetadata is the metadata taken from a filename, by name.
+type ExtractedMetadata struct {
+	ManifestEntry
+	Pattern string `json:"pattern"`
+}
+
+var templateGroup = regexp.MustCompile(`\{(\w+)\}`)
+
+// Extract returns the metadata of the first pattern matching the filename,
+// nil if none matches.
+func (e *FilenameExtractor) Extract(path string) (*ExtractedMetadata, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, pattern := range e.Patterns {
+		if pattern.regexp == nil {
+			return nil, fmt.Errorf("filename pattern '%s' not compiled, use NewFilenameExtractor", pattern.Name)
+		}
+		match := pattern.regexp.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		groups := make(map[string]string)
+		for idx, group := range pattern.regexp.SubexpNames() {
+			if group != "" {
+				groups[group] = strings.TrimSpace(match[idx])
+			}
+		}
+		return pattern.metadata(path, groups)
+	}
+	return nil, nil
+}
+
+func (p FilenamePattern) metadata(path string, groups map[string]string) (*ExtractedMetadata, error) {
+	expand := func(template, group string) string {
+		if template == "" {
+			return groups[group]
+		}
+		return strings.TrimSpace(templateGroup.ReplaceAllStringFunc(template, func(ref string) string {
+			return groups[ref[1:len(ref)-1]]
+		}))
+	}
+	output := &ExtractedMetadata{Pattern: p.Name, ManifestEntry: ManifestEntry{
+		Path:          path,
+		Title:         expand(p.Title, groupTitle),
+		Correspondent: expand(p.Correspondent, groupCorrespondent),
+		DocumentType:  expand(p.DocumentType, groupDocumentType),
+	}}
+	if value := groups[groupCreated]; value != "" {
+		created, err := p.parseDate(value)
+		if err != nil {
+			return nil, err
+		}
+		output.Created = created.Format(time.RFC3339)
+	}
+	output.Tags = append(output.Tags, p.Tags...)
+	for _, tag := range strings.Split(groups[groupTags], p.TagSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			output.Tags = append(output.Tags, tag)
+		}
+	}
+	customFields := make(map[string]string)
+	for group, value := range groups {
+		if field, ok := strings.CutPrefix(group, groupCustomField); ok && value != "" {
+			customFields[field] = value
+		}
+	}
+	for field, template := range p.CustomFields {
+		if value := expand(template, ""); value != "" {
+			customFields[field] = value
+		}
+	}
+	if len(customFields) > 0 {
+		output.CustomFields = customFields
+	}
+	return output, nil
+}
+
+func (p FilenamePattern) parseDate(value string) (time.Time, error) {
+	for _, layout := range p.DateLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, p.location); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("filename pattern '%s': date '%s' matches none of %v", p.Name, value, p.DateLayouts)
+}
+
+// ExtractionPreview shows what would be uploaded for a file.
+type ExtractionPreview struct {
+	Path string `json:"path"`
+	// Extracted is nil if no pattern matched.
+	Extracted *ExtractedMetadata `json:"extracted,omitempty"`
+	// Metadata is the upload metadata with names resolved to IDs.
+	Metadata     *DocumentCreate `json:"metadata,omitempty"`
+	CustomFields map[int]string  `json:"custom_fields,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+func (p ExtractionPreview) String() string {
+	if p.Error != "" {
+		return fmt.Sprintf("❌ %s: %s", p.Path, p.Error)
+	}
+	if p.Extracted == nil {
+		return fmt.Sprintf("- %s: no pattern matches", p.Path)
+	}
+	lines := []string{fmt.Sprintf("✔ %s (%s)", p.Path, p.Extracted.Pattern)}
+	for _, field := range []struct{ name, value string }{
+		{"title", p.Extracted.Title},
+		{"created", p.Extracted.Created},
+		{"correspondent", p.Extracted.Correspondent},
+		{"document type", p.Extracted.DocumentType},
+		{"tags", strings.Join(p.Extracted.Tags, ", ")},
+	} {
+		if field.value != "" {
+			lines = append(lines, fmt.Sprintf("    %-14s %s", field.name+":", field.value))
+		}
+	}
+	for name, value := range p.Extracted.CustomFields {
+		lines = append(lines, fmt.Sprintf("    %-14s %s", name+":", value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PreviewExtraction extracts the metadata of the files and resolves names
+// to IDs without uploading anything.
+func (x XClient) PreviewExtraction(ctx context.Context, extractor *FilenameExtractor, paths []string) ([]ExtractionPreview, error) {
+	live, err := x.fetchLiveState(ctx, []ObjectKind{KindTags, KindCorrespondents, KindDocumentTypes, KindCustomFields})
+	if err != nil {
+		return nil, err
+	}
+	output := make([]ExtractionPreview, 0, len(paths))
+	for _, path := range paths {
+		preview := ExtractionPreview{Path: path}
+		preview.Extracted, err = extractor.Extract(path)
+		if err != nil {
+			preview.Error = err.Error()
+		} else if preview.Extracted != nil {
+			preview.Metadata, preview.CustomFields, err = preview.Extracted.resolve(live)
+			if err != nil {
+				preview.Error = err.Error()
+			}
+		}
+		output = append(output, preview)
+	}
+	return output, nil
+}
+
+// MetadataFromFilename returns the upload metadata extracted from the
+// filename, nil if no pattern matches. Custom field values can only be set
+// once the document exists, see SetCustomFields.
+func (x XClient) MetadataFromFilename(ctx context.Context, extractor *FilenameExtractor, path string) (*DocumentCreate, map[int]string, error) {
+	previews, err := x.PreviewExtraction(ctx, extractor, []string{path})
+	if err != nil {
+		return nil, nil, err
+	}
+	if previews[0].Error != "" {
+		return nil, nil, fmt.Errorf("failed to extract metadata of '%s': %s", path, previews[0].Error)
+	}
+	return previews[0].Metadata, previews[0].CustomFields, nil
+}
+
+// ManifestEntries extracts the metadata of the files for UploadBatch. Files
+// matching no pattern are uploaded without metadata.
+func (e *FilenameExtractor) ManifestEntries(paths []string) ([]ManifestEntry, error) {
+	output := make([]ManifestEntry, 0, len(paths))
+	for _, path := range paths {
+		extracted, err := e.Extract(path)
+		if err != nil {
+			return nil, err
+		}
+		if extracted == nil {
+			output = append(output, ManifestEntry{Path: path})
+			continue
+		}
+		output = append(output, extracted.ManifestEntry)
+	}
+	return output, nil
+}
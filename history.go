@@ -0,0 +1,323 @@
+package paperless
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HistoryAction string
+
+const (
+	HistoryCreate HistoryAction = "create"
+	HistoryUpdate HistoryAction = "update"
+	HistoryDelete HistoryAction = "delete"
+	HistoryAccess HistoryAction = "access"
+)
+
+// FieldChange is the change of one field. Many-to-many fields such as tags
+// report Added and Removed names instead of Before and After.
+type FieldChange struct {
+	Field   string   `json:"field"`
+	Before  string   `json:"before,omitempty"`
+	After   string   `json:"after,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// HistoryEntry is a typed audit log entry of a document.
+type HistoryEntry struct {
+	ID         int       `json:"id"`
+	DocumentID int       `json:"document"`
+	Timestamp  time.Time `json:"timestamp"`
+	// Actor is empty for changes made by the system, e.g. workflows.
+	Actor   string        `json:"actor,omitempty"`
+	ActorID int           `json:"actor_id,omitempty"`
+	Action  HistoryAction `json:"action"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// fields referencing objects, older paperless releases log their IDs
+var historyRefs = map[string]ObjectKind{
+	"tags":          KindTags,
+	"correspondent": KindCorrespondents,
+	"document_type": KindDocumentTypes,
+	"storage_path":  KindStoragePaths,
+}
+
+// listHistory fetches all audit log entries of a document. paperless answers
+// with a plain list instead of the paginated list of the spec, which the
+// generated client fails to decode.
+func (x XClient) listHistory(ctx context.Context, id int) ([]LogEntry, error) {
+	output := make([]LogEntry, 0)
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/api/documents/%d/history/?page=%d&page_size=%d", id, page, listPageSize)
+		req, err := x.newRawRequest(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := x.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch history of document %d: %w", id, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch history of document %d: %w", id, err)
+		}
+		if resp.StatusCode != 200 {
+			return nil, apiError("fetch history", resp.StatusCode, body)
+		}
+		var list PaginatedLogEntryList
+		if json.Unmarshal(body, &list) != nil {
+			var entries []LogEntry
+			err = json.Unmarshal(body, &entries)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode history of document %d: %w", id, err)
+			}
+			return append(output, entries...), nil
+		}
+		output = append(output, list.Results...)
+		if list.Next == nil {
+			return output, nil
+		}
+	}
+}
+
+// historyKinds are the kinds audit log entries refer to by id.
+var historyKinds = []ObjectKind{KindTags, KindCorrespondents, KindDocumentTypes, KindStoragePaths}
+
+// DocumentHistory returns the audit log of a document, oldest first, with
+// referenced objects resolved to names.
+func (x XClient) DocumentHistory(ctx context.Context, id int) ([]HistoryEntry, error) {
+	return x.DocumentsHistory(ctx, []int{id})
+}
+
+// DocumentsHistory returns the audit logs of several documents, ordered by
+// time. Referenced objects are fetched once for all documents.
+func (x XClient) DocumentsHistory(ctx context.Context, ids []int) ([]HistoryEntry, error) {
+	live, err := x.fetchLiveState(ctx, historyKinds)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]HistoryEntry, 0)
+	for _, id := range ids {
+		entries, err := x.listHistory(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			output = append(output, typedHistoryEntry(id, entry, live))
+		}
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Timestamp.Before(output[j].Timestamp)
+	})
+	return output, nil
+}
+
+func typedHistoryEntry(documentID int, entry LogEntry, live *liveState) HistoryEntry {
+	output := HistoryEntry{
+		ID:         entry.Id,
+		DocumentID: documentID,
+		Timestamp:  entry.Timestamp,
+		Actor:      entry.Actor.Username,
+		ActorID:    entry.Actor.Id,
+		Action:     HistoryAction(strings.ToLower(entry.Action)),
+		Changes:    make([]FieldChange, 0, len(entry.Changes)),
+	}
+	for field, value := range entry.Changes {
+		output.Changes = append(output.Changes, typedFieldChange(field, value, live))
+	}
+	sort.Slice(output.Changes, func(i, j int) bool {
+		return output.Changes[i].Field < output.Changes[j].Field
+	})
+	return output
+}
+
+// typedFieldChange decodes the change formats of paperless: [before, after]
+// for plain fields, {"type": "m2m", "operation": ..., "objects": [...]} for
+// many-to-many fields and {"type": "custom_field", "field": ..., "value": ...}
+// for custom fields.
+func typedFieldChange(field string, value interface{}, live *liveState) FieldChange {
+	change := FieldChange{Field: field}
+	kind, isRef := historyRefs[field]
+	resolve := func(value interface{}) string {
+		text := historyValue(value)
+		if !isRef {
+			return text
+		}
+		if id, err := strconv.Atoi(text); err == nil {
+			if name, ok := live.byID[kind][id]; ok {
+				return name
+			}
+		}
+		return text
+	}
+	switch typed := value.(type) {
+	case []interface{}:
+		if len(typed) == 2 {
+			change.Before = resolve(typed[0])
+			change.After = resolve(typed[1])
+		}
+	case map[string]interface{}:
+		switch typed["type"] {
+		case "m2m":
+			objects, _ := typed["objects"].([]interface{})
+			names := make([]string, 0, len(objects))
+			for _, object := range objects {
+				names = append(names, resolve(object))
+			}
+			if typed["operation"] == "remove" || typed["operation"] == "clear" {
+				change.Removed = names
+			} else {
+				change.Added = names
+			}
+		case "custom_field":
+			change.Field = fmt.Sprintf("%s.%s", field, historyValue(typed["field"]))
+			change.After = historyValue(typed["value"])
+		default:
+			change.After = historyValue(typed)
+		}
+	default:
+		change.After = historyValue(typed)
+	}
+	return change
+}
+
+// historyValue formats logged values, paperless logs missing values as "None".
+func historyValue(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if typed == "None" {
+			return ""
+		}
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	default:
+		raw, _ := json.Marshal(typed)
+		return string(raw)
+	}
+}
+
+// WriteHistoryCSV writes one row per field change, entries without changes
+// get a single row.
+func WriteHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"document", "id", "timestamp", "actor", "action", "field", "before", "after", "added", "removed"})
+	for _, entry := range entries {
+		prefix := []string{
+			strconv.Itoa(entry.DocumentID),
+			strconv.Itoa(entry.ID),
+			entry.Timestamp.Format(time.RFC3339),
+			entry.Actor,
+			string(entry.Action),
+		}
+		if len(entry.Changes) == 0 {
+			writer.Write(append(prefix, "", "", "", "", ""))
+			continue
+		}
+		for _, change := range entry.Changes {
+			writer.Write(append(append([]string{}, prefix...),
+				change.Field,
+				change.Before,
+				change.After,
+				strings.Join(change.Added, ","),
+				strings.Join(change.Removed, ","),
+			))
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteHistoryJSONL writes one JSON object per entry.
+func WriteHistoryJSONL(w io.Writer, entries []HistoryEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type TimelineKind string
+
+const (
+	TimelineHistory   TimelineKind = "history"
+	TimelineNote      TimelineKind = "note"
+	TimelineShareLink TimelineKind = "share_link"
+)
+
+// TimelineEvent is one event of a document timeline, exactly one of
+// History, Note and ShareLink is set.
+type TimelineEvent struct {
+	Time      time.Time     `json:"time"`
+	Kind      TimelineKind  `json:"kind"`
+	History   *HistoryEntry `json:"history,omitempty"`
+	Note      *Notes        `json:"note,omitempty"`
+	ShareLink *ShareLink    `json:"share_link,omitempty"`
+}
+
+// DocumentTimeline merges the history, notes and share links of a document
+// into one chronological view.
+func (x XClient) DocumentTimeline(ctx context.Context, id int) ([]TimelineEvent, error) {
+	history, err := x.DocumentHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]TimelineEvent, 0, len(history))
+	for idx := range history {
+		output = append(output, TimelineEvent{Time: history[idx].Timestamp, Kind: TimelineHistory, History: &history[idx]})
+	}
+
+	notesResp, err := x.DocumentsNotesListWithResponse(ctx, id, &DocumentsNotesListParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notes of document %d: %w", id, err)
+	}
+	if notesResp.StatusCode() != 200 {
+		return nil, apiError("fetch notes", notesResp.StatusCode(), notesResp.Body)
+	}
+	for idx := range notesResp.JSON200 {
+		note := &notesResp.JSON200[idx]
+		if note.Created != nil {
+			output = append(output, TimelineEvent{Time: *note.Created, Kind: TimelineNote, Note: note})
+		}
+	}
+
+	linksResp, err := x.DocumentShareLinksWithResponse(ctx, strconv.Itoa(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch share links of document %d: %w", id, err)
+	}
+	if linksResp.StatusCode() != 200 {
+		return nil, apiError("fetch share links", linksResp.StatusCode(), linksResp.Body)
+	}
+	for _, link := range linksResp.JSON200 {
+		if link.Created == nil {
+			continue
+		}
+		output = append(output, TimelineEvent{Time: *link.Created, Kind: TimelineShareLink, ShareLink: &ShareLink{
+			Created:    link.Created,
+			Document:   P(id),
+			Expiration: link.Expiration,
+			Id:         link.Id,
+			Slug:       link.Slug,
+		}})
+	}
+
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Time.Before(output[j].Time)
+	})
+	return output, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+const responseTypeSuffix string = "HTTPResponse"
+
+// deptrize turns pointer-to-slice and pointer-to-map fields of the generated
+// response types (e.g. 'JSON200 *[]Notes') into plain slices and maps and
+// fixes the assignments in the matching Parse...HTTPResponse functions. A nil
+// slice or map already tells 'absent', the pointer only gets in the way.
+func deptrize(src []byte) ([]byte, []string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "client.gen.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse generated code: %w", err)
+	}
+
+	// response type name -> de-pointerized field names
+	changed := make(map[string]map[string]bool)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if !strings.HasSuffix(typeSpec.Name.Name, responseTypeSuffix) {
+				continue
+			}
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range structType.Fields.List {
+				star, ok := field.Type.(*ast.StarExpr)
+				if !ok || !isContainer(star.X) {
+					continue
+				}
+				field.Type = star.X
+				for _, name := range field.Names {
+					if changed[typeSpec.Name.Name] == nil {
+						changed[typeSpec.Name.Name] = make(map[string]bool)
+					}
+					changed[typeSpec.Name.Name][name.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Parse") {
+			continue
+		}
+		fields, ok := changed[strings.TrimPrefix(fn.Name.Name, "Parse")]
+		if !ok {
+			continue
+		}
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			assign, ok := node.(*ast.AssignStmt)
+			if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+				return true
+			}
+			selector, ok := assign.Lhs[0].(*ast.SelectorExpr)
+			if !ok || !fields[selector.Sel.Name] {
+				return true
+			}
+			if unary, ok := assign.Rhs[0].(*ast.UnaryExpr); ok && unary.Op == token.AND {
+				assign.Rhs[0] = unary.X
+			}
+			return true
+		})
+	}
+
+	output := new(bytes.Buffer)
+	err = format.Node(output, fset, file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to print generated code: %w", err)
+	}
+
+	report := make([]string, 0)
+	for typeName, fields := range changed {
+		for field := range fields {
+			report = append(report, typeName+"."+field)
+		}
+	}
+	sort.Strings(report)
+	return output.Bytes(), report, nil
+}
+
+func isContainer(expr ast.Expr) bool {
+	switch typed := expr.(type) {
+	case *ast.ArrayType:
+		// slices only, arrays have a length
+		return typed.Len == nil
+	case *ast.MapType:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+// Command gen prepares the upstream paperless-ngx OpenAPI spec for
+// oapi-codegen and post-processes the generated client.
+//
+//	gen spec -in patch/api.yaml.orig -overlay patch/overlay.yaml -out api.yaml
+//	gen deptrize -in client.gen.go.orig -out client.gen.go
+//
+// Both steps work on the structure of their input rather than on line
+// numbers, so they keep working when upstream reorders the spec.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "spec":
+		err = runSpec(os.Args[2:])
+	case "deptrize":
+		err = runDeptrize(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s spec|deptrize [flags]\n", os.Args[0])
+	os.Exit(2)
+}
+
+func runSpec(args []string) error {
+	fs := flag.NewFlagSet("spec", flag.ExitOnError)
+	in := fs.String("in", "patch/api.yaml.orig", "upstream spec")
+	overlayPath := fs.String("overlay", "patch/overlay.yaml", "overlay to apply")
+	out := fs.String("out", "api.yaml", "patched spec")
+	fs.Parse(args)
+
+	overlay, err := loadOverlay(*overlayPath)
+	if err != nil {
+		return err
+	}
+	spec, err := os.ReadFile(*in)
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %w", err)
+	}
+	patched, err := overlay.apply(spec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, patched, 0o644)
+}
+
+func runDeptrize(args []string) error {
+	fs := flag.NewFlagSet("deptrize", flag.ExitOnError)
+	in := fs.String("in", "client.gen.go.orig", "code generated by oapi-codegen")
+	out := fs.String("out", "client.gen.go", "post-processed code")
+	fs.Parse(args)
+
+	src, err := os.ReadFile(*in)
+	if err != nil {
+		return fmt.Errorf("failed to read generated code: %w", err)
+	}
+	processed, fields, err := deptrize(src)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		log.Printf("de-pointerized %s", field)
+	}
+	return os.WriteFile(*out, processed, 0o644)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// overlay is a list of declarative changes to the upstream spec. Targets are
+// given as a list of mapping keys (or sequence indices) starting at the
+// document root, e.g. [components, schemas, Metadata, properties, lang].
+type overlay struct {
+	Actions []overlayAction `yaml:"actions"`
+}
+
+type overlayAction struct {
+	Description string   `yaml:"description"`
+	Target      []string `yaml:"target"`
+	// Replace substitutes the target node.
+	Replace yaml.Node `yaml:"replace,omitempty"`
+	// Update merges the given mapping into the target mapping.
+	Update yaml.Node `yaml:"update,omitempty"`
+	// Remove deletes the target node from its parent mapping.
+	Remove bool `yaml:"remove,omitempty"`
+}
+
+func loadOverlay(path string) (*overlay, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay: %w", err)
+	}
+	output := &overlay{}
+	err = yaml.Unmarshal(raw, output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode overlay '%s': %w", path, err)
+	}
+	return output, nil
+}
+
+func (o *overlay) apply(spec []byte) ([]byte, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(spec, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, fmt.Errorf("spec is not a single yaml document")
+	}
+	for _, action := range o.Actions {
+		err = action.apply(doc.Content[0])
+		if err != nil {
+			return nil, fmt.Errorf("overlay action '%s' failed: %w", action.Description, err)
+		}
+	}
+
+	output := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(output)
+	encoder.SetIndent(2)
+	// match the drf-spectacular output
+	encoder.CompactSeqIndent()
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	return output.Bytes(), nil
+}
+
+func (a overlayAction) apply(root *yaml.Node) error {
+	if len(a.Target) == 0 {
+		return fmt.Errorf("empty target")
+	}
+	operations := 0
+	for _, set := range []bool{a.Replace.Kind != 0, a.Update.Kind != 0, a.Remove} {
+		if set {
+			operations++
+		}
+	}
+	if operations != 1 {
+		return fmt.Errorf("exactly one of replace, update or remove required")
+	}
+
+	parent, err := lookup(root, a.Target[:len(a.Target)-1])
+	if err != nil {
+		return err
+	}
+	key := a.Target[len(a.Target)-1]
+	if parent.Kind != yaml.MappingNode {
+		return fmt.Errorf("parent of '%s' is no mapping", strings.Join(a.Target, "/"))
+	}
+	for idx := 0; idx+1 < len(parent.Content); idx += 2 {
+		if parent.Content[idx].Value != key {
+			continue
+		}
+		switch {
+		case a.Replace.Kind != 0:
+			parent.Content[idx+1] = &a.Replace
+		case a.Update.Kind != 0:
+			return merge(parent.Content[idx+1], &a.Update)
+		case a.Remove:
+			parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+		}
+		return nil
+	}
+	// upstream moved things around, the overlay has to be revisited
+	return fmt.Errorf("target '%s' not found", strings.Join(a.Target, "/"))
+}
+
+func lookup(node *yaml.Node, path []string) (*yaml.Node, error) {
+	current := node
+	for depth, key := range path {
+		var next *yaml.Node
+		switch current.Kind {
+		case yaml.MappingNode:
+			for idx := 0; idx+1 < len(current.Content); idx += 2 {
+				if current.Content[idx].Value == key {
+					next = current.Content[idx+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			var idx int
+			if _, err := fmt.Sscanf(key, "%d", &idx); err == nil && idx >= 0 && idx < len(current.Content) {
+				next = current.Content[idx]
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("target '%s' not found", strings.Join(path[:depth+1], "/"))
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func merge(target, update *yaml.Node) error {
+	if target.Kind != yaml.MappingNode || update.Kind != yaml.MappingNode {
+		return fmt.Errorf("update requires mappings")
+	}
+	for idx := 0; idx+1 < len(update.Content); idx += 2 {
+		replaced := false
+		for tidx := 0; tidx+1 < len(target.Content); tidx += 2 {
+			if target.Content[tidx].Value == update.Content[idx].Value {
+				target.Content[tidx+1] = update.Content[idx+1]
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			target.Content = append(target.Content, update.Content[idx], update.Content[idx+1])
+		}
+	}
+	return nil
+}
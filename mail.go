@@ -0,0 +1,461 @@
+package paperless
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MailAttachment is a file part of a message. Inline parts are embedded in
+// the message body, e.g. logos of HTML mails.
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Inline      bool
+	Content     []byte
+}
+
+type MailMessage struct {
+	MessageID string
+	Subject   string
+	From      *mail.Address
+	To        string
+	Date      time.Time
+	// Text is the plain text body.
+	Text        string
+	Attachments []MailAttachment
+}
+
+var mailWordDecoder = &mime.WordDecoder{}
+
+func decodeMailHeader(value string) string {
+	decoded, err := mailWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// ParseMail parses a single RFC 5322 message, e.g. an .eml file.
+func ParseMail(r io.Reader) (*MailMessage, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	output := &MailMessage{
+		MessageID: strings.Trim(msg.Header.Get("Message-Id"), "<>"),
+		Subject:   decodeMailHeader(msg.Header.Get("Subject")),
+		To:        decodeMailHeader(msg.Header.Get("To")),
+	}
+	if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+		output.From = from[0]
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		output.Date = date
+	}
+	err = output.walk(textprotoHeader(msg.Header), msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message '%s': %w", output.Subject, err)
+	}
+	return output, nil
+}
+
+// textprotoHeader adapts mail headers to the header interface of parts.
+type textprotoHeader map[string][]string
+
+func (h textprotoHeader) Get(key string) string {
+	return mail.Header(h).Get(key)
+}
+
+type partHeader interface {
+	Get(key string) string
+}
+
+// walk collects the text body and attachments of a (multipart) body.
+func (m *MailMessage) walk(header partHeader, body io.Reader) error {
+	contentType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		contentType = "text/plain"
+	}
+	if strings.HasPrefix(contentType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = m.walk(part.Header, part)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeMailHeader(filename)
+	if filename == "" && disposition != "attachment" {
+		if contentType == "text/plain" && m.Text == "" {
+			m.Text = string(content)
+		}
+		return nil
+	}
+	if filename == "" {
+		filename = "attachment"
+		if extensions, _ := mime.ExtensionsByType(contentType); len(extensions) > 0 {
+			filename += extensions[0]
+		}
+	}
+	m.Attachments = append(m.Attachments, MailAttachment{
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Inline:      disposition != "attachment",
+		Content:     content,
+	})
+	return nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// base64Cleaner drops the line breaks of base64 bodies.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	clean := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[clean] = b
+			clean++
+		}
+	}
+	if clean == 0 && n > 0 && err == nil {
+		return c.Read(p)
+	}
+	return clean, err
+}
+
+// ReadMbox parses all messages of an mbox archive.
+func ReadMbox(r io.Reader) ([]*MailMessage, error) {
+	output := make([]*MailMessage, 0)
+	var current *bytes.Buffer
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		msg, err := ParseMail(current)
+		if err != nil {
+			return err
+		}
+		output = append(output, msg)
+		return nil
+	}
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if strings.HasPrefix(line, "From ") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = &bytes.Buffer{}
+		} else if current != nil {
+			// mboxrd escapes lines starting with "From " as ">From "
+			if trimmed := strings.TrimLeft(line, ">"); len(trimmed) < len(line) && strings.HasPrefix(trimmed, "From ") {
+				line = line[1:]
+			}
+			current.WriteString(line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mbox: %w", err)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// LoadMail reads an .mbox archive or a single message file.
+func LoadMail(path string) ([]*MailMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open '%s': %w", path, err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	prefix, _ := reader.Peek(5)
+	if strings.EqualFold(filepath.Ext(path), ".mbox") || string(prefix) == "From " {
+		return ReadMbox(reader)
+	}
+	msg, err := ParseMail(reader)
+	if err != nil {
+		return nil, err
+	}
+	return []*MailMessage{msg}, nil
+}
+
+// MailIngestOptions mirror the mail rules of paperless.
+type MailIngestOptions struct {
+	// AttachmentType defaults to attachments only, AttachmentTypeEnumN2
+	// includes inline parts.
+	AttachmentType AttachmentTypeEnum
+	// AssignTitleFrom defaults to the subject.
+	AssignTitleFrom AssignTitleFromEnum
+	// AssignCorrespondentFrom defaults to none, AssignCorrespondentFromEnumN2
+	// and N3 use the address and name of the sender, creating missing
+	// correspondents.
+	AssignCorrespondentFrom AssignCorrespondentFromEnum
+	// Correspondent is assigned with AssignCorrespondentFromEnumN4.
+	Correspondent int
+	// MimeTypes restricts attachments to matching types, e.g. "image/*".
+	MimeTypes []string
+	// FilenameInclude and FilenameExclude are case insensitive filename
+	// patterns, e.g. "*.pdf".
+	FilenameInclude string
+	FilenameExclude string
+	Tags            []int
+	SkipDuplicates  bool
+	// OriginalNote adds the headers and text of the message as a note.
+	OriginalNote bool
+	// TaskTimeout limits the wait for a single consumption task, defaults
+	// to 30m.
+	TaskTimeout time.Duration
+}
+
+type MailIngestResult struct {
+	MessageID   string `json:"message_id"`
+	Subject     string `json:"subject"`
+	Attachment  string `json:"attachment"`
+	DocumentID  int    `json:"document_id,omitempty"`
+	DuplicateOf int    `json:"duplicate_of,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (r MailIngestResult) String() string {
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("❌ %s / %s: %s", r.Subject, r.Attachment, r.Error)
+	case r.DuplicateOf != 0:
+		return fmt.Sprintf("- %s / %s: duplicate of %d", r.Subject, r.Attachment, r.DuplicateOf)
+	default:
+		return fmt.Sprintf("✔ %s / %s: document %d", r.Subject, r.Attachment, r.DocumentID)
+	}
+}
+
+// Matches reports whether the attachment passes the type and filename
+// filters.
+func (o MailIngestOptions) Matches(attachment MailAttachment) bool {
+	if attachment.Inline && o.AttachmentType != AttachmentTypeEnumN2 {
+		return false
+	}
+	name := strings.ToLower(attachment.Filename)
+	if o.FilenameInclude != "" {
+		if ok, _ := path.Match(strings.ToLower(o.FilenameInclude), name); !ok {
+			return false
+		}
+	}
+	if o.FilenameExclude != "" {
+		if ok, _ := path.Match(strings.ToLower(o.FilenameExclude), name); ok {
+			return false
+		}
+	}
+	if len(o.MimeTypes) == 0 {
+		return true
+	}
+	for _, pattern := range o.MimeTypes {
+		if ok, _ := path.Match(pattern, attachment.ContentType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IngestMail uploads the matching attachments of the messages with title,
+// created date and correspondent taken from the message. Failures are
+// reported per attachment.
+func (x XClient) IngestMail(ctx context.Context, messages []*MailMessage, opts MailIngestOptions) ([]MailIngestResult, error) {
+	if opts.TaskTimeout <= 0 {
+		opts.TaskTimeout = defaultBatchTaskTimeout
+	}
+	live, err := x.fetchLiveState(ctx, []ObjectKind{KindCorrespondents})
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "paperless-mail-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := make([]MailIngestResult, 0)
+	for idx, msg := range messages {
+		// attachments keep their name, paperless takes the original filename
+		// and the default title from it
+		msgDir := filepath.Join(dir, strconv.Itoa(idx))
+		if err := os.Mkdir(msgDir, 0o700); err != nil {
+			return output, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		for _, attachment := range msg.Attachments {
+			if !opts.Matches(attachment) {
+				continue
+			}
+			result := MailIngestResult{MessageID: msg.MessageID, Subject: msg.Subject, Attachment: attachment.Filename}
+			err := x.ingestAttachment(ctx, msg, attachment, filepath.Join(msgDir, filepath.Base(attachment.Filename)), live, opts, &result)
+			if err != nil {
+				if ctx.Err() != nil {
+					return output, ctx.Err()
+				}
+				result.Error = err.Error()
+			}
+			output = append(output, result)
+		}
+	}
+	return output, nil
+}
+
+func (x XClient) ingestAttachment(ctx context.Context, msg *MailMessage, attachment MailAttachment, tmpPath string, live *liveState, opts MailIngestOptions, result *MailIngestResult) error {
+	err := os.WriteFile(tmpPath, attachment.Content, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	metadata, err := x.mailMetadata(ctx, msg, attachment, live, opts)
+	if err != nil {
+		return err
+	}
+	upload, err := x.UploadDocumentWithOptions(ctx, tmpPath, UploadOptions{Metadata: metadata, SkipDuplicates: opts.SkipDuplicates})
+	if err != nil {
+		return err
+	}
+	if upload.Skipped() {
+		result.DuplicateOf = upload.DuplicateOf
+		return nil
+	}
+	taskCtx, cancel := context.WithTimeout(ctx, opts.TaskTimeout)
+	defer cancel()
+	result.DocumentID, err = x.WaitForTaskDocument(taskCtx, upload.TaskID, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	if opts.OriginalNote {
+		_, err = x.AddNote(ctx, result.DocumentID, msg.noteText())
+		return err
+	}
+	return nil
+}
+
+func (x XClient) mailMetadata(ctx context.Context, msg *MailMessage, attachment MailAttachment, live *liveState, opts MailIngestOptions) (*DocumentCreate, error) {
+	metadata := &DocumentCreate{}
+	switch opts.AssignTitleFrom {
+	case AssignTitleFromEnumN2:
+		metadata.Title = P(strings.TrimSuffix(attachment.Filename, filepath.Ext(attachment.Filename)))
+	case AssignTitleFromEnumN3:
+	default:
+		if msg.Subject != "" {
+			metadata.Title = P(msg.Subject)
+		}
+	}
+	if !msg.Date.IsZero() {
+		metadata.Created = P(msg.Date)
+	}
+	for _, tag := range opts.Tags {
+		metadata.Tags = append(metadata.Tags, fmt.Sprintf("%d", tag))
+	}
+
+	name := ""
+	switch opts.AssignCorrespondentFrom {
+	case AssignCorrespondentFromEnumN2:
+		if msg.From != nil {
+			name = msg.From.Address
+		}
+	case AssignCorrespondentFromEnumN3:
+		if msg.From != nil {
+			name = msg.From.Name
+			if name == "" {
+				name = msg.From.Address
+			}
+		}
+	case AssignCorrespondentFromEnumN4:
+		if opts.Correspondent != 0 {
+			metadata.Correspondent = P(opts.Correspondent)
+		}
+	}
+	if name == "" {
+		return metadata, nil
+	}
+	id, ok := live.byName[KindCorrespondents][name]
+	if !ok {
+		body, _ := json.Marshal(map[string]interface{}{"name": name})
+		object, err := objectKindSpecs[KindCorrespondents].create(ctx, x, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create correspondent '%s': %w", name, err)
+		}
+		live.add(KindCorrespondents, object)
+		id = live.byName[KindCorrespondents][name]
+	}
+	metadata.Correspondent = P(id)
+	return metadata, nil
+}
+
+// noteText renders the original message for a note.
+func (m *MailMessage) noteText() string {
+	lines := []string{"Original message"}
+	if m.From != nil {
+		lines = append(lines, "From: "+m.From.String())
+	}
+	for _, header := range []struct{ name, value string }{
+		{"To", m.To},
+		{"Subject", m.Subject},
+		{"Message-ID", m.MessageID},
+	} {
+		if header.value != "" {
+			lines = append(lines, header.name+": "+header.value)
+		}
+	}
+	if !m.Date.IsZero() {
+		lines = append(lines, "Date: "+m.Date.Format(time.RFC1123Z))
+	}
+	if text := strings.TrimSpace(m.Text); text != "" {
+		lines = append(lines, "", text)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,403 @@
+package paperless
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MatchingNone    MatchingAlgorithm = MatchingAlgorithmN0
+	MatchingAny     MatchingAlgorithm = MatchingAlgorithmN1
+	MatchingAll     MatchingAlgorithm = MatchingAlgorithmN2
+	MatchingLiteral MatchingAlgorithm = MatchingAlgorithmN3
+	MatchingRegex   MatchingAlgorithm = MatchingAlgorithmN4
+	MatchingFuzzy   MatchingAlgorithm = MatchingAlgorithmN5
+	MatchingAuto    MatchingAlgorithm = MatchingAlgorithmN6
+)
+
+func (m MatchingAlgorithm) String() string {
+	switch m {
+	case MatchingNone:
+		return "none"
+	case MatchingAny:
+		return "any"
+	case MatchingAll:
+		return "all"
+	case MatchingLiteral:
+		return "literal"
+	case MatchingRegex:
+		return "regex"
+	case MatchingFuzzy:
+		return "fuzzy"
+	case MatchingAuto:
+		return "auto"
+	}
+	return fmt.Sprintf("unknown(%d)", int(m))
+}
+
+// minimum rapidfuzz partial_ratio paperless requires for fuzzy matches
+const fuzzyScoreCutoff float64 = 90
+
+// MatchingRule is the matching configuration of a tag, correspondent,
+// document type, storage path or workflow trigger.
+type MatchingRule struct {
+	Kind          ObjectKind
+	ID            int
+	Name          string
+	Match         string
+	Algorithm     MatchingAlgorithm
+	IsInsensitive bool
+}
+
+func (r MatchingRule) String() string {
+	return fmt.Sprintf("%s '%s' (%s '%s')", r.Kind, r.Name, r.Algorithm, r.Match)
+}
+
+// MatchResult tells whether a rule matches and why.
+type MatchResult struct {
+	Rule    MatchingRule
+	Matched bool
+	// Reason explains the outcome like the paperless log does.
+	Reason string
+	// Matches are the words or strings found in the content.
+	Matches []string
+	// Simulated is false if the outcome cannot be predicted locally, e.g. for
+	// the auto algorithm or regular expressions go does not support.
+	Simulated bool
+}
+
+// Evaluate applies the rule to document content the way paperless-ngx
+// (documents/matching.py) does.
+func (r MatchingRule) Evaluate(content string) MatchResult {
+	result := MatchResult{Rule: r, Simulated: true}
+	if strings.TrimSpace(r.Match) == "" && r.Algorithm != MatchingAuto {
+		result.Reason = "empty match"
+		return result
+	}
+	flags := ""
+	if r.IsInsensitive {
+		flags = "(?i)"
+	}
+
+	switch r.Algorithm {
+	case MatchingNone:
+		result.Reason = "matching disabled"
+	case MatchingAny:
+		for _, word := range splitMatch(r.Match) {
+			found, ok := findWord(flags+word, content)
+			if ok {
+				result.Matched = true
+				result.Matches = []string{found}
+				result.Reason = fmt.Sprintf("contains the word '%s'", found)
+				return result
+			}
+		}
+		result.Reason = "contains none of the words"
+	case MatchingAll:
+		for _, word := range splitMatch(r.Match) {
+			found, ok := findWord(flags+word, content)
+			if !ok {
+				result.Matches = nil
+				result.Reason = fmt.Sprintf("misses the word '%s'", unquoteWord(word))
+				return result
+			}
+			result.Matches = append(result.Matches, found)
+		}
+		result.Matched = true
+		result.Reason = fmt.Sprintf("contains all words: %s", strings.Join(result.Matches, ", "))
+	case MatchingLiteral:
+		found, ok := findWord(flags+regexp.QuoteMeta(r.Match), content)
+		result.Matched = ok
+		if ok {
+			result.Matches = []string{found}
+			result.Reason = fmt.Sprintf("contains '%s'", found)
+		} else {
+			result.Reason = fmt.Sprintf("does not contain '%s'", r.Match)
+		}
+	case MatchingRegex:
+		pattern, err := regexp.Compile(flags + r.Match)
+		if err != nil {
+			// python supports lookarounds and backreferences, go does not
+			result.Simulated = false
+			result.Reason = fmt.Sprintf("regular expression not supported by simulator: %v", err)
+			return result
+		}
+		found := pattern.FindString(content)
+		result.Matched = pattern.MatchString(content)
+		if result.Matched {
+			result.Matches = []string{found}
+			result.Reason = fmt.Sprintf("the string '%s' matches the regular expression %s", found, r.Match)
+		} else {
+			result.Reason = fmt.Sprintf("nothing matches the regular expression %s", r.Match)
+		}
+	case MatchingFuzzy:
+		match := stripPunctuation(r.Match)
+		text := stripPunctuation(content)
+		if r.IsInsensitive {
+			match = strings.ToLower(match)
+			text = strings.ToLower(text)
+		}
+		score, found := partialRatio(match, text)
+		result.Matched = score >= fuzzyScoreCutoff
+		if result.Matched {
+			result.Matches = []string{found}
+			result.Reason = fmt.Sprintf("'%s' is similar to '%s' (score %.1f)", found, match, score)
+		} else {
+			result.Reason = fmt.Sprintf("best fuzzy score %.1f below %.0f", score, fuzzyScoreCutoff)
+		}
+	case MatchingAuto:
+		result.Simulated = false
+		result.Reason = "assigned by the trained classifier, not simulated"
+	default:
+		result.Simulated = false
+		result.Reason = fmt.Sprintf("unknown matching algorithm %d", r.Algorithm)
+	}
+	return result
+}
+
+var (
+	matchTerms  = regexp.MustCompile(`"([^"]+)"|(\S+)`)
+	matchSpaces = regexp.MustCompile(`\s+`)
+)
+
+// splitMatch splits the match into word patterns, quoted words stay
+// together:
+//
+//	'  some random  words "with   quotes  " and   spaces'
+//	  -> some, random, words, with\s+quotes, and, spaces
+func splitMatch(match string) []string {
+	output := make([]string, 0)
+	for _, groups := range matchTerms.FindAllStringSubmatch(match, -1) {
+		term := groups[1]
+		if term == "" {
+			term = groups[2]
+		}
+		term = matchSpaces.ReplaceAllString(strings.TrimSpace(term), " ")
+		output = append(output, strings.ReplaceAll(regexp.QuoteMeta(term), " ", `\s+`))
+	}
+	return output
+}
+
+func unquoteWord(pattern string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(pattern, `\s+`, " "), `\`, "")
+}
+
+// findWord finds pattern surrounded by word boundaries. Go's \b only knows
+// ascii, python's knows unicode, so the boundaries are checked by hand.
+func findWord(pattern, content string) (string, bool) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", false
+	}
+	for offset := 0; offset <= len(content); {
+		loc := re.FindStringIndex(content[offset:])
+		if loc == nil {
+			return "", false
+		}
+		start, end := offset+loc[0], offset+loc[1]
+		if wordBoundary(content, start) && wordBoundary(content, end) && end > start {
+			return content[start:end], true
+		}
+		// retry one rune later, matches may overlap
+		_, size := utf8.DecodeRuneInString(content[start:])
+		offset = start + max(size, 1)
+	}
+	return "", false
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func wordBoundary(content string, idx int) bool {
+	before, after := false, false
+	if idx > 0 {
+		r, _ := utf8.DecodeLastRuneInString(content[:idx])
+		before = isWordRune(r)
+	}
+	if idx < len(content) {
+		r, _ := utf8.DecodeRuneInString(content[idx:])
+		after = isWordRune(r)
+	}
+	return before != after
+}
+
+// stripPunctuation mimics re.sub(r"[^\w\s]", "", text).
+func stripPunctuation(text string) string {
+	return strings.Map(func(r rune) rune {
+		if isWordRune(r) || unicode.IsSpace(r) {
+			return r
+		}
+		return -1
+	}, text)
+}
+
+// partialRatio is rapidfuzz's fuzz.partial_ratio: the best normalized indel
+// similarity of the shorter string against all equally long windows of the
+// longer one. It returns the score (0-100) and the best window.
+func partialRatio(a, b string) (float64, string) {
+	short, long := []rune(a), []rune(b)
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	if len(short) == 0 {
+		return 0, ""
+	}
+	best, bestWindow := 0.0, ""
+	// windows partially outside of long cover matches at the very start or end
+	for start := -len(short) + 1; start < len(long); start++ {
+		from, to := max(start, 0), min(start+len(short), len(long))
+		window := long[from:to]
+		score := 200 * float64(lcsLength(short, window)) / float64(len(short)+len(window))
+		if score > best {
+			best, bestWindow = score, string(window)
+			if best == 100 {
+				break
+			}
+		}
+	}
+	return best, bestWindow
+}
+
+func lcsLength(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				current[j] = previous[j-1] + 1
+			case previous[j] >= current[j-1]:
+				current[j] = previous[j]
+			default:
+				current[j] = current[j-1]
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Matcher predicts which objects paperless assigns automatically.
+type Matcher struct {
+	Rules []MatchingRule
+}
+
+// Evaluate applies all rules, including the ones not matching.
+func (m Matcher) Evaluate(content string) []MatchResult {
+	output := make([]MatchResult, 0, len(m.Rules))
+	for _, rule := range m.Rules {
+		output = append(output, rule.Evaluate(content))
+	}
+	return output
+}
+
+// Match returns the results of matching rules only. Paperless assigns all
+// matching tags, but only the first matching correspondent, document type and
+// storage path.
+func (m Matcher) Match(content string) []MatchResult {
+	output := make([]MatchResult, 0)
+	assigned := make(map[ObjectKind]bool)
+	for _, result := range m.Evaluate(content) {
+		if !result.Matched {
+			continue
+		}
+		switch result.Rule.Kind {
+		case KindCorrespondents, KindDocumentTypes, KindStoragePaths:
+			if assigned[result.Rule.Kind] {
+				continue
+			}
+			assigned[result.Rule.Kind] = true
+		}
+		output = append(output, result)
+	}
+	return output
+}
+
+// MatchingKinds are the kinds with matching rules.
+var MatchingKinds = []ObjectKind{KindTags, KindCorrespondents, KindDocumentTypes, KindStoragePaths}
+
+// FetchMatcher loads the matching rules of the given kinds, all of
+// MatchingKinds if none are given. KindWorkflows adds the content matching of
+// workflow triggers.
+func (x XClient) FetchMatcher(ctx context.Context, kinds ...ObjectKind) (*Matcher, error) {
+	if len(kinds) == 0 {
+		kinds = MatchingKinds
+	}
+	matcher := &Matcher{Rules: make([]MatchingRule, 0)}
+	for _, kind := range kinds {
+		spec, ok := objectKindSpecs[kind]
+		if !ok {
+			return nil, fmt.Errorf("no matching rules for %s", kind)
+		}
+		objects, err := spec.list(ctx, x)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		for _, object := range objects {
+			if kind == KindWorkflows {
+				matcher.Rules = append(matcher.Rules, workflowMatchingRules(object)...)
+				continue
+			}
+			matcher.Rules = append(matcher.Rules, matchingRuleFrom(kind, object))
+		}
+	}
+	return matcher, nil
+}
+
+func matchingRuleFrom(kind ObjectKind, object map[string]interface{}) MatchingRule {
+	rule := MatchingRule{Kind: kind}
+	rule.ID, _ = objectID(object)
+	rule.Name, _ = object["name"].(string)
+	rule.Match, _ = object["match"].(string)
+	// model defaults of paperless
+	rule.Algorithm = MatchingAny
+	if algorithm, ok := object["matching_algorithm"].(float64); ok {
+		rule.Algorithm = MatchingAlgorithm(algorithm)
+	}
+	rule.IsInsensitive = true
+	if insensitive, ok := object["is_insensitive"].(bool); ok {
+		rule.IsInsensitive = insensitive
+	}
+	return rule
+}
+
+func workflowMatchingRules(workflow map[string]interface{}) []MatchingRule {
+	output := make([]MatchingRule, 0)
+	name, _ := workflow["name"].(string)
+	triggers, _ := workflow["triggers"].([]interface{})
+	for idx, item := range triggers {
+		trigger, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule := matchingRuleFrom(KindWorkflows, trigger)
+		// most triggers filter on anything but content
+		if rule.Algorithm == MatchingNone || strings.TrimSpace(rule.Match) == "" {
+			continue
+		}
+		// the rule names the workflow, the id the trigger
+		rule.Name = fmt.Sprintf("%s trigger %d", name, idx+1)
+		output = append(output, rule)
+	}
+	return output
+}
+
+// FetchDocumentContent returns the text content of a document.
+func (x XClient) FetchDocumentContent(ctx context.Context, id int) (string, error) {
+	resp, err := x.DocumentsRetrieveWithResponse(ctx, id, &DocumentsRetrieveParams{Fields: []string{"id", "content"}})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch document %d: %w", id, err)
+	}
+	if resp.JSON200 == nil {
+		return "", apiError("fetch document", resp.StatusCode(), resp.Body)
+	}
+	if resp.JSON200.Content == nil {
+		return "", nil
+	}
+	return *resp.JSON200.Content, nil
+}
@@ -0,0 +1,448 @@
+package paperless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// LabeledDocument is a document with the labels it is expected to get. It is
+// either a document on the server (ID) or a local text file (File). Labels
+// left out are not evaluated, an empty tag list expects no tags.
+type LabeledDocument struct {
+	ID            int       `json:"id,omitempty" yaml:"id,omitempty"`
+	File          string    `json:"file,omitempty" yaml:"file,omitempty"`
+	Tags          *[]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Correspondent *string   `json:"correspondent,omitempty" yaml:"correspondent,omitempty"`
+	DocumentType  *string   `json:"document_type,omitempty" yaml:"document_type,omitempty"`
+}
+
+func (d LabeledDocument) String() string {
+	if d.File != "" {
+		return d.File
+	}
+	return fmt.Sprintf("document %d", d.ID)
+}
+
+// MatchingCorpus is a set of labeled documents, e.g.
+//
+//	documents:
+//	  - id: 12
+//	    tags: [invoice, paid]
+//	    correspondent: ACME
+//	  - file: corpus/receipt.txt
+//	    tags: [receipt]
+//	    document_type: Receipt
+type MatchingCorpus struct {
+	Documents []LabeledDocument `json:"documents" yaml:"documents"`
+}
+
+// LoadMatchingCorpus reads a YAML or JSON corpus, files are relative to it.
+func LoadMatchingCorpus(path string) (*MatchingCorpus, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	corpus := &MatchingCorpus{}
+	err = yaml.Unmarshal(raw, corpus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode corpus '%s': %w", path, err)
+	}
+	for idx, document := range corpus.Documents {
+		if (document.ID == 0) == (document.File == "") {
+			return nil, fmt.Errorf("corpus '%s' document %d: exactly one of id or file required", path, idx+1)
+		}
+		if document.File != "" && !filepath.IsAbs(document.File) {
+			corpus.Documents[idx].File = filepath.Join(filepath.Dir(path), document.File)
+		}
+	}
+	return corpus, nil
+}
+
+type SuiteSource string
+
+const (
+	// SuiteSuggestions asks the server (/api/documents/{id}/suggestions/),
+	// which includes the trained classifier.
+	SuiteSuggestions SuiteSource = "suggestions"
+	// SuiteLocal evaluates the rules locally, see Matcher.
+	SuiteLocal SuiteSource = "local"
+)
+
+type MatchingSuiteOptions struct {
+	Source SuiteSource
+	// Matcher for SuiteLocal, defaults to the rules on the server.
+	Matcher *Matcher
+}
+
+// LabelScore counts the predictions of one tag, correspondent or document
+// type.
+type LabelScore struct {
+	Kind           ObjectKind `json:"kind"`
+	Name           string     `json:"name"`
+	TruePositives  int        `json:"true_positives"`
+	FalsePositives int        `json:"false_positives"`
+	FalseNegatives int        `json:"false_negatives"`
+}
+
+// Precision is the share of correct predictions, 1 without predictions.
+func (s LabelScore) Precision() float64 {
+	if s.TruePositives+s.FalsePositives == 0 {
+		return 1
+	}
+	return float64(s.TruePositives) / float64(s.TruePositives+s.FalsePositives)
+}
+
+// Recall is the share of expected labels predicted, 1 without expectations.
+func (s LabelScore) Recall() float64 {
+	if s.TruePositives+s.FalseNegatives == 0 {
+		return 1
+	}
+	return float64(s.TruePositives) / float64(s.TruePositives+s.FalseNegatives)
+}
+
+func (s LabelScore) F1() float64 {
+	precision, recall := s.Precision(), s.Recall()
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// SuiteMistake is a label predicted but not expected (Missing false) or
+// expected but not predicted (Missing true).
+type SuiteMistake struct {
+	Document string     `json:"document"`
+	Kind     ObjectKind `json:"kind"`
+	Name     string     `json:"name"`
+	Missing  bool       `json:"missing"`
+}
+
+type SuiteReport struct {
+	Source    SuiteSource    `json:"source"`
+	Documents int            `json:"documents"`
+	Scores    []LabelScore   `json:"scores"`
+	Mistakes  []SuiteMistake `json:"mistakes"`
+	// Skipped lists documents the source cannot evaluate, e.g. local files
+	// for server suggestions.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Total sums up all label scores (micro average).
+func (r SuiteReport) Total() LabelScore {
+	total := LabelScore{Name: "total"}
+	for _, score := range r.Scores {
+		total.TruePositives += score.TruePositives
+		total.FalsePositives += score.FalsePositives
+		total.FalseNegatives += score.FalseNegatives
+	}
+	return total
+}
+
+func (r SuiteReport) String() string {
+	lines := []string{
+		fmt.Sprintf("%d documents via %s", r.Documents, r.Source),
+		fmt.Sprintf("  %-16s %-32s %9s %9s %9s", "kind", "name", "precision", "recall", "f1"),
+	}
+	for _, score := range append(r.Scores, r.Total()) {
+		lines = append(lines, fmt.Sprintf("  %-16s %-32s %9.3f %9.3f %9.3f", score.Kind, score.Name, score.Precision(), score.Recall(), score.F1()))
+	}
+	for _, mistake := range r.Mistakes {
+		what := "unexpected"
+		if mistake.Missing {
+			what = "missing"
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s %s '%s'", mistake.Document, what, mistake.Kind, mistake.Name))
+	}
+	for _, skipped := range r.Skipped {
+		lines = append(lines, fmt.Sprintf("  %s: skipped", skipped))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// suiteLabels are the predicted or expected labels of one document, nil if
+// not known.
+type suiteLabels struct {
+	tags          []string
+	correspondent *string
+	documentType  *string
+}
+
+// RunMatchingSuite compares the expected labels of the corpus with the
+// server suggestions or the local matcher.
+func (x XClient) RunMatchingSuite(ctx context.Context, corpus *MatchingCorpus, opts MatchingSuiteOptions) (*SuiteReport, error) {
+	names := make(map[ObjectKind]map[int]string)
+	for _, kind := range []ObjectKind{KindTags, KindCorrespondents, KindDocumentTypes} {
+		objects, err := objectKindSpecs[kind].list(ctx, x)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		names[kind] = make(map[int]string)
+		for _, object := range objects {
+			id, _ := objectID(object)
+			names[kind][id], _ = object["name"].(string)
+		}
+	}
+	idsToNames := func(kind ObjectKind, ids []int) []string {
+		output := make([]string, 0, len(ids))
+		for _, id := range ids {
+			name, ok := names[kind][id]
+			if !ok {
+				name = fmt.Sprintf("#%d", id)
+			}
+			output = append(output, name)
+		}
+		return output
+	}
+
+	matcher := opts.Matcher
+	if opts.Source == SuiteLocal && matcher == nil {
+		var err error
+		matcher, err = x.FetchMatcher(ctx, KindTags, KindCorrespondents, KindDocumentTypes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scorer := newSuiteScorer(opts.Source)
+	for _, document := range corpus.Documents {
+		var predicted suiteLabels
+		switch opts.Source {
+		case SuiteSuggestions:
+			if document.ID == 0 {
+				scorer.report.Skipped = append(scorer.report.Skipped, document.String())
+				continue
+			}
+			resp, err := x.DocumentsSuggestionsRetrieveWithResponse(ctx, document.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch suggestions of %s: %w", document, err)
+			}
+			if resp.JSON200 == nil {
+				return nil, apiError("fetch suggestions", resp.StatusCode(), resp.Body)
+			}
+			predicted.tags = idsToNames(KindTags, resp.JSON200.Tags)
+			predicted.correspondent = firstOf(idsToNames(KindCorrespondents, resp.JSON200.Correspondents))
+			predicted.documentType = firstOf(idsToNames(KindDocumentTypes, resp.JSON200.DocumentTypes))
+		case SuiteLocal:
+			content, err := x.corpusContent(ctx, document)
+			if err != nil {
+				return nil, err
+			}
+			predicted = matcher.predict(content)
+		default:
+			return nil, fmt.Errorf("unknown source '%s'", opts.Source)
+		}
+		scorer.add(document, predicted)
+	}
+	return scorer.finish(), nil
+}
+
+// RunLocalMatchingSuite evaluates a corpus of local files with the matcher,
+// without a server.
+func RunLocalMatchingSuite(corpus *MatchingCorpus, matcher *Matcher) (*SuiteReport, error) {
+	scorer := newSuiteScorer(SuiteLocal)
+	for _, document := range corpus.Documents {
+		if document.File == "" {
+			scorer.report.Skipped = append(scorer.report.Skipped, document.String())
+			continue
+		}
+		raw, err := os.ReadFile(document.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read corpus document: %w", err)
+		}
+		scorer.add(document, matcher.predict(string(raw)))
+	}
+	return scorer.finish(), nil
+}
+
+func (x XClient) corpusContent(ctx context.Context, document LabeledDocument) (string, error) {
+	if document.File != "" {
+		raw, err := os.ReadFile(document.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read corpus document: %w", err)
+		}
+		return string(raw), nil
+	}
+	return x.FetchDocumentContent(ctx, document.ID)
+}
+
+// predict assigns the labels Match picks, like the consumer does.
+func (m Matcher) predict(content string) suiteLabels {
+	labels := suiteLabels{tags: make([]string, 0)}
+	for _, result := range m.Match(content) {
+		name := result.Rule.Name
+		switch result.Rule.Kind {
+		case KindTags:
+			labels.tags = append(labels.tags, name)
+		case KindCorrespondents:
+			labels.correspondent = &name
+		case KindDocumentTypes:
+			labels.documentType = &name
+		}
+	}
+	return labels
+}
+
+func firstOf(names []string) *string {
+	if len(names) == 0 {
+		return nil
+	}
+	return &names[0]
+}
+
+type suiteScorer struct {
+	report *SuiteReport
+	scores map[ObjectKind]map[string]*LabelScore
+}
+
+func newSuiteScorer(source SuiteSource) *suiteScorer {
+	return &suiteScorer{
+		report: &SuiteReport{Source: source, Scores: make([]LabelScore, 0), Mistakes: make([]SuiteMistake, 0)},
+		scores: make(map[ObjectKind]map[string]*LabelScore),
+	}
+}
+
+func (s *suiteScorer) score(kind ObjectKind, name string) *LabelScore {
+	if s.scores[kind] == nil {
+		s.scores[kind] = make(map[string]*LabelScore)
+	}
+	if s.scores[kind][name] == nil {
+		s.scores[kind][name] = &LabelScore{Kind: kind, Name: name}
+	}
+	return s.scores[kind][name]
+}
+
+func (s *suiteScorer) compare(document string, kind ObjectKind, expected, predicted []string) {
+	want := make(map[string]bool)
+	for _, name := range expected {
+		want[name] = true
+	}
+	got := make(map[string]bool)
+	for _, name := range predicted {
+		got[name] = true
+		if want[name] {
+			s.score(kind, name).TruePositives++
+			continue
+		}
+		s.score(kind, name).FalsePositives++
+		s.report.Mistakes = append(s.report.Mistakes, SuiteMistake{Document: document, Kind: kind, Name: name})
+	}
+	for _, name := range expected {
+		if !got[name] {
+			s.score(kind, name).FalseNegatives++
+			s.report.Mistakes = append(s.report.Mistakes, SuiteMistake{Document: document, Kind: kind, Name: name, Missing: true})
+		}
+	}
+}
+
+func optionalList(value *string) []string {
+	if value == nil {
+		return nil
+	}
+	return []string{*value}
+}
+
+func (s *suiteScorer) add(document LabeledDocument, predicted suiteLabels) {
+	s.report.Documents++
+	if document.Tags != nil {
+		s.compare(document.String(), KindTags, *document.Tags, predicted.tags)
+	}
+	if document.Correspondent != nil {
+		expected := optionalList(document.Correspondent)
+		if *document.Correspondent == "" {
+			expected = nil
+		}
+		s.compare(document.String(), KindCorrespondents, expected, optionalList(predicted.correspondent))
+	}
+	if document.DocumentType != nil {
+		expected := optionalList(document.DocumentType)
+		if *document.DocumentType == "" {
+			expected = nil
+		}
+		s.compare(document.String(), KindDocumentTypes, expected, optionalList(predicted.documentType))
+	}
+}
+
+func (s *suiteScorer) finish() *SuiteReport {
+	for _, byName := range s.scores {
+		for _, score := range byName {
+			s.report.Scores = append(s.report.Scores, *score)
+		}
+	}
+	sort.Slice(s.report.Scores, func(i, j int) bool {
+		if s.report.Scores[i].Kind != s.report.Scores[j].Kind {
+			return s.report.Scores[i].Kind < s.report.Scores[j].Kind
+		}
+		return s.report.Scores[i].Name < s.report.Scores[j].Name
+	})
+	return s.report
+}
+
+// SuiteRegression is a label whose F1 score dropped compared to a baseline.
+type SuiteRegression struct {
+	Kind     ObjectKind `json:"kind"`
+	Name     string     `json:"name"`
+	Baseline float64    `json:"baseline"`
+	Current  float64    `json:"current"`
+}
+
+func (r SuiteRegression) String() string {
+	return fmt.Sprintf("%s '%s': f1 %.3f -> %.3f", r.Kind, r.Name, r.Baseline, r.Current)
+}
+
+// Regressions lists the labels (and the total) whose F1 score dropped by
+// more than maxDrop compared to the baseline.
+func (r SuiteReport) Regressions(baseline *SuiteReport, maxDrop float64) []SuiteRegression {
+	current := make(map[string]LabelScore)
+	for _, score := range r.Scores {
+		current[string(score.Kind)+"/"+score.Name] = score
+	}
+	output := make([]SuiteRegression, 0)
+	check := func(before, after LabelScore) {
+		if before.F1()-after.F1() > maxDrop {
+			output = append(output, SuiteRegression{Kind: before.Kind, Name: before.Name, Baseline: before.F1(), Current: after.F1()})
+		}
+	}
+	for _, before := range baseline.Scores {
+		// labels no longer part of the corpus are ignored
+		if after, ok := current[string(before.Kind)+"/"+before.Name]; ok {
+			check(before, after)
+		}
+	}
+	check(baseline.Total(), r.Total())
+	return output
+}
+
+func LoadSuiteReport(path string) (*SuiteReport, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	report := &SuiteReport{}
+	err = json.Unmarshal(raw, report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode report '%s': %w", path, err)
+	}
+	return report, nil
+}
+
+// Matcher returns a matcher of the tags, correspondents, document types and
+// storage paths in the desired state, to run suites against rules before
+// they are applied.
+func (s *DesiredState) Matcher() *Matcher {
+	matcher := &Matcher{Rules: make([]MatchingRule, 0)}
+	for _, kind := range MatchingKinds {
+		for _, object := range s.Objects[kind] {
+			plain := make(map[string]interface{})
+			_ = remarshal(object, &plain)
+			matcher.Rules = append(matcher.Rules, matchingRuleFrom(kind, plain))
+		}
+	}
+	return matcher
+}
@@ -0,0 +1,335 @@
+package paperless
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Note is a typed note of a document.
+type Note struct {
+	ID         int       `json:"id"`
+	DocumentID int       `json:"document"`
+	Text       string    `json:"note"`
+	Created    time.Time `json:"created"`
+	UserID     int       `json:"user_id,omitempty"`
+	Username   string    `json:"username,omitempty"`
+}
+
+func typedNote(documentID int, note Notes) Note {
+	output := Note{DocumentID: documentID}
+	if note.Id != nil {
+		output.ID = *note.Id
+	}
+	if note.Note != nil {
+		output.Text = *note.Note
+	}
+	if note.Created != nil {
+		output.Created = *note.Created
+	}
+	if note.User != nil {
+		if note.User.Id != nil {
+			output.UserID = *note.User.Id
+		}
+		output.Username = note.User.Username
+	}
+	return output
+}
+
+func sortNotes(notes []Note) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Created.Equal(notes[j].Created) {
+			return notes[i].ID < notes[j].ID
+		}
+		return notes[i].Created.Before(notes[j].Created)
+	})
+}
+
+// notesRequest sends a request to the notes endpoint of a document and
+// returns the notes of the answer. paperless answers creations and deletions
+// with the plain list of notes instead of the paginated list of the spec,
+// which the generated client fails to decode.
+func (x XClient) notesRequest(ctx context.Context, method string, documentID int, query url.Values, body interface{}) ([]Note, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	path := fmt.Sprintf("/api/documents/%d/notes/", documentID)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := x.newRawRequest(ctx, method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request notes of document %d: %w", documentID, err)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to request notes of document %d: %w", documentID, err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, apiError(strings.ToLower(method)+" notes", resp.StatusCode, raw)
+	}
+	var notes []Notes
+	if json.Unmarshal(raw, &notes) != nil {
+		var list PaginatedNotesList
+		err = json.Unmarshal(raw, &list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode notes of document %d: %w", documentID, err)
+		}
+		notes = list.Results
+	}
+	output := make([]Note, 0, len(notes))
+	for _, note := range notes {
+		output = append(output, typedNote(documentID, note))
+	}
+	sortNotes(output)
+	return output, nil
+}
+
+// ListNotes returns the notes of a document, oldest first.
+func (x XClient) ListNotes(ctx context.Context, documentID int) ([]Note, error) {
+	return x.notesRequest(ctx, http.MethodGet, documentID, nil, nil)
+}
+
+// AddNote adds a note to a document and returns it.
+func (x XClient) AddNote(ctx context.Context, documentID int, text string) (*Note, error) {
+	notes, err := x.notesRequest(ctx, http.MethodPost, documentID, nil, NoteCreateRequestRequest{Note: text})
+	if err != nil {
+		return nil, err
+	}
+	var added *Note
+	for idx := range notes {
+		if notes[idx].Text == text && (added == nil || notes[idx].ID > added.ID) {
+			added = &notes[idx]
+		}
+	}
+	if added == nil {
+		return nil, fmt.Errorf("added note missing in notes of document %d", documentID)
+	}
+	return added, nil
+}
+
+func (x XClient) DeleteNote(ctx context.Context, documentID, noteID int) error {
+	_, err := x.notesRequest(ctx, http.MethodDelete, documentID, url.Values{"id": {fmt.Sprint(noteID)}}, nil)
+	return err
+}
+
+// UpdateNote replaces the text of a note. paperless can't edit notes, so the
+// note is recreated; to keep the order of the notes, the notes created after
+// it are recreated as well. Recreated notes get new IDs and the current user
+// as author. It returns the notes of the document.
+func (x XClient) UpdateNote(ctx context.Context, documentID, noteID int, text string) ([]Note, error) {
+	notes, err := x.ListNotes(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	position := -1
+	for idx, note := range notes {
+		if note.ID == noteID {
+			position = idx
+		}
+	}
+	if position < 0 {
+		return nil, fmt.Errorf("note %d not found on document %d", noteID, documentID)
+	}
+	if notes[position].Text == text {
+		return notes, nil
+	}
+	recreate := append([]Note{}, notes[position:]...)
+	recreate[0].Text = text
+	// add before deleting, a failure must not lose notes
+	for _, note := range recreate {
+		_, err = x.AddNote(ctx, documentID, note.Text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, note := range recreate {
+		err = x.DeleteNote(ctx, documentID, note.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return x.ListNotes(ctx, documentID)
+}
+
+// DocumentNotes are the notes of a document as exported, identified by the
+// checksum of the original.
+type DocumentNotes struct {
+	DocumentID int    `json:"document"`
+	Title      string `json:"title"`
+	Checksum   string `json:"checksum"`
+	Notes      []Note `json:"notes"`
+}
+
+// ExportNotes returns the notes of all documents having notes.
+func (x XClient) ExportNotes(ctx context.Context) ([]DocumentNotes, error) {
+	documents, err := x.listDocuments(ctx, &DocumentsListParams{Fields: []string{"id", "title", "notes"}})
+	if err != nil {
+		return nil, err
+	}
+	output := make([]DocumentNotes, 0)
+	for _, document := range documents {
+		if document.Id == nil || len(document.Notes) == 0 {
+			continue
+		}
+		resp, err := x.DocumentsMetadataRetrieveWithResponse(ctx, *document.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch metadata of document %d: %w", *document.Id, err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("fetch metadata", resp.StatusCode(), resp.Body)
+		}
+		entry := DocumentNotes{DocumentID: *document.Id, Checksum: resp.JSON200.OriginalChecksum}
+		if document.Title != nil {
+			entry.Title = *document.Title
+		}
+		for _, note := range document.Notes {
+			entry.Notes = append(entry.Notes, typedNote(*document.Id, note))
+		}
+		sortNotes(entry.Notes)
+		output = append(output, entry)
+	}
+	return output, nil
+}
+
+// WriteNotesJSONL writes one line per document, readable by ReadNotesJSONL.
+func WriteNotesJSONL(w io.Writer, documents []DocumentNotes) error {
+	encoder := json.NewEncoder(w)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteNotesMarkdown writes a section per document.
+func WriteNotesMarkdown(w io.Writer, documents []DocumentNotes) error {
+	buffer := &bytes.Buffer{}
+	for _, document := range documents {
+		fmt.Fprintf(buffer, "## %s\n\ndocument %d, checksum `%s`\n\n", document.Title, document.DocumentID, document.Checksum)
+		for _, note := range document.Notes {
+			author := ""
+			if note.Username != "" {
+				author = " " + note.Username
+			}
+			fmt.Fprintf(buffer, "### %s%s\n\n%s\n\n", note.Created.Format(time.RFC3339), author, strings.TrimSpace(note.Text))
+		}
+	}
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+func ReadNotesJSONL(r io.Reader) ([]DocumentNotes, error) {
+	output := make([]DocumentNotes, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var document DocumentNotes
+		err := json.Unmarshal(scanner.Bytes(), &document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode notes (line %d): %w", line, err)
+		}
+		output = append(output, document)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+	return output, nil
+}
+
+type NotesImportOptions struct {
+	DryRun bool
+}
+
+type NotesImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Imported counts notes added, Skipped notes the document has already.
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	// Missing lists checksums without matching document.
+	Missing []string `json:"missing"`
+}
+
+func (r NotesImportReport) String() string {
+	prefix := ""
+	if r.DryRun {
+		prefix = "dry run: "
+	}
+	return fmt.Sprintf("%s%d notes imported, %d already present, %d documents missing", prefix, r.Imported, r.Skipped, len(r.Missing))
+}
+
+// ImportNotes adds exported notes to the documents with the same original
+// checksum, e.g. after a migration. Notes with a text the document has
+// already are skipped, so imports can be repeated.
+func (x XClient) ImportNotes(ctx context.Context, documents []DocumentNotes, opts NotesImportOptions) (*NotesImportReport, error) {
+	report := &NotesImportReport{DryRun: opts.DryRun, Missing: make([]string, 0)}
+	for _, exported := range documents {
+		// an empty checksum would not filter at all
+		if exported.Checksum == "" {
+			report.Missing = append(report.Missing, exported.Checksum)
+			continue
+		}
+		resp, err := x.DocumentsListWithResponse(ctx, &DocumentsListParams{
+			ChecksumIexact: P(exported.Checksum),
+			PageSize:       P(1),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("list documents", resp.StatusCode(), resp.Body)
+		}
+		if len(resp.JSON200.Results) == 0 || resp.JSON200.Results[0].Id == nil {
+			report.Missing = append(report.Missing, exported.Checksum)
+			continue
+		}
+		documentID := *resp.JSON200.Results[0].Id
+		existing, err := x.ListNotes(ctx, documentID)
+		if err != nil {
+			return nil, err
+		}
+		present := make(map[string]bool)
+		for _, note := range existing {
+			present[note.Text] = true
+		}
+		for _, note := range exported.Notes {
+			if present[note.Text] {
+				report.Skipped++
+				continue
+			}
+			if !opts.DryRun {
+				if _, err := x.AddNote(ctx, documentID, note.Text); err != nil {
+					return nil, err
+				}
+			}
+			present[note.Text] = true
+			report.Imported++
+		}
+	}
+	return report, nil
+}
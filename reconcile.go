@@ -0,0 +1,1038 @@
+package paperless
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.yaml.in/yaml/v3"
+)
+
+type ObjectKind string
+
+const (
+	KindTags           ObjectKind = "tags"
+	KindCorrespondents ObjectKind = "correspondents"
+	KindDocumentTypes  ObjectKind = "document_types"
+	KindStoragePaths   ObjectKind = "storage_paths"
+	KindCustomFields   ObjectKind = "custom_fields"
+	KindMailAccounts   ObjectKind = "mail_accounts"
+	KindMailRules      ObjectKind = "mail_rules"
+	KindSavedViews     ObjectKind = "saved_views"
+	KindWorkflows      ObjectKind = "workflows"
+)
+
+// ManagedKinds are the kinds a DesiredState may contain, in the order they
+// are created and updated. Deletions happen in reverse order.
+var ManagedKinds = []ObjectKind{
+	KindTags,
+	KindCorrespondents,
+	KindDocumentTypes,
+	KindStoragePaths,
+	KindCustomFields,
+	KindMailRules,
+	KindSavedViews,
+	KindWorkflows,
+}
+
+const listPageSize int = 100
+
+// DesiredObject is an object as sent to the API, references to other objects
+// may be given by name instead of ID.
+type DesiredObject map[string]interface{}
+
+func (o DesiredObject) Name() string {
+	name, _ := o["name"].(string)
+	return name
+}
+
+// DesiredState is the 'paperless as code' description of taxonomy and
+// workflows. Only kinds present are reconciled.
+//
+//	protected:
+//	  tags: [inbox]
+//	tags:
+//	  - name: invoice
+//	    color: "#ff0000"
+//	    matching_algorithm: 0
+//	workflows:
+//	  - name: tag invoices
+//	    triggers:
+//	      - type: 1
+//	        filter_filename: "*inv*"
+//	    actions:
+//	      - type: 1
+//	        assign_tags: [invoice]
+type DesiredState struct {
+	Objects map[ObjectKind][]DesiredObject
+	// Protected names are never deleted, even when pruning.
+	Protected map[ObjectKind][]string
+}
+
+func LoadDesiredState(path string) (*DesiredState, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read desired state: %w", err)
+	}
+	state, err := ParseDesiredState(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid desired state '%s': %w", path, err)
+	}
+	return state, nil
+}
+
+// ParseDesiredState parses a YAML or JSON desired state and checks it against
+// the request schemas of the embedded api.yaml.
+func ParseDesiredState(raw []byte) (*DesiredState, error) {
+	var document map[string]interface{}
+	err := yaml.Unmarshal(raw, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode desired state: %w", err)
+	}
+	state := &DesiredState{
+		Objects:   make(map[ObjectKind][]DesiredObject),
+		Protected: make(map[ObjectKind][]string),
+	}
+	for key, value := range document {
+		if key == "protected" {
+			err = remarshal(value, &state.Protected)
+			if err != nil {
+				return nil, fmt.Errorf("invalid protected section: %w", err)
+			}
+			continue
+		}
+		kind := ObjectKind(key)
+		if _, ok := objectKindSpecs[kind]; !ok || kind == KindMailAccounts {
+			return nil, fmt.Errorf("unknown section '%s'", key)
+		}
+		objects := make([]DesiredObject, 0)
+		err = remarshal(value, &objects)
+		if err != nil {
+			return nil, fmt.Errorf("invalid section '%s': %w", key, err)
+		}
+		state.Objects[kind] = objects
+	}
+	return state, state.Validate()
+}
+
+// Validate checks for missing or duplicate names and fields unknown to the
+// API.
+func (s *DesiredState) Validate() error {
+	doc, _, err := loadEmbeddedSpec()
+	if err != nil {
+		return err
+	}
+	problems := make([]string, 0)
+	for kind, objects := range s.Objects {
+		spec := objectKindSpecs[kind]
+		seen := make(map[string]bool)
+		for idx, object := range objects {
+			name := object.Name()
+			if name == "" {
+				problems = append(problems, fmt.Sprintf("%s[%d]: missing name", kind, idx))
+				continue
+			}
+			if seen[name] {
+				problems = append(problems, fmt.Sprintf("%s '%s': duplicate name", kind, name))
+			}
+			seen[name] = true
+			problems = append(problems, unknownFields(doc.Components.Schemas, spec.requestSchema, fmt.Sprintf("%s '%s'", kind, name), object)...)
+			for field, schema := range spec.nestedSchemas {
+				items, _ := object[field].([]interface{})
+				for nestedIdx, item := range items {
+					nested, ok := item.(map[string]interface{})
+					if !ok {
+						problems = append(problems, fmt.Sprintf("%s '%s' %s[%d]: no object", kind, name, field, nestedIdx))
+						continue
+					}
+					location := fmt.Sprintf("%s '%s' %s[%d]", kind, name, field, nestedIdx)
+					problems = append(problems, unknownFields(doc.Components.Schemas, schema, location, nested)...)
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid desired state:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func unknownFields(schemas openapi3.Schemas, schemaName, location string, object map[string]interface{}) []string {
+	ref, ok := schemas[schemaName]
+	if !ok || ref.Value == nil {
+		return nil
+	}
+	problems := make([]string, 0)
+	for field := range object {
+		if _, ok := ref.Value.Properties[field]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown field '%s'", location, field))
+		}
+	}
+	return problems
+}
+
+type PlanAction string
+
+const (
+	PlanCreate    PlanAction = "create"
+	PlanUpdate    PlanAction = "update"
+	PlanDelete    PlanAction = "delete"
+	PlanUnmanaged PlanAction = "unmanaged"
+)
+
+type FieldDiff struct {
+	Field   string      `json:"field"`
+	Live    interface{} `json:"live,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+type PlanChange struct {
+	Kind   ObjectKind  `json:"kind"`
+	Name   string      `json:"name"`
+	Action PlanAction  `json:"action"`
+	ID     *int        `json:"id,omitempty"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+	// Protected marks unmanaged objects kept because pruning is disabled or
+	// the name is protected.
+	Protected bool `json:"protected,omitempty"`
+
+	desired DesiredObject
+	live    map[string]interface{}
+}
+
+type Plan struct {
+	Changes []PlanChange `json:"changes"`
+}
+
+// HasChanges tells whether applying the plan would modify the server.
+func (p Plan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != PlanUnmanaged {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDrift tells whether the server differs from the desired state in any
+// way, including objects unknown to the desired state.
+func (p Plan) HasDrift() bool {
+	return len(p.Changes) > 0
+}
+
+func (p Plan) String() string {
+	if !p.HasDrift() {
+		return "no changes, live state matches desired state"
+	}
+	lines := make([]string, 0)
+	counts := make(map[PlanAction]int)
+	for _, change := range p.Changes {
+		counts[change.Action]++
+		switch change.Action {
+		case PlanCreate:
+			lines = append(lines, fmt.Sprintf("  + %s '%s'", change.Kind, change.Name))
+			for _, field := range change.Fields {
+				lines = append(lines, fmt.Sprintf("      %s: %s", field.Field, planValue(field.Desired)))
+			}
+		case PlanUpdate:
+			lines = append(lines, fmt.Sprintf("  ~ %s '%s' (id %d)", change.Kind, change.Name, *change.ID))
+			for _, field := range change.Fields {
+				lines = append(lines, fmt.Sprintf("      %s: %s -> %s", field.Field, planValue(field.Live), planValue(field.Desired)))
+			}
+		case PlanDelete:
+			lines = append(lines, fmt.Sprintf("  - %s '%s' (id %d)", change.Kind, change.Name, *change.ID))
+		case PlanUnmanaged:
+			lines = append(lines, fmt.Sprintf("  ! %s '%s' (id %d) not in desired state, kept", change.Kind, change.Name, *change.ID))
+		}
+	}
+	lines = append(lines, fmt.Sprintf(
+		"plan: %d to create, %d to update, %d to delete, %d unmanaged",
+		counts[PlanCreate],
+		counts[PlanUpdate],
+		counts[PlanDelete],
+		counts[PlanUnmanaged],
+	))
+	return strings.Join(lines, "\n")
+}
+
+func planValue(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
+
+type ReconcileOptions struct {
+	// Prune deletes live objects missing from the desired state. Without it
+	// they are reported as unmanaged and left alone.
+	Prune bool
+}
+
+// liveState holds all live objects of the relevant kinds, indexed by name
+// and ID.
+type liveState struct {
+	objects map[ObjectKind][]map[string]interface{}
+	byName  map[ObjectKind]map[string]int
+	byID    map[ObjectKind]map[int]string
+}
+
+func (l *liveState) add(kind ObjectKind, object map[string]interface{}) {
+	id, ok := objectID(object)
+	if !ok {
+		return
+	}
+	name, _ := object["name"].(string)
+	l.objects[kind] = append(l.objects[kind], object)
+	l.byName[kind][name] = id
+	l.byID[kind][id] = name
+}
+
+func (x XClient) fetchLiveState(ctx context.Context, kinds []ObjectKind) (*liveState, error) {
+	live := &liveState{
+		objects: make(map[ObjectKind][]map[string]interface{}),
+		byName:  make(map[ObjectKind]map[string]int),
+		byID:    make(map[ObjectKind]map[int]string),
+	}
+	for _, kind := range kinds {
+		live.byName[kind] = make(map[string]int)
+		live.byID[kind] = make(map[int]string)
+		objects, err := objectKindSpecs[kind].list(ctx, x)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		for _, object := range objects {
+			live.add(kind, object)
+		}
+	}
+	return live, nil
+}
+
+// referencedKinds are all kinds needed to plan the desired state, including
+// those only referenced by name.
+func (s *DesiredState) referencedKinds() []ObjectKind {
+	needed := make(map[ObjectKind]bool)
+	for kind := range s.Objects {
+		needed[kind] = true
+		spec := objectKindSpecs[kind]
+		for _, ref := range spec.refs {
+			needed[ref] = true
+		}
+		for _, refs := range spec.nestedRefs {
+			for _, ref := range refs {
+				needed[ref] = true
+			}
+		}
+	}
+	output := make([]ObjectKind, 0)
+	for _, kind := range append([]ObjectKind{KindMailAccounts}, ManagedKinds...) {
+		if needed[kind] {
+			output = append(output, kind)
+		}
+	}
+	return output
+}
+
+// PlanReconcile compares the desired state with the live state, matching
+// objects by name.
+func (x XClient) PlanReconcile(ctx context.Context, desired *DesiredState, opts ReconcileOptions) (*Plan, error) {
+	live, err := x.fetchLiveState(ctx, desired.referencedKinds())
+	if err != nil {
+		return nil, err
+	}
+	return planReconcile(desired, live, opts), nil
+}
+
+func planReconcile(desired *DesiredState, live *liveState, opts ReconcileOptions) *Plan {
+	plan := &Plan{Changes: make([]PlanChange, 0)}
+	for _, kind := range ManagedKinds {
+		objects, ok := desired.Objects[kind]
+		if !ok {
+			continue
+		}
+		spec := objectKindSpecs[kind]
+		liveByName := make(map[string]map[string]interface{})
+		for _, object := range live.objects[kind] {
+			name, _ := object["name"].(string)
+			liveByName[name] = object
+		}
+
+		wanted := make(map[string]bool)
+		for _, object := range objects {
+			wanted[object.Name()] = true
+			desiredNamed := spec.toNames(object, live)
+			liveObject, exists := liveByName[object.Name()]
+			if !exists {
+				plan.Changes = append(plan.Changes, PlanChange{
+					Kind:    kind,
+					Name:    object.Name(),
+					Action:  PlanCreate,
+					Fields:  fieldDiffs(desiredNamed, nil),
+					desired: object,
+				})
+				continue
+			}
+			liveNamed := spec.toNames(liveObject, live)
+			diffs := fieldDiffs(desiredNamed, liveNamed)
+			if len(diffs) == 0 {
+				continue
+			}
+			id, _ := objectID(liveObject)
+			plan.Changes = append(plan.Changes, PlanChange{
+				Kind:    kind,
+				Name:    object.Name(),
+				Action:  PlanUpdate,
+				ID:      P(id),
+				Fields:  diffs,
+				desired: object,
+				live:    liveObject,
+			})
+		}
+
+		protected := make(map[string]bool)
+		for _, name := range desired.Protected[kind] {
+			protected[name] = true
+		}
+		for _, liveObject := range live.objects[kind] {
+			name, _ := liveObject["name"].(string)
+			if wanted[name] {
+				continue
+			}
+			id, _ := objectID(liveObject)
+			change := PlanChange{
+				Kind:   kind,
+				Name:   name,
+				Action: PlanDelete,
+				ID:     P(id),
+				live:   liveObject,
+			}
+			if !opts.Prune || protected[name] {
+				change.Action = PlanUnmanaged
+				change.Protected = true
+			}
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+	return plan
+}
+
+// fieldDiffs lists the top level fields of desired differing from live. Only
+// fields present in desired are compared.
+func fieldDiffs(desired, live map[string]interface{}) []FieldDiff {
+	fields := make([]string, 0, len(desired))
+	for field := range desired {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	output := make([]FieldDiff, 0)
+	for _, field := range fields {
+		if live != nil && equalDesired(desired[field], live[field]) {
+			continue
+		}
+		diff := FieldDiff{Field: field, Desired: desired[field]}
+		if live != nil {
+			diff.Live = live[field]
+		}
+		output = append(output, diff)
+	}
+	return output
+}
+
+// equalDesired compares a desired value with a live one: mappings only by the
+// desired keys, lists of scalars ignoring order.
+func equalDesired(desired, live interface{}) bool {
+	switch typed := desired.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range typed {
+			if !equalDesired(value, liveMap[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		liveList, ok := live.([]interface{})
+		if !ok {
+			return len(typed) == 0 && live == nil
+		}
+		if len(typed) != len(liveList) {
+			return false
+		}
+		if isScalarList(typed) && isScalarList(liveList) {
+			return reflect.DeepEqual(sortedScalars(typed), sortedScalars(liveList))
+		}
+		for idx := range typed {
+			if !equalDesired(typed[idx], liveList[idx]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(desired, live)
+}
+
+func isScalarList(list []interface{}) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+func sortedScalars(list []interface{}) []string {
+	output := make([]string, len(list))
+	for idx, item := range list {
+		output[idx] = fmt.Sprintf("%T:%v", item, item)
+	}
+	sort.Strings(output)
+	return output
+}
+
+type ApplyResult struct {
+	Applied []PlanChange `json:"applied"`
+}
+
+// ApplyPlan executes a plan created by PlanReconcile. Objects referencing
+// other objects created by the same plan are created once those exist.
+func (x XClient) ApplyPlan(ctx context.Context, plan *Plan) (*ApplyResult, error) {
+	// everything, references may point to objects outside of the plan
+	live, err := x.fetchLiveState(ctx, append([]ObjectKind{KindMailAccounts}, ManagedKinds...))
+	if err != nil {
+		return nil, err
+	}
+	result := &ApplyResult{Applied: make([]PlanChange, 0)}
+
+	pending := make([]PlanChange, 0)
+	for _, change := range plan.Changes {
+		if change.Action == PlanCreate || change.Action == PlanUpdate {
+			pending = append(pending, change)
+		}
+	}
+	sortByKindOrder(pending, false)
+	for len(pending) > 0 {
+		postponed := make([]PlanChange, 0)
+		var lastErr error
+		for _, change := range pending {
+			spec := objectKindSpecs[change.Kind]
+			body, err := spec.requestBody(change, live)
+			if err != nil {
+				lastErr = err
+				postponed = append(postponed, change)
+				continue
+			}
+			raw, err := json.Marshal(body)
+			if err != nil {
+				return result, fmt.Errorf("failed to encode %s '%s': %w", change.Kind, change.Name, err)
+			}
+			if change.Action == PlanCreate {
+				created, err := spec.create(ctx, x, raw)
+				if err != nil {
+					return result, fmt.Errorf("failed to create %s '%s': %w", change.Kind, change.Name, err)
+				}
+				live.add(change.Kind, created)
+				if id, ok := objectID(created); ok {
+					change.ID = P(id)
+				}
+			} else {
+				err = spec.update(ctx, x, *change.ID, raw)
+				if err != nil {
+					return result, fmt.Errorf("failed to update %s '%s': %w", change.Kind, change.Name, err)
+				}
+			}
+			result.Applied = append(result.Applied, change)
+		}
+		if len(postponed) == len(pending) {
+			return result, lastErr
+		}
+		pending = postponed
+	}
+
+	deletions := make([]PlanChange, 0)
+	for _, change := range plan.Changes {
+		if change.Action == PlanDelete {
+			deletions = append(deletions, change)
+		}
+	}
+	sortByKindOrder(deletions, true)
+	for _, change := range deletions {
+		err = objectKindSpecs[change.Kind].destroy(ctx, x, *change.ID)
+		if err != nil {
+			return result, fmt.Errorf("failed to delete %s '%s': %w", change.Kind, change.Name, err)
+		}
+		result.Applied = append(result.Applied, change)
+	}
+	return result, nil
+}
+
+// Reconcile plans and applies the desired state in one go.
+func (x XClient) Reconcile(ctx context.Context, desired *DesiredState, opts ReconcileOptions) (*Plan, *ApplyResult, error) {
+	plan, err := x.PlanReconcile(ctx, desired, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := x.ApplyPlan(ctx, plan)
+	return plan, result, err
+}
+
+func sortByKindOrder(changes []PlanChange, reverse bool) {
+	order := make(map[ObjectKind]int)
+	for idx, kind := range ManagedKinds {
+		order[kind] = idx
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if reverse {
+			return order[changes[i].Kind] > order[changes[j].Kind]
+		}
+		return order[changes[i].Kind] < order[changes[j].Kind]
+	})
+}
+
+type objectKindSpec struct {
+	requestSchema string
+	// top level fields referencing other objects
+	refs map[string]ObjectKind
+	// request schemas and references of nested object lists
+	nestedSchemas map[string]string
+	nestedRefs    map[string]map[string]ObjectKind
+
+	list    func(ctx context.Context, x XClient) ([]map[string]interface{}, error)
+	create  func(ctx context.Context, x XClient, body []byte) (map[string]interface{}, error)
+	update  func(ctx context.Context, x XClient, id int, body []byte) error
+	destroy func(ctx context.Context, x XClient, id int) error
+}
+
+// toNames normalizes an object to plain JSON values with references given by
+// name wherever the referenced object is known.
+func (s objectKindSpec) toNames(object map[string]interface{}, live *liveState) map[string]interface{} {
+	output := make(map[string]interface{})
+	_ = remarshal(object, &output)
+	convertRefs(output, s.refs, func(kind ObjectKind, value interface{}) interface{} {
+		return live.refToName(kind, value)
+	})
+	for field, refs := range s.nestedRefs {
+		items, _ := output[field].([]interface{})
+		for _, item := range items {
+			if nested, ok := item.(map[string]interface{}); ok {
+				convertRefs(nested, refs, func(kind ObjectKind, value interface{}) interface{} {
+					return live.refToName(kind, value)
+				})
+			}
+		}
+	}
+	return output
+}
+
+// requestBody builds the JSON body of a create or update, resolving names to
+// IDs. Nested lists are merged into the live items to keep their IDs.
+func (s objectKindSpec) requestBody(change PlanChange, live *liveState) (map[string]interface{}, error) {
+	body := make(map[string]interface{})
+	if change.Action == PlanCreate {
+		_ = remarshal(change.desired, &body)
+	} else {
+		desired := make(map[string]interface{})
+		_ = remarshal(change.desired, &desired)
+		liveObject := make(map[string]interface{})
+		_ = remarshal(change.live, &liveObject)
+		for _, diff := range change.Fields {
+			body[diff.Field] = desired[diff.Field]
+			if _, nested := s.nestedRefs[diff.Field]; nested {
+				body[diff.Field] = mergeNestedLists(desired[diff.Field], liveObject[diff.Field])
+			}
+		}
+	}
+
+	var unresolved []string
+	resolve := func(kind ObjectKind, value interface{}) interface{} {
+		id, ok := live.refToID(kind, value)
+		if !ok {
+			unresolved = append(unresolved, fmt.Sprintf("%s '%v'", kind, value))
+			return value
+		}
+		return id
+	}
+	convertRefs(body, s.refs, resolve)
+	for field, refs := range s.nestedRefs {
+		items, _ := body[field].([]interface{})
+		for _, item := range items {
+			if nested, ok := item.(map[string]interface{}); ok {
+				convertRefs(nested, refs, resolve)
+			}
+		}
+	}
+	if len(unresolved) > 0 {
+		return nil, fmt.Errorf("%s '%s' references unknown %s", change.Kind, change.Name, strings.Join(unresolved, ", "))
+	}
+	return body, nil
+}
+
+func mergeNestedLists(desired, live interface{}) interface{} {
+	desiredItems, ok := desired.([]interface{})
+	if !ok {
+		return desired
+	}
+	liveItems, _ := live.([]interface{})
+	output := make([]interface{}, len(desiredItems))
+	for idx, item := range desiredItems {
+		desiredItem, ok := item.(map[string]interface{})
+		if !ok || idx >= len(liveItems) {
+			output[idx] = item
+			continue
+		}
+		merged := make(map[string]interface{})
+		if liveItem, ok := liveItems[idx].(map[string]interface{}); ok {
+			for key, value := range liveItem {
+				merged[key] = value
+			}
+		}
+		for key, value := range desiredItem {
+			merged[key] = value
+		}
+		output[idx] = merged
+	}
+	return output
+}
+
+func convertRefs(object map[string]interface{}, refs map[string]ObjectKind, convert func(ObjectKind, interface{}) interface{}) {
+	for field, kind := range refs {
+		value, ok := object[field]
+		if !ok || value == nil {
+			continue
+		}
+		if list, ok := value.([]interface{}); ok {
+			converted := make([]interface{}, len(list))
+			for idx, item := range list {
+				converted[idx] = convert(kind, item)
+			}
+			object[field] = converted
+			continue
+		}
+		object[field] = convert(kind, value)
+	}
+}
+
+func (l *liveState) refToName(kind ObjectKind, value interface{}) interface{} {
+	id, ok := value.(float64)
+	if !ok {
+		return value
+	}
+	if name, ok := l.byID[kind][int(id)]; ok {
+		return name
+	}
+	return value
+}
+
+func (l *liveState) refToID(kind ObjectKind, value interface{}) (interface{}, bool) {
+	switch typed := value.(type) {
+	case string:
+		id, ok := l.byName[kind][typed]
+		return id, ok
+	default:
+		return value, true
+	}
+}
+
+func objectID(object map[string]interface{}) (int, bool) {
+	switch typed := object["id"].(type) {
+	case float64:
+		return int(typed), true
+	case int:
+		return typed, true
+	}
+	return 0, false
+}
+
+// remarshal converts between arbitrary values via JSON.
+func remarshal(in, out interface{}) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func asObjects[T any](items []T) ([]map[string]interface{}, error) {
+	output := make([]map[string]interface{}, 0, len(items))
+	err := remarshal(items, &output)
+	return output, err
+}
+
+// listAllPages collects all pages of a paginated list endpoint.
+func listAllPages[T any](fetch func(page int) ([]T, bool, error)) ([]map[string]interface{}, error) {
+	items := make([]T, 0)
+	for page := 1; ; page++ {
+		results, more, err := fetch(page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, results...)
+		if !more {
+			break
+		}
+	}
+	return asObjects(items)
+}
+
+func jsonBody(body []byte) *bytes.Reader {
+	return bytes.NewReader(body)
+}
+
+func apiError(action string, status int, body []byte) error {
+	return fmt.Errorf("invalid response code (%s): %d: %s", action, status, strings.TrimSpace(string(body)))
+}
+
+var workflowTriggerRefs = map[string]ObjectKind{
+	"filter_mailrule":               KindMailRules,
+	"filter_has_tags":               KindTags,
+	"filter_has_all_tags":           KindTags,
+	"filter_has_not_tags":           KindTags,
+	"filter_has_correspondent":      KindCorrespondents,
+	"filter_has_not_correspondents": KindCorrespondents,
+	"filter_has_document_type":      KindDocumentTypes,
+	"filter_has_not_document_types": KindDocumentTypes,
+	"filter_has_storage_path":       KindStoragePaths,
+	"filter_has_not_storage_paths":  KindStoragePaths,
+	"schedule_date_custom_field":    KindCustomFields,
+}
+
+var workflowActionRefs = map[string]ObjectKind{
+	"assign_tags":           KindTags,
+	"assign_correspondent":  KindCorrespondents,
+	"assign_document_type":  KindDocumentTypes,
+	"assign_storage_path":   KindStoragePaths,
+	"assign_custom_fields":  KindCustomFields,
+	"remove_tags":           KindTags,
+	"remove_correspondents": KindCorrespondents,
+	"remove_document_types": KindDocumentTypes,
+	"remove_storage_paths":  KindStoragePaths,
+	"remove_custom_fields":  KindCustomFields,
+}
+
+var objectKindSpecs = map[ObjectKind]objectKindSpec{
+	KindTags: collectionSpec[Tag](KindTags, "tag", objectKindSpec{
+		requestSchema: "TagRequest",
+		refs:          map[string]ObjectKind{"parent": KindTags},
+	}, collectionOps(
+		ClientWithResponsesInterface.TagsListWithResponse,
+		ClientWithResponsesInterface.TagsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.TagsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.TagsDestroyWithResponse,
+		func(page int) *TagsListParams {
+			return &TagsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindCorrespondents: collectionSpec[Correspondent](KindCorrespondents, "correspondent", objectKindSpec{
+		requestSchema: "CorrespondentRequest",
+	}, collectionOps(
+		ClientWithResponsesInterface.CorrespondentsListWithResponse,
+		ClientWithResponsesInterface.CorrespondentsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.CorrespondentsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.CorrespondentsDestroyWithResponse,
+		func(page int) *CorrespondentsListParams {
+			return &CorrespondentsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindDocumentTypes: collectionSpec[DocumentType](KindDocumentTypes, "document type", objectKindSpec{
+		requestSchema: "DocumentTypeRequest",
+	}, collectionOps(
+		ClientWithResponsesInterface.DocumentTypesListWithResponse,
+		ClientWithResponsesInterface.DocumentTypesCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.DocumentTypesPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.DocumentTypesDestroyWithResponse,
+		func(page int) *DocumentTypesListParams {
+			return &DocumentTypesListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindStoragePaths: collectionSpec[StoragePath](KindStoragePaths, "storage path", objectKindSpec{
+		requestSchema: "StoragePathRequest",
+	}, collectionOps(
+		ClientWithResponsesInterface.StoragePathsListWithResponse,
+		ClientWithResponsesInterface.StoragePathsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.StoragePathsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.StoragePathsDestroyWithResponse,
+		func(page int) *StoragePathsListParams {
+			return &StoragePathsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindCustomFields: collectionSpec[CustomField](KindCustomFields, "custom field", objectKindSpec{
+		requestSchema: "CustomFieldRequest",
+	}, collectionOps(
+		ClientWithResponsesInterface.CustomFieldsListWithResponse,
+		ClientWithResponsesInterface.CustomFieldsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.CustomFieldsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.CustomFieldsDestroyWithResponse,
+		func(page int) *CustomFieldsListParams {
+			return &CustomFieldsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	// only listed to resolve references of mail rules
+	KindMailAccounts: collectionSpec[MailAccount](KindMailAccounts, "mail account", objectKindSpec{
+		requestSchema: "MailAccountRequest",
+	}, collectionOps(
+		ClientWithResponsesInterface.MailAccountsListWithResponse,
+		ClientWithResponsesInterface.MailAccountsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.MailAccountsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.MailAccountsDestroyWithResponse,
+		func(page int) *MailAccountsListParams {
+			return &MailAccountsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindMailRules: collectionSpec[MailRule](KindMailRules, "mail rule", objectKindSpec{
+		requestSchema: "MailRuleRequest",
+		refs: map[string]ObjectKind{
+			"account":              KindMailAccounts,
+			"assign_tags":          KindTags,
+			"assign_correspondent": KindCorrespondents,
+			"assign_document_type": KindDocumentTypes,
+		},
+	}, collectionOps(
+		ClientWithResponsesInterface.MailRulesListWithResponse,
+		ClientWithResponsesInterface.MailRulesCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.MailRulesPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.MailRulesDestroyWithResponse,
+		func(page int) *MailRulesListParams {
+			return &MailRulesListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindSavedViews: collectionSpec[SavedView](KindSavedViews, "saved view", objectKindSpec{
+		requestSchema: "SavedViewRequest",
+	}, collectionOps(
+		ClientWithResponsesInterface.SavedViewsListWithResponse,
+		ClientWithResponsesInterface.SavedViewsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.SavedViewsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.SavedViewsDestroyWithResponse,
+		func(page int) *SavedViewsListParams {
+			return &SavedViewsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+	KindWorkflows: collectionSpec[Workflow](KindWorkflows, "workflow", objectKindSpec{
+		requestSchema: "WorkflowRequest",
+		nestedSchemas: map[string]string{
+			"triggers": "WorkflowTriggerRequest",
+			"actions":  "WorkflowActionRequest",
+		},
+		nestedRefs: map[string]map[string]ObjectKind{
+			"triggers": workflowTriggerRefs,
+			"actions":  workflowActionRefs,
+		},
+	}, collectionOps(
+		ClientWithResponsesInterface.WorkflowsListWithResponse,
+		ClientWithResponsesInterface.WorkflowsCreateWithBodyWithResponse,
+		ClientWithResponsesInterface.WorkflowsPartialUpdateWithBodyWithResponse,
+		ClientWithResponsesInterface.WorkflowsDestroyWithResponse,
+		func(page int) *WorkflowsListParams {
+			return &WorkflowsListParams{Page: P(page), PageSize: P(listPageSize)}
+		},
+	)),
+}
+
+// generatedResponse is a response of the generated client.
+type generatedResponse interface {
+	StatusCode() int
+}
+
+// generatedBody returns the raw body every response of the generated client
+// keeps in its Body field.
+func generatedBody(resp generatedResponse) []byte {
+	value := reflect.Indirect(reflect.ValueOf(resp))
+	if body := value.FieldByName("Body"); body.IsValid() {
+		return body.Bytes()
+	}
+	return nil
+}
+
+// generatedOps are the generated list, create, partial update and destroy
+// operations of the collection endpoint of a kind.
+type generatedOps[P any, L, C, U, D generatedResponse] struct {
+	list    func(ClientWithResponsesInterface, context.Context, *P, ...RequestEditorFn) (L, error)
+	create  func(ClientWithResponsesInterface, context.Context, string, io.Reader, ...RequestEditorFn) (C, error)
+	update  func(ClientWithResponsesInterface, context.Context, int, string, io.Reader, ...RequestEditorFn) (U, error)
+	destroy func(ClientWithResponsesInterface, context.Context, int, ...RequestEditorFn) (D, error)
+	// params of a page of the list
+	params func(page int) *P
+}
+
+func collectionOps[P any, L, C, U, D generatedResponse](
+	list func(ClientWithResponsesInterface, context.Context, *P, ...RequestEditorFn) (L, error),
+	create func(ClientWithResponsesInterface, context.Context, string, io.Reader, ...RequestEditorFn) (C, error),
+	update func(ClientWithResponsesInterface, context.Context, int, string, io.Reader, ...RequestEditorFn) (U, error),
+	destroy func(ClientWithResponsesInterface, context.Context, int, ...RequestEditorFn) (D, error),
+	params func(page int) *P,
+) generatedOps[P, L, C, U, D] {
+	return generatedOps[P, L, C, U, D]{list: list, create: create, update: update, destroy: destroy, params: params}
+}
+
+// collectionSpec adds the generated operations of the collection endpoint
+// /api/<kind>/ to the spec. Objects are decoded into T, the model of the
+// generated client.
+func collectionSpec[T, P any, L, C, U, D generatedResponse](kind ObjectKind, name string, spec objectKindSpec, ops generatedOps[P, L, C, U, D]) objectKindSpec {
+	plural := strings.ReplaceAll(string(kind), "_", " ")
+	spec.list = func(ctx context.Context, x XClient) ([]map[string]interface{}, error) {
+		return listAllPages(func(page int) ([]T, bool, error) {
+			resp, err := ops.list(x.ClientWithResponsesInterface, ctx, ops.params(page))
+			if err != nil {
+				return nil, false, err
+			}
+			if resp.StatusCode() != http.StatusOK {
+				return nil, false, apiError("list "+plural, resp.StatusCode(), generatedBody(resp))
+			}
+			var list struct {
+				Next    *string `json:"next"`
+				Results []T     `json:"results"`
+			}
+			if err := json.Unmarshal(generatedBody(resp), &list); err != nil {
+				return nil, false, fmt.Errorf("failed to decode %s: %w", plural, err)
+			}
+			return list.Results, list.Next != nil, nil
+		})
+	}
+	spec.create = func(ctx context.Context, x XClient, body []byte) (map[string]interface{}, error) {
+		resp, err := ops.create(x.ClientWithResponsesInterface, ctx, "application/json", jsonBody(body))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode() != http.StatusCreated {
+			return nil, apiError("create "+name, resp.StatusCode(), generatedBody(resp))
+		}
+		var created T
+		if err := json.Unmarshal(generatedBody(resp), &created); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+		objects, err := asObjects([]T{created})
+		return objects[0], err
+	}
+	spec.update = func(ctx context.Context, x XClient, id int, body []byte) error {
+		resp, err := ops.update(x.ClientWithResponsesInterface, ctx, id, "application/json", jsonBody(body))
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return apiError("update "+name, resp.StatusCode(), generatedBody(resp))
+		}
+		return nil
+	}
+	spec.destroy = func(ctx context.Context, x XClient, id int) error {
+		resp, err := ops.destroy(x.ClientWithResponsesInterface, ctx, id)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode() != http.StatusNoContent {
+			return apiError("delete "+name, resp.StatusCode(), generatedBody(resp))
+		}
+		return nil
+	}
+	return spec
+}
@@ -0,0 +1,422 @@
+package paperless
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type RecorderMode string
+
+const (
+	RecorderModeRecord RecorderMode = "record"
+	RecorderModeReplay RecorderMode = "replay"
+)
+
+const (
+	redactedValue       string = "REDACTED"
+	normalizedTimestamp string = "2000-01-01T00:00:00Z"
+	normalizedUUIDStem  string = "00000000-0000-4000-8000-"
+)
+
+var ErrNoRecordedInteraction = errors.New("no recorded interaction matches request")
+
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	csrfPattern      = regexp.MustCompile(`(csrftoken|csrfmiddlewaretoken|sessionid|password)=[^;&\s"]+`)
+)
+
+// headers which carry credentials and are redacted before being written to disk
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Csrftoken",
+}
+
+// headers which change on every exchange and are dropped from recordings
+var volatileHeaders = []string{
+	"Date",
+	"Vary",
+	"Content-Length",
+	"X-Frame-Options",
+	"Referrer-Policy",
+	"Cross-Origin-Opener-Policy",
+	"Sec-Websocket-Key",
+	"Sec-Websocket-Accept",
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is a http.RoundTripper which either records real exchanges with a
+// paperless instance into a cassette (golden file) or serves them back from it.
+type Recorder struct {
+	mode     RecorderMode
+	path     string
+	next     http.RoundTripper
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	uuids    map[string]string
+}
+
+// NewRecorder creates a recorder for the cassette at path. In replay mode the
+// cassette has to exist, in record mode it is (re)written by Save. If next is
+// nil, http.DefaultTransport is used to talk to the server while recording.
+func NewRecorder(mode RecorderMode, path string, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{
+		mode:  mode,
+		path:  path,
+		next:  next,
+		uuids: make(map[string]string),
+	}
+	switch mode {
+	case RecorderModeRecord:
+	case RecorderModeReplay:
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		err = json.Unmarshal(raw, &r.cassette)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode cassette '%s': %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("unknown recorder mode '%s'", mode)
+	}
+	return r, nil
+}
+
+func (r *Recorder) Mode() RecorderMode {
+	return r.mode
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	r.mu.Lock()
+	recorded := r.recordRequest(req, reqBody)
+	r.mu.Unlock()
+
+	if r.mode == RecorderModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// only the handshake is recorded, the upgraded connection is passed on
+		r.mu.Lock()
+		r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+			Request:  recorded,
+			Response: r.recordResponse(resp, nil),
+		})
+		r.mu.Unlock()
+		return resp, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recorded,
+		Response: r.recordResponse(resp, respBody),
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Save writes all recorded interactions to the cassette file. It is a no-op in
+// replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != RecorderModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	raw, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	return os.WriteFile(r.path, raw, 0o644)
+}
+
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match, last := -1, -1
+	for idx, interaction := range r.cassette.Interactions {
+		if !interaction.Request.matches(recorded) {
+			continue
+		}
+		if !r.used[idx] {
+			match = idx
+			break
+		}
+		last = idx
+	}
+	if match < 0 {
+		// polling loops may ask more often than during recording, keep
+		// answering with the latest state
+		if last < 0 {
+			return nil, fmt.Errorf("%w: %s %s?%s", ErrNoRecordedInteraction, recorded.Method, recorded.Path, recorded.Query)
+		}
+		match = last
+	}
+	r.used[match] = true
+
+	recordedResp := r.cassette.Interactions[match].Response
+	body := []byte(recordedResp.Body)
+	if recordedResp.BodyEncoding == "base64" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(recordedResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode recorded body: %w", err)
+		}
+	}
+	header := recordedResp.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if recordedResp.StatusCode == http.StatusSwitchingProtocols {
+		// the messages are not recorded, the connection ends right away
+		header.Set("Sec-Websocket-Accept", websocketAccept(req.Header.Get("Sec-Websocket-Key")))
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", recordedResp.StatusCode, http.StatusText(recordedResp.StatusCode)),
+			StatusCode: recordedResp.StatusCode,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     header,
+			Body:       closedConnection{},
+			Request:    req,
+		}, nil
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recordedResp.StatusCode, http.StatusText(recordedResp.StatusCode)),
+		StatusCode:    recordedResp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) recordRequest(req *http.Request, body []byte) RecordedRequest {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Header: r.normalizeHeader(req.Header),
+	}
+	if query := req.URL.Query(); len(query) > 0 {
+		for key, values := range query {
+			for idx := range values {
+				values[idx] = r.normalize(values[idx])
+			}
+			query[key] = values
+		}
+		recorded.Query = query.Encode()
+	}
+	if len(body) > 0 {
+		recorded.Body = r.normalizeRequestBody(req.Header.Get("Content-Type"), body)
+	}
+	return recorded
+}
+
+func (r *Recorder) recordResponse(resp *http.Response, body []byte) RecordedResponse {
+	recorded := RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     r.normalizeHeader(resp.Header),
+	}
+	if utf8.Valid(body) {
+		recorded.Body = r.scrub(string(body))
+	} else {
+		recorded.Body = base64.StdEncoding.EncodeToString(body)
+		recorded.BodyEncoding = "base64"
+	}
+	return recorded
+}
+
+// normalizeRequestBody replaces multipart bodies, which contain a random
+// boundary and whole files, with a stable textual digest.
+func (r *Recorder) normalizeRequestBody(contentType string, body []byte) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		if !utf8.Valid(body) {
+			sum := sha256.Sum256(body)
+			return "sha256:" + hex.EncodeToString(sum[:])
+		}
+		return r.normalize(string(body))
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	lines := make([]string, 0)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		content, _ := io.ReadAll(part)
+		if part.FileName() != "" {
+			sum := sha256.Sum256(content)
+			lines = append(lines, fmt.Sprintf("%s=@%s sha256:%s", part.FormName(), part.FileName(), hex.EncodeToString(sum[:])))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s=%s", part.FormName(), r.normalize(string(content))))
+		}
+		part.Close()
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func (r *Recorder) normalizeHeader(header http.Header) http.Header {
+	output := header.Clone()
+	for _, key := range volatileHeaders {
+		output.Del(key)
+	}
+	for _, key := range sensitiveHeaders {
+		if values := output.Values(key); len(values) > 0 {
+			redacted := make([]string, len(values))
+			for idx, value := range values {
+				redacted[idx] = redactedValue
+				// replayed logins need to know which cookies were set
+				if cookie, err := http.ParseSetCookie(value); err == nil && key == "Set-Cookie" {
+					redacted[idx] = cookie.Name + "=" + redactedValue
+				}
+			}
+			output[http.CanonicalHeaderKey(key)] = redacted
+		}
+	}
+	if len(output) == 0 {
+		return nil
+	}
+	return output
+}
+
+// normalize replaces volatile values of requests with stable placeholders,
+// so they match on replay.
+func (r *Recorder) normalize(s string) string {
+	return timestampPattern.ReplaceAllString(r.scrub(s), normalizedTimestamp)
+}
+
+// scrub replaces UUIDs and CSRF tokens with stable placeholders. UUIDs are
+// mapped in order of appearance, so references between exchanges survive.
+// Timestamps are kept, responses replay the recorded dates.
+func (r *Recorder) scrub(s string) string {
+	s = uuidPattern.ReplaceAllStringFunc(s, func(uuid string) string {
+		if strings.HasPrefix(uuid, normalizedUUIDStem) {
+			return uuid
+		}
+		key := strings.ToLower(uuid)
+		if placeholder, ok := r.uuids[key]; ok {
+			return placeholder
+		}
+		placeholder := fmt.Sprintf("%s%012d", normalizedUUIDStem, len(r.uuids)+1)
+		r.uuids[key] = placeholder
+		return placeholder
+	})
+	s = csrfPattern.ReplaceAllString(s, "$1="+redactedValue)
+	return s
+}
+
+func (req RecordedRequest) key() string {
+	return req.Method + " " + req.Path + "?" + req.Query
+}
+
+func (req RecordedRequest) matches(other RecordedRequest) bool {
+	if req.key() != other.key() {
+		return false
+	}
+	if req.Body == other.Body {
+		return true
+	}
+	return equalJSON(req.Body, other.Body)
+}
+
+func equalJSON(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	ra, _ := json.Marshal(va)
+	rb, _ := json.Marshal(vb)
+	return bytes.Equal(ra, rb)
+}
+
+// websocketAccept answers the key of a websocket handshake (RFC 6455).
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// closedConnection is the body of replayed protocol upgrades.
+type closedConnection struct{}
+
+func (closedConnection) Read([]byte) (int, error)    { return 0, io.EOF }
+func (closedConnection) Write(p []byte) (int, error) { return len(p), nil }
+func (closedConnection) Close() error                { return nil }
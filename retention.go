@@ -0,0 +1,503 @@
+package paperless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type RetentionBasis string
+
+const (
+	RetentionByCreated RetentionBasis = "created"
+	RetentionByAdded   RetentionBasis = "added"
+)
+
+// documents are deleted in chunks to keep bulk edit requests small
+const retentionDeleteChunk int = 100
+
+// RetentionAge is a calendar based age such as "10y", "6m" or "1y6m".
+type RetentionAge struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+var retentionAgePattern = regexp.MustCompile(`^(?:(\d+)y)?(?:(\d+)m)?(?:(\d+)w)?(?:(\d+)d)?$`)
+
+func ParseRetentionAge(value string) (RetentionAge, error) {
+	match := retentionAgePattern.FindStringSubmatch(strings.ReplaceAll(value, " ", ""))
+	if match == nil || value == "" {
+		return RetentionAge{}, fmt.Errorf("invalid retention age '%s', expected e.g. 10y, 6m, 2w or 30d", value)
+	}
+	number := func(text string) int {
+		n, _ := strconv.Atoi(text)
+		return n
+	}
+	age := RetentionAge{Years: number(match[1]), Months: number(match[2]), Days: 7*number(match[3]) + number(match[4])}
+	if age.IsZero() {
+		return RetentionAge{}, fmt.Errorf("invalid retention age '%s', must not be zero", value)
+	}
+	return age, nil
+}
+
+func (a RetentionAge) IsZero() bool {
+	return a.Years == 0 && a.Months == 0 && a.Days == 0
+}
+
+// Cutoff returns the point in time documents older than the age are
+// expired at.
+func (a RetentionAge) Cutoff(now time.Time) time.Time {
+	return now.AddDate(-a.Years, -a.Months, -a.Days)
+}
+
+func (a RetentionAge) String() string {
+	output := ""
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{a.Years, "y"}, {a.Months, "m"}, {a.Days, "d"}} {
+		if part.value != 0 {
+			output += fmt.Sprintf("%d%s", part.value, part.unit)
+		}
+	}
+	return output
+}
+
+func (a RetentionAge) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *RetentionAge) UnmarshalText(text []byte) error {
+	age, err := ParseRetentionAge(string(text))
+	if err != nil {
+		return err
+	}
+	*a = age
+	return nil
+}
+
+// RetentionRule selects documents which expire MaxAge after their created or
+// added date. Filters by name are resolved to IDs, documents must match all
+// of them.
+type RetentionRule struct {
+	Name          string         `yaml:"name" json:"name"`
+	Query         string         `yaml:"query,omitempty" json:"query,omitempty"`
+	DocumentType  string         `yaml:"document_type,omitempty" json:"document_type,omitempty"`
+	Correspondent string         `yaml:"correspondent,omitempty" json:"correspondent,omitempty"`
+	Tags          []string       `yaml:"tags,omitempty" json:"tags,omitempty"`
+	MaxAge        RetentionAge   `yaml:"max_age" json:"max_age"`
+	Basis         RetentionBasis `yaml:"basis,omitempty" json:"basis,omitempty"`
+	// Filter holds further list filters for rules defined in code, its
+	// date, page and field settings are overridden.
+	Filter *DocumentsListParams `yaml:"-" json:"-"`
+}
+
+type RetentionExport struct {
+	// Dir receives a copy of every document before it is deleted.
+	Dir      string `yaml:"dir" json:"dir"`
+	Original bool   `yaml:"original,omitempty" json:"original,omitempty"`
+}
+
+// RetentionPolicy is a set of retention rules, e.g.
+//
+//	legal_hold_tag: legal-hold
+//	rules:
+//	  - name: invoices
+//	    document_type: Invoice
+//	    max_age: 10y
+//	  - name: applications
+//	    tags: [application]
+//	    max_age: 6m
+//	    basis: added
+type RetentionPolicy struct {
+	// LegalHoldTag names the tag which blocks deletion of a document under
+	// all circumstances.
+	LegalHoldTag string           `yaml:"legal_hold_tag,omitempty" json:"legal_hold_tag,omitempty"`
+	Export       *RetentionExport `yaml:"export,omitempty" json:"export,omitempty"`
+	Rules        []RetentionRule  `yaml:"rules" json:"rules"`
+}
+
+func LoadRetentionPolicy(path string) (*RetentionPolicy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention policy: %w", err)
+	}
+	return ParseRetentionPolicy(raw)
+}
+
+// ParseRetentionPolicy parses a YAML (or JSON) policy.
+func ParseRetentionPolicy(raw []byte) (*RetentionPolicy, error) {
+	policy := &RetentionPolicy{}
+	err := yaml.Unmarshal(raw, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode retention policy: %w", err)
+	}
+	return policy, policy.Validate()
+}
+
+func (p *RetentionPolicy) Validate() error {
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("retention policy without rules")
+	}
+	names := make(map[string]bool)
+	for idx, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("retention rule %d without name", idx)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate retention rule '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.MaxAge.IsZero() {
+			return fmt.Errorf("retention rule '%s' without max_age", rule.Name)
+		}
+		if rule.Basis != "" && rule.Basis != RetentionByCreated && rule.Basis != RetentionByAdded {
+			return fmt.Errorf("retention rule '%s' has invalid basis '%s'", rule.Name, rule.Basis)
+		}
+		// a rule without any filter would expire the whole archive
+		if rule.Query == "" && rule.DocumentType == "" && rule.Correspondent == "" && len(rule.Tags) == 0 && rule.Filter == nil {
+			return fmt.Errorf("retention rule '%s' has no filter", rule.Name)
+		}
+	}
+	if p.Export != nil && p.Export.Dir == "" {
+		return fmt.Errorf("retention export without dir")
+	}
+	return nil
+}
+
+type RetentionOptions struct {
+	// DryRun reports expired documents without exporting or deleting them.
+	DryRun bool
+	// ReportDir receives a JSON report of every run.
+	ReportDir string
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+type RetentionItem struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Created string `json:"created,omitempty"`
+	Added   string `json:"added,omitempty"`
+	// Export is the path of the exported copy.
+	Export string `json:"export,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type RetentionRuleReport struct {
+	Rule    string          `json:"rule"`
+	Cutoff  time.Time       `json:"cutoff"`
+	Basis   RetentionBasis  `json:"basis"`
+	Expired []RetentionItem `json:"expired"`
+	// Held are expired documents kept because of the legal hold tag.
+	Held []RetentionItem `json:"held,omitempty"`
+	// Extended are documents expired according to this rule, but kept as
+	// another matching rule retains them longer.
+	Extended []RetentionItem `json:"extended,omitempty"`
+}
+
+// RetentionReport is the audit report of a run. Documents matched by several
+// rules are kept for the longest of their retention periods and reported for
+// the rule defining it.
+type RetentionReport struct {
+	Started  time.Time             `json:"started"`
+	Finished time.Time             `json:"finished"`
+	DryRun   bool                  `json:"dry_run"`
+	Rules    []RetentionRuleReport `json:"rules"`
+	// Deleted are the documents moved to the trash.
+	Deleted []int `json:"deleted"`
+	// Failed are expired documents kept because their export or deletion
+	// failed.
+	Failed []RetentionItem `json:"failed,omitempty"`
+	// Path of the written report.
+	Path string `json:"-"`
+}
+
+func (r *RetentionReport) String() string {
+	lines := make([]string, 0)
+	for _, rule := range r.Rules {
+		lines = append(lines, fmt.Sprintf("%s (%s before %s): %d expired, %d on legal hold, %d retained by longer rules",
+			rule.Rule, rule.Basis, rule.Cutoff.Format("2006-01-02"), len(rule.Expired), len(rule.Held), len(rule.Extended)))
+		for _, item := range rule.Expired {
+			lines = append(lines, fmt.Sprintf("  - %d %q", item.ID, item.Title))
+		}
+		for _, item := range rule.Held {
+			lines = append(lines, fmt.Sprintf("  ⚖ %d %q", item.ID, item.Title))
+		}
+	}
+	if r.DryRun {
+		lines = append(lines, "dry run, nothing deleted")
+	} else {
+		lines = append(lines, fmt.Sprintf("%d documents moved to trash", len(r.Deleted)))
+	}
+	for _, item := range r.Failed {
+		lines = append(lines, fmt.Sprintf("❌ %d %q: %s", item.ID, item.Title, item.Error))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ApplyRetention finds the documents expired according to the policy,
+// exports them if configured and moves them to the trash via bulk edit.
+// Documents matched by several rules expire once the longest of their
+// retention periods passed. Documents tagged with the legal hold tag are
+// never deleted.
+func (x XClient) ApplyRetention(ctx context.Context, policy *RetentionPolicy, opts RetentionOptions) (*RetentionReport, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	report := &RetentionReport{Started: now(), DryRun: opts.DryRun, Rules: make([]RetentionRuleReport, 0), Deleted: make([]int, 0)}
+
+	live, err := x.fetchLiveState(ctx, []ObjectKind{KindTags, KindCorrespondents, KindDocumentTypes})
+	if err != nil {
+		return nil, err
+	}
+	holdID := 0
+	if policy.LegalHoldTag != "" {
+		id, ok := live.byName[KindTags][policy.LegalHoldTag]
+		if !ok {
+			// a typo must not silently lift all holds
+			return nil, fmt.Errorf("legal hold tag '%s' not found", policy.LegalHoldTag)
+		}
+		holdID = id
+	}
+
+	// a document is kept as long as the longest of its matching rules says
+	matches := make(map[int][]retentionMatch)
+	order := make([]int, 0)
+	for idx, rule := range policy.Rules {
+		ruleReport, found, err := x.retentionMatches(ctx, rule, live, holdID, report.Started)
+		if err != nil {
+			return nil, err
+		}
+		report.Rules = append(report.Rules, *ruleReport)
+		for _, match := range found {
+			match.rule = idx
+			if _, ok := matches[match.item.ID]; !ok {
+				order = append(order, match.item.ID)
+			}
+			matches[match.item.ID] = append(matches[match.item.ID], match)
+		}
+	}
+	expired := make([]RetentionItem, 0)
+	for _, id := range order {
+		governing, allExpired := matches[id][0], true
+		for _, match := range matches[id] {
+			allExpired = allExpired && match.expired
+			if match.expires.After(governing.expires) {
+				governing = match
+			}
+		}
+		ruleReport := &report.Rules[governing.rule]
+		switch {
+		case !allExpired:
+			for _, match := range matches[id] {
+				if match.expired {
+					report.Rules[match.rule].Extended = append(report.Rules[match.rule].Extended, match.item)
+				}
+			}
+		case governing.held:
+			ruleReport.Held = append(ruleReport.Held, governing.item)
+		default:
+			ruleReport.Expired = append(ruleReport.Expired, governing.item)
+			expired = append(expired, governing.item)
+		}
+	}
+
+	if !opts.DryRun {
+		deletable := make([]int, 0, len(expired))
+		exports := make(map[int]string)
+		for _, item := range expired {
+			if policy.Export != nil {
+				path, err := x.DownloadDocumentTo(ctx, item.ID, policy.Export.Original, policy.Export.Dir)
+				if err != nil {
+					item.Error = err.Error()
+					report.Failed = append(report.Failed, item)
+					continue
+				}
+				exports[item.ID] = path
+			}
+			deletable = append(deletable, item.ID)
+		}
+		for idx := range report.Rules {
+			for itemIdx := range report.Rules[idx].Expired {
+				item := &report.Rules[idx].Expired[itemIdx]
+				item.Export = exports[item.ID]
+			}
+		}
+		for start := 0; start < len(deletable); start += retentionDeleteChunk {
+			chunk := deletable[start:min(start+retentionDeleteChunk, len(deletable))]
+			err := x.bulkDelete(ctx, chunk)
+			if err != nil {
+				for _, id := range chunk {
+					report.Failed = append(report.Failed, RetentionItem{ID: id, Error: err.Error()})
+				}
+				continue
+			}
+			report.Deleted = append(report.Deleted, chunk...)
+		}
+	}
+
+	report.Finished = now()
+	if opts.ReportDir != "" {
+		err = report.write(opts.ReportDir)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// retentionMatch is a document matched by a retention rule.
+type retentionMatch struct {
+	rule int
+	item RetentionItem
+	// expires is the end of the retention period of the rule
+	expires time.Time
+	expired bool
+	held    bool
+}
+
+// retentionMatches lists all documents matching the filters of the rule,
+// expired or not, as other rules may retain a document longer.
+func (x XClient) retentionMatches(ctx context.Context, rule RetentionRule, live *liveState, holdID int, now time.Time) (*RetentionRuleReport, []retentionMatch, error) {
+	params := &DocumentsListParams{}
+	if rule.Filter != nil {
+		copied := *rule.Filter
+		params = &copied
+	}
+	basis := rule.Basis
+	if basis == "" {
+		basis = RetentionByCreated
+	}
+	cutoff := rule.MaxAge.Cutoff(now)
+	// filters of rules defined in code must not hide retained documents
+	params.CreatedLt, params.AddedLt = nil, nil
+	if rule.Query != "" {
+		params.Query = P(rule.Query)
+	}
+	resolve := func(kind ObjectKind, name string) (int, error) {
+		id, ok := live.byName[kind][name]
+		if !ok {
+			return 0, fmt.Errorf("retention rule '%s': unknown %s '%s'", rule.Name, kind, name)
+		}
+		return id, nil
+	}
+	if rule.DocumentType != "" {
+		id, err := resolve(KindDocumentTypes, rule.DocumentType)
+		if err != nil {
+			return nil, nil, err
+		}
+		params.DocumentTypeId = P(id)
+	}
+	if rule.Correspondent != "" {
+		id, err := resolve(KindCorrespondents, rule.Correspondent)
+		if err != nil {
+			return nil, nil, err
+		}
+		params.CorrespondentId = P(id)
+	}
+	tagIDs := make([]int, 0, len(rule.Tags))
+	for _, name := range rule.Tags {
+		id, err := resolve(KindTags, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		tagIDs = append(tagIDs, id)
+	}
+	if len(tagIDs) > 0 {
+		// the filter takes a single tag, the others are checked below
+		params.TagsIdAll = P(tagIDs[0])
+	}
+	params.Fields = []string{"id", "title", "created", "added", "tags"}
+
+	documents, err := x.listDocuments(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	output := &RetentionRuleReport{Rule: rule.Name, Cutoff: cutoff, Basis: basis, Expired: make([]RetentionItem, 0)}
+	matches := make([]retentionMatch, 0)
+	for _, document := range documents {
+		if !hasAllTags(document.Tags, tagIDs) {
+			continue
+		}
+		match := retentionMatch{item: RetentionItem{ID: *document.Id}}
+		if document.Title != nil {
+			match.item.Title = *document.Title
+		}
+		if document.Added != nil {
+			match.item.Added = document.Added.Format(time.RFC3339)
+		}
+		var since *time.Time
+		if document.Created != nil {
+			match.item.Created = document.Created.String()
+			if basis == RetentionByCreated {
+				since = &document.Created.Time
+			}
+		}
+		if basis == RetentionByAdded {
+			since = document.Added
+		}
+		if since == nil {
+			// without the date the retention period never ends
+			match.expires = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+		} else {
+			match.expires = since.AddDate(rule.MaxAge.Years, rule.MaxAge.Months, rule.MaxAge.Days)
+			match.expired = since.Before(cutoff)
+		}
+		match.held = holdID != 0 && containsID(document.Tags, holdID)
+		matches = append(matches, match)
+	}
+	return output, matches, nil
+}
+
+func hasAllTags(tags []int, required []int) bool {
+	for _, id := range required {
+		if !containsID(tags, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkDelete moves documents to the trash, or deletes them on servers
+// without trash.
+func (x XClient) bulkDelete(ctx context.Context, ids []int) error {
+	resp, err := x.BulkEditWithResponse(ctx, BulkEditRequest{
+		Documents:  ids,
+		Method:     P(MethodEnumDelete),
+		Parameters: map[string]interface{}{},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete documents: %w", err)
+	}
+	if resp.StatusCode() != 200 {
+		return apiError("bulk delete", resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+func (r *RetentionReport) write(dir string) error {
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.Path = filepath.Join(dir, fmt.Sprintf("retention-%s.json", r.Started.UTC().Format("20060102T150405Z")))
+	err = os.WriteFile(r.Path, raw, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write retention report: %w", err)
+	}
+	return nil
+}
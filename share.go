@@ -0,0 +1,260 @@
+package paperless
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ShareOptions struct {
+	// Expiration of the link, nil for links that never expire.
+	Expiration *time.Time
+	// FileVersion defaults to the archive version.
+	FileVersion FileVersionEnum
+}
+
+// SharedLink is a share link with its public URL.
+type SharedLink struct {
+	ID          int             `json:"id"`
+	DocumentID  int             `json:"document"`
+	Slug        string          `json:"slug"`
+	URL         string          `json:"url"`
+	Created     time.Time       `json:"created"`
+	Expiration  *time.Time      `json:"expiration,omitempty"`
+	FileVersion FileVersionEnum `json:"file_version"`
+}
+
+func (l SharedLink) Expired(now time.Time) bool {
+	return l.Expiration != nil && !l.Expiration.After(now)
+}
+
+// ShareURL returns the public URL of a share link. paperless serves share
+// links below its base URL, which is the endpoint of the client.
+func (x XClient) ShareURL(slug string) string {
+	return fmt.Sprintf("%s/share/%s", x.endpoint, slug)
+}
+
+func (x XClient) sharedLink(link ShareLink) SharedLink {
+	output := SharedLink{Expiration: link.Expiration, FileVersion: FileVersionEnumArchive}
+	if link.Id != nil {
+		output.ID = *link.Id
+	}
+	if link.Document != nil {
+		output.DocumentID = *link.Document
+	}
+	if link.Slug != nil {
+		output.Slug = *link.Slug
+		output.URL = x.ShareURL(*link.Slug)
+	}
+	if link.Created != nil {
+		output.Created = *link.Created
+	}
+	if link.FileVersion != nil {
+		output.FileVersion = *link.FileVersion
+	}
+	return output
+}
+
+// ShareDocument creates a share link and returns it with its public URL.
+func (x XClient) ShareDocument(ctx context.Context, id int, opts ShareOptions) (*SharedLink, error) {
+	if opts.FileVersion == "" {
+		opts.FileVersion = FileVersionEnumArchive
+	}
+	resp, err := x.ShareLinksCreateWithResponse(ctx, ShareLinkRequest{
+		Document:    P(id),
+		Expiration:  opts.Expiration,
+		FileVersion: P(opts.FileVersion),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to share document %d: %w", id, err)
+	}
+	if resp.JSON201 == nil {
+		return nil, apiError("create share link", resp.StatusCode(), resp.Body)
+	}
+	link := x.sharedLink(*resp.JSON201)
+	if link.Slug == "" {
+		return nil, fmt.Errorf("share link of document %d has no slug", id)
+	}
+	return &link, nil
+}
+
+type ShareLinkFilter struct {
+	// Document restricts the links to a document.
+	Document int
+	// Expired restricts the links to expired (true) or active (false) ones.
+	Expired *bool
+	// Now defaults to the current time.
+	Now time.Time
+}
+
+// ListShareLinks returns the matching share links ordered by ID.
+func (x XClient) ListShareLinks(ctx context.Context, filter ShareLinkFilter) ([]SharedLink, error) {
+	if filter.Now.IsZero() {
+		filter.Now = time.Now()
+	}
+	output := make([]SharedLink, 0)
+	for page := 1; ; page++ {
+		resp, err := x.ShareLinksListWithResponse(ctx, &ShareLinksListParams{Page: P(page), PageSize: P(listPageSize)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list share links: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("list share links", resp.StatusCode(), resp.Body)
+		}
+		for _, link := range resp.JSON200.Results {
+			typed := x.sharedLink(link)
+			if filter.Document != 0 && typed.DocumentID != filter.Document {
+				continue
+			}
+			if filter.Expired != nil && typed.Expired(filter.Now) != *filter.Expired {
+				continue
+			}
+			output = append(output, typed)
+		}
+		if resp.JSON200.Next == nil {
+			break
+		}
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].ID < output[j].ID
+	})
+	return output, nil
+}
+
+func (x XClient) DeleteShareLink(ctx context.Context, id int) error {
+	resp, err := x.ShareLinksDestroyWithResponse(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete share link %d: %w", id, err)
+	}
+	if resp.StatusCode() != 204 {
+		return apiError("delete share link", resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+type ShareSweepOptions struct {
+	DryRun bool
+	// Trashed sweeps links to documents in the trash as well. By default
+	// they are kept, so restored documents are still shared.
+	Trashed bool
+	// Now defaults to the current time.
+	Now time.Time
+}
+
+type ShareSweepReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Expired []SharedLink `json:"expired"`
+	// Orphaned links point to documents which no longer exist, or are in
+	// the trash if ShareSweepOptions.Trashed is set.
+	Orphaned []SharedLink `json:"orphaned"`
+}
+
+func (r *ShareSweepReport) String() string {
+	prefix := ""
+	if r.DryRun {
+		prefix = "dry run: "
+	}
+	lines := []string{fmt.Sprintf("%s%d expired and %d orphaned share links deleted", prefix, len(r.Expired), len(r.Orphaned))}
+	for _, group := range []struct {
+		reason string
+		links  []SharedLink
+	}{{"expired", r.Expired}, {"orphaned", r.Orphaned}} {
+		for _, link := range group.links {
+			lines = append(lines, fmt.Sprintf("  %-8s %d document %d %s", group.reason, link.ID, link.DocumentID, link.URL))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SweepShareLinks deletes expired share links and links to documents which
+// no longer exist. The document list excludes trashed documents, so their
+// links are kept unless opts.Trashed is set.
+func (x XClient) SweepShareLinks(ctx context.Context, opts ShareSweepOptions) (*ShareSweepReport, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	links, err := x.ListShareLinks(ctx, ShareLinkFilter{Now: opts.Now})
+	if err != nil {
+		return nil, err
+	}
+	documents, err := x.listDocuments(ctx, &DocumentsListParams{Fields: []string{"id"}})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[int]bool, len(documents))
+	for _, document := range documents {
+		if document.Id != nil {
+			existing[*document.Id] = true
+		}
+	}
+	if !opts.Trashed && x.Supports(FeatureTrash) {
+		trashed, err := x.ListTrash(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range trashed {
+			if document.Id != nil {
+				existing[*document.Id] = true
+			}
+		}
+	}
+
+	report := &ShareSweepReport{DryRun: opts.DryRun, Expired: make([]SharedLink, 0), Orphaned: make([]SharedLink, 0)}
+	for _, link := range links {
+		switch {
+		case link.Expired(opts.Now):
+			report.Expired = append(report.Expired, link)
+		case !existing[link.DocumentID]:
+			report.Orphaned = append(report.Orphaned, link)
+		default:
+			continue
+		}
+		if opts.DryRun {
+			continue
+		}
+		if err := x.DeleteShareLink(ctx, link.ID); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// SharedDocument is a document with active share links.
+type SharedDocument struct {
+	DocumentID int          `json:"document"`
+	Title      string       `json:"title"`
+	Links      []SharedLink `json:"links"`
+}
+
+// SharedDocuments reports which documents are currently shared externally,
+// ordered by document ID.
+func (x XClient) SharedDocuments(ctx context.Context) ([]SharedDocument, error) {
+	links, err := x.ListShareLinks(ctx, ShareLinkFilter{Expired: P(false)})
+	if err != nil {
+		return nil, err
+	}
+	byDocument := make(map[int]*SharedDocument)
+	for _, link := range links {
+		if byDocument[link.DocumentID] == nil {
+			byDocument[link.DocumentID] = &SharedDocument{DocumentID: link.DocumentID}
+		}
+		byDocument[link.DocumentID].Links = append(byDocument[link.DocumentID].Links, link)
+	}
+	output := make([]SharedDocument, 0, len(byDocument))
+	for id, shared := range byDocument {
+		resp, err := x.DocumentsRetrieveWithResponse(ctx, id, &DocumentsRetrieveParams{Fields: []string{"id", "title"}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch document %d: %w", id, err)
+		}
+		if resp.JSON200 != nil && resp.JSON200.Title != nil {
+			shared.Title = *resp.JSON200.Title
+		}
+		output = append(output, *shared)
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].DocumentID < output[j].DocumentID
+	})
+	return output, nil
+}
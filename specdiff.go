@@ -0,0 +1,495 @@
+package paperless
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type SpecChangeKind string
+
+const (
+	SpecChangeAdded   SpecChangeKind = "added"
+	SpecChangeRemoved SpecChangeKind = "removed"
+	SpecChangeChanged SpecChangeKind = "changed"
+)
+
+type SpecElement string
+
+const (
+	SpecElementEndpoint     SpecElement = "endpoint"
+	SpecElementParameter    SpecElement = "parameter"
+	SpecElementRequestBody  SpecElement = "request body"
+	SpecElementResponse     SpecElement = "response"
+	SpecElementSchema       SpecElement = "schema"
+	SpecElementSchemaField  SpecElement = "schema field"
+	SpecElementEnumValue    SpecElement = "enum value"
+	SpecElementSpecMetadata SpecElement = "spec"
+)
+
+// SpecChange is a single semantic difference between two OpenAPI specs. A
+// change is breaking if a client built upon the base spec may fail against a
+// server serving the target spec.
+type SpecChange struct {
+	Kind     SpecChangeKind `json:"kind"`
+	Element  SpecElement    `json:"element"`
+	Location string         `json:"location"`
+	Name     string         `json:"name,omitempty"`
+	Detail   string         `json:"detail,omitempty"`
+	Breaking bool           `json:"breaking"`
+}
+
+func (c SpecChange) String() string {
+	severity := "non-breaking"
+	if c.Breaking {
+		severity = "BREAKING"
+	}
+	name := ""
+	if c.Name != "" {
+		name = fmt.Sprintf(" '%s'", c.Name)
+	}
+	detail := ""
+	if c.Detail != "" {
+		detail = ": " + c.Detail
+	}
+	return fmt.Sprintf("[%s] %s %s%s in %s%s", severity, c.Kind, c.Element, name, c.Location, detail)
+}
+
+type SpecDiff struct {
+	BaseVersion   string       `json:"base_version"`
+	TargetVersion string       `json:"target_version"`
+	Changes       []SpecChange `json:"changes"`
+}
+
+func (d SpecDiff) Breaking() []SpecChange {
+	output := make([]SpecChange, 0)
+	for _, change := range d.Changes {
+		if change.Breaking {
+			output = append(output, change)
+		}
+	}
+	return output
+}
+
+func (d SpecDiff) HasBreakingChanges() bool {
+	return len(d.Breaking()) > 0
+}
+
+func (d SpecDiff) String() string {
+	lines := make([]string, 0, len(d.Changes)+1)
+	lines = append(lines, fmt.Sprintf(
+		"spec diff %s -> %s: %d changes, %d breaking",
+		d.BaseVersion,
+		d.TargetVersion,
+		len(d.Changes),
+		len(d.Breaking()),
+	))
+	for _, change := range d.Changes {
+		lines = append(lines, "  "+change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffSpecs semantically compares two OpenAPI specs (YAML or JSON).
+func DiffSpecs(base, target []byte) (*SpecDiff, error) {
+	baseDoc, err := openapi3.NewLoader().LoadFromData(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base spec: %w", err)
+	}
+	targetDoc, err := openapi3.NewLoader().LoadFromData(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target spec: %w", err)
+	}
+	return diffSpecDocs(baseDoc, targetDoc), nil
+}
+
+// FetchServerSpec downloads the OpenAPI schema served by paperless.
+func (x XClient) FetchServerSpec(ctx context.Context) ([]byte, error) {
+	req, err := x.newRawRequest(ctx, http.MethodGet, "/api/schema/?format=json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.oai.openapi+json, application/json")
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response code (fetch schema): %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// DetectSpecDrift compares the schema served by paperless with base, or with
+// the embedded api.yaml if base is nil.
+func (x XClient) DetectSpecDrift(ctx context.Context, base []byte) (*SpecDiff, error) {
+	if base == nil {
+		base = embeddedSpec
+	}
+	live, err := x.FetchServerSpec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSpecs(base, live)
+}
+
+type specDiffer struct {
+	changes []SpecChange
+}
+
+func (d *specDiffer) add(kind SpecChangeKind, element SpecElement, location, name, detail string, breaking bool) {
+	d.changes = append(d.changes, SpecChange{
+		Kind:     kind,
+		Element:  element,
+		Location: location,
+		Name:     name,
+		Detail:   detail,
+		Breaking: breaking,
+	})
+}
+
+func diffSpecDocs(base, target *openapi3.T) *SpecDiff {
+	d := &specDiffer{}
+	output := &SpecDiff{}
+	if base.Info != nil {
+		output.BaseVersion = base.Info.Version
+	}
+	if target.Info != nil {
+		output.TargetVersion = target.Info.Version
+	}
+	if output.BaseVersion != output.TargetVersion {
+		d.add(SpecChangeChanged, SpecElementSpecMetadata, "info", "version",
+			fmt.Sprintf("'%s' -> '%s'", output.BaseVersion, output.TargetVersion), false)
+	}
+	d.diffPaths(base.Paths, target.Paths)
+	d.diffComponentSchemas(base.Components, target.Components)
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		a, b := d.changes[i], d.changes[j]
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		if a.Element != b.Element {
+			return a.Element < b.Element
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Detail < b.Detail
+	})
+	output.Changes = d.changes
+	return output
+}
+
+func (d *specDiffer) diffPaths(base, target *openapi3.Paths) {
+	baseOps := collectOperations(base)
+	targetOps := collectOperations(target)
+	for key, op := range baseOps {
+		targetOp, ok := targetOps[key]
+		if !ok {
+			d.add(SpecChangeRemoved, SpecElementEndpoint, key, op.operation.OperationID, "", true)
+			continue
+		}
+		d.diffOperation(key, op, targetOp)
+	}
+	for key, op := range targetOps {
+		if _, ok := baseOps[key]; !ok {
+			d.add(SpecChangeAdded, SpecElementEndpoint, key, op.operation.OperationID, "", false)
+		}
+	}
+}
+
+type specOperation struct {
+	operation  *openapi3.Operation
+	parameters map[string]*openapi3.Parameter
+}
+
+func collectOperations(paths *openapi3.Paths) map[string]specOperation {
+	output := make(map[string]specOperation)
+	if paths == nil {
+		return output
+	}
+	for path, item := range paths.Map() {
+		for method, op := range item.Operations() {
+			params := make(map[string]*openapi3.Parameter)
+			for _, refs := range []openapi3.Parameters{item.Parameters, op.Parameters} {
+				for _, ref := range refs {
+					if ref == nil || ref.Value == nil {
+						continue
+					}
+					params[ref.Value.In+":"+ref.Value.Name] = ref.Value
+				}
+			}
+			output[strings.ToUpper(method)+" "+path] = specOperation{
+				operation:  op,
+				parameters: params,
+			}
+		}
+	}
+	return output
+}
+
+func (d *specDiffer) diffOperation(location string, base, target specOperation) {
+	for key, param := range base.parameters {
+		targetParam, ok := target.parameters[key]
+		if !ok {
+			d.add(SpecChangeRemoved, SpecElementParameter, location, key, "", true)
+			continue
+		}
+		if !param.Required && targetParam.Required {
+			d.add(SpecChangeChanged, SpecElementParameter, location, key, "became required", true)
+		}
+		if param.Required && !targetParam.Required {
+			d.add(SpecChangeChanged, SpecElementParameter, location, key, "became optional", false)
+		}
+		if a, b := schemaShape(param.Schema), schemaShape(targetParam.Schema); a != b {
+			d.add(SpecChangeChanged, SpecElementParameter, location, key, fmt.Sprintf("type '%s' -> '%s'", a, b), true)
+		}
+		d.diffEnum(location, key, schemaValue(param.Schema), schemaValue(targetParam.Schema))
+	}
+	for key, param := range target.parameters {
+		if _, ok := base.parameters[key]; !ok {
+			d.add(SpecChangeAdded, SpecElementParameter, location, key, requiredDetail(param.Required), param.Required)
+		}
+	}
+
+	baseBody, targetBody := base.operation.RequestBody, target.operation.RequestBody
+	switch {
+	case baseBody == nil && targetBody != nil:
+		required := targetBody.Value != nil && targetBody.Value.Required
+		d.add(SpecChangeAdded, SpecElementRequestBody, location, "", requiredDetail(required), required)
+	case baseBody != nil && targetBody == nil:
+		d.add(SpecChangeRemoved, SpecElementRequestBody, location, "", "", true)
+	case baseBody != nil && targetBody != nil && baseBody.Value != nil && targetBody.Value != nil:
+		d.diffContent(location, SpecElementRequestBody, "", baseBody.Value.Content, targetBody.Value.Content)
+	}
+
+	baseResponses := responseMap(base.operation.Responses)
+	targetResponses := responseMap(target.operation.Responses)
+	for status, resp := range baseResponses {
+		targetResp, ok := targetResponses[status]
+		if !ok {
+			// a vanished error response does not hurt, a vanished success does
+			d.add(SpecChangeRemoved, SpecElementResponse, location, status, "", strings.HasPrefix(status, "2"))
+			continue
+		}
+		d.diffContent(location, SpecElementResponse, status, resp.Content, targetResp.Content)
+	}
+	for status := range targetResponses {
+		if _, ok := baseResponses[status]; !ok {
+			d.add(SpecChangeAdded, SpecElementResponse, location, status, "", false)
+		}
+	}
+}
+
+func responseMap(responses *openapi3.Responses) map[string]*openapi3.Response {
+	output := make(map[string]*openapi3.Response)
+	if responses == nil {
+		return output
+	}
+	for status, ref := range responses.Map() {
+		if ref != nil && ref.Value != nil {
+			output[status] = ref.Value
+		}
+	}
+	return output
+}
+
+func (d *specDiffer) diffContent(location string, element SpecElement, name string, base, target openapi3.Content) {
+	for mediaType, media := range base {
+		targetMedia, ok := target[mediaType]
+		if !ok {
+			d.add(SpecChangeRemoved, element, location, joinName(name, mediaType), "media type", true)
+			continue
+		}
+		if a, b := schemaShape(media.Schema), schemaShape(targetMedia.Schema); a != b {
+			d.add(SpecChangeChanged, element, location, joinName(name, mediaType), fmt.Sprintf("schema '%s' -> '%s'", a, b), true)
+		}
+	}
+	for mediaType := range target {
+		if _, ok := base[mediaType]; !ok {
+			d.add(SpecChangeAdded, element, location, joinName(name, mediaType), "media type", false)
+		}
+	}
+}
+
+func (d *specDiffer) diffComponentSchemas(base, target *openapi3.Components) {
+	baseSchemas, targetSchemas := openapi3.Schemas{}, openapi3.Schemas{}
+	if base != nil {
+		baseSchemas = base.Schemas
+	}
+	if target != nil {
+		targetSchemas = target.Schemas
+	}
+	for name, ref := range baseSchemas {
+		targetRef, ok := targetSchemas[name]
+		if !ok {
+			d.add(SpecChangeRemoved, SpecElementSchema, "#/components/schemas/"+name, name, "", true)
+			continue
+		}
+		d.diffSchema("#/components/schemas/"+name, isRequestSchema(name), ref.Value, targetRef.Value)
+	}
+	for name := range targetSchemas {
+		if _, ok := baseSchemas[name]; !ok {
+			d.add(SpecChangeAdded, SpecElementSchema, "#/components/schemas/"+name, name, "", false)
+		}
+	}
+}
+
+// isRequestSchema relies on drf-spectacular naming schemas sent by the
+// client '...Request'.
+func isRequestSchema(name string) bool {
+	return strings.HasSuffix(name, "Request")
+}
+
+func (d *specDiffer) diffSchema(location string, request bool, base, target *openapi3.Schema) {
+	if base == nil || target == nil {
+		return
+	}
+	if a, b := schemaTypeString(base), schemaTypeString(target); a != b {
+		d.add(SpecChangeChanged, SpecElementSchema, location, "", fmt.Sprintf("type '%s' -> '%s'", a, b), true)
+	}
+	if a, b := schemaShape(base.Items), schemaShape(target.Items); a != b {
+		d.add(SpecChangeChanged, SpecElementSchema, location, "items", fmt.Sprintf("'%s' -> '%s'", a, b), true)
+	}
+	d.diffEnum(location, "", base, target)
+
+	baseRequired := stringSet(base.Required)
+	targetRequired := stringSet(target.Required)
+	for field, ref := range base.Properties {
+		targetRef, ok := target.Properties[field]
+		if !ok {
+			d.add(SpecChangeRemoved, SpecElementSchemaField, location, field, "", true)
+			continue
+		}
+		if a, b := schemaShape(ref), schemaShape(targetRef); a != b {
+			d.add(SpecChangeChanged, SpecElementSchemaField, location, field, fmt.Sprintf("type '%s' -> '%s'", a, b), true)
+		}
+		if ref.Value != nil && targetRef.Value != nil && ref.Value.Nullable != targetRef.Value.Nullable {
+			// responses suddenly containing null break non-pointer fields,
+			// requests may no longer send null
+			breaking := targetRef.Value.Nullable != request
+			d.add(SpecChangeChanged, SpecElementSchemaField, location, field,
+				fmt.Sprintf("nullable %t -> %t", ref.Value.Nullable, targetRef.Value.Nullable), breaking)
+		}
+		if !baseRequired[field] && targetRequired[field] {
+			d.add(SpecChangeChanged, SpecElementSchemaField, location, field, "became required", request)
+		}
+		if baseRequired[field] && !targetRequired[field] {
+			d.add(SpecChangeChanged, SpecElementSchemaField, location, field, "became optional", !request)
+		}
+		if ref.Ref == "" && targetRef.Ref == "" {
+			d.diffEnum(location, field, ref.Value, targetRef.Value)
+		}
+	}
+	for field := range target.Properties {
+		if _, ok := base.Properties[field]; !ok {
+			required := targetRequired[field]
+			d.add(SpecChangeAdded, SpecElementSchemaField, location, field, requiredDetail(required), request && required)
+		}
+	}
+}
+
+func (d *specDiffer) diffEnum(location, name string, base, target *openapi3.Schema) {
+	if base == nil || target == nil {
+		return
+	}
+	baseValues := enumSet(base.Enum)
+	targetValues := enumSet(target.Enum)
+	if len(baseValues) == 0 && len(targetValues) == 0 {
+		return
+	}
+	for value := range baseValues {
+		if !targetValues[value] {
+			d.add(SpecChangeRemoved, SpecElementEnumValue, location, joinName(name, value), "", true)
+		}
+	}
+	for value := range targetValues {
+		if !baseValues[value] {
+			d.add(SpecChangeAdded, SpecElementEnumValue, location, joinName(name, value), "", false)
+		}
+	}
+}
+
+// schemaShape describes a schema by reference name or structure, ignoring
+// descriptions and the like.
+func schemaShape(ref *openapi3.SchemaRef) string {
+	if ref == nil {
+		return ""
+	}
+	if ref.Ref != "" {
+		return strings.TrimPrefix(ref.Ref, "#/components/schemas/")
+	}
+	schema := ref.Value
+	if schema == nil {
+		return ""
+	}
+	if len(schema.AllOf) == 1 {
+		return schemaShape(schema.AllOf[0])
+	}
+	shape := schemaTypeString(schema)
+	if schema.Format != "" {
+		shape += "(" + schema.Format + ")"
+	}
+	if schema.Items != nil {
+		shape += "[" + schemaShape(schema.Items) + "]"
+	}
+	return shape
+}
+
+func schemaValue(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+	return ref.Value
+}
+
+func schemaTypeString(schema *openapi3.Schema) string {
+	if schema.Type == nil {
+		return ""
+	}
+	return strings.Join(schema.Type.Slice(), "|")
+}
+
+func enumSet(values []interface{}) map[string]bool {
+	output := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		output[fmt.Sprintf("%v", value)] = true
+	}
+	return output
+}
+
+func stringSet(values []string) map[string]bool {
+	output := make(map[string]bool, len(values))
+	for _, value := range values {
+		output[value] = true
+	}
+	return output
+}
+
+func joinName(name, sub string) string {
+	if name == "" {
+		return sub
+	}
+	return name + " " + sub
+}
+
+func requiredDetail(required bool) string {
+	if required {
+		return "required"
+	}
+	return "optional"
+}
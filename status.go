@@ -0,0 +1,356 @@
+package paperless
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type ConsumptionStatus string
+
+const (
+	ConsumptionStarted ConsumptionStatus = "STARTED"
+	ConsumptionWorking ConsumptionStatus = "WORKING"
+	ConsumptionSuccess ConsumptionStatus = "SUCCESS"
+	ConsumptionFailed  ConsumptionStatus = "FAILED"
+)
+
+const (
+	StatusMessageUpdate           string = "status_update"
+	StatusMessageDocumentsDeleted string = "documents_deleted"
+)
+
+// StatusMessage is a message of the /ws/status/ websocket the web UI shows
+// consumption progress with.
+type StatusMessage struct {
+	// Type is one of the StatusMessage* constants, unknown types are passed on.
+	Type            string
+	TaskID          string
+	Filename        string
+	CurrentProgress int
+	MaxProgress     int
+	Status          ConsumptionStatus
+	// Message is a translation key such as "finished" or an error text.
+	Message    string
+	DocumentID *int
+	// Documents holds the ids of documents_deleted messages.
+	Documents []int
+}
+
+// Done tells whether consumption finished, successfully or not.
+func (m StatusMessage) Done() bool {
+	return m.Status == ConsumptionSuccess || m.Status == ConsumptionFailed
+}
+
+type rawStatusMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		TaskID          string            `json:"task_id"`
+		Filename        string            `json:"filename"`
+		CurrentProgress int               `json:"current_progress"`
+		MaxProgress     int               `json:"max_progress"`
+		Status          ConsumptionStatus `json:"status"`
+		Message         string            `json:"message"`
+		DocumentID      *int              `json:"document_id"`
+		Documents       []int             `json:"documents"`
+	} `json:"data"`
+}
+
+// statusSession holds the session cookies the status websocket is
+// authenticated with, it is shared by all copies of a client.
+type statusSession struct {
+	mu      sync.Mutex
+	cookies []*http.Cookie
+}
+
+// SubscribeStatus connects to the status websocket. Paperless authenticates
+// websockets by session only, so clients with basic auth credentials log in
+// via /accounts/login/ first and keep the session for later subscriptions.
+// Other clients only send what their request editors add. The handshake goes
+// through the HttpRequestDoer of the client, which has to pass on protocol
+// upgrades like http.Transport does (a http.Client with a Timeout does not).
+// The channel is closed once ctx is done or the connection is lost.
+func (x XClient) SubscribeStatus(ctx context.Context) (<-chan StatusMessage, error) {
+	if x.session == nil {
+		return nil, fmt.Errorf("client not created via NewXClient")
+	}
+	cookies, err := x.statusCookies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	failure := make(chan error, 1)
+	dialer := websocket.Dialer{
+		NetDialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			local, remote := net.Pipe()
+			go func() {
+				failure <- x.forwardStatusHandshake(ctx, remote, cookies)
+				remote.Close()
+			}()
+			return local, nil
+		},
+	}
+	// the handshake is forwarded as plain HTTP, TLS is up to the doer
+	conn, resp, err := dialer.DialContext(ctx, "ws://paperless/ws/status/", nil)
+	if err != nil {
+		select {
+		case forwardErr := <-failure:
+			if forwardErr != nil {
+				err = forwardErr
+			}
+		default:
+		}
+		if resp != nil {
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				x.session.reset()
+			}
+			return nil, fmt.Errorf("failed to connect to status websocket: %d: %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("failed to connect to status websocket: %w", err)
+	}
+
+	messages := make(chan StatusMessage)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer close(messages)
+		defer conn.Close()
+		for {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var raw rawStatusMessage
+			if json.Unmarshal(payload, &raw) != nil {
+				continue
+			}
+			message := StatusMessage{
+				Type:            raw.Type,
+				TaskID:          raw.Data.TaskID,
+				Filename:        raw.Data.Filename,
+				CurrentProgress: raw.Data.CurrentProgress,
+				MaxProgress:     raw.Data.MaxProgress,
+				Status:          raw.Data.Status,
+				Message:         raw.Data.Message,
+				DocumentID:      raw.Data.DocumentID,
+				Documents:       raw.Data.Documents,
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case messages <- message:
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// waitForTaskStatus waits for the task via the status websocket. It returns
+// false if the websocket is unavailable or lost before the task finished.
+func (x XClient) waitForTaskStatus(ctx context.Context, taskID string) (bool, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	messages, err := x.SubscribeStatus(subCtx)
+	if err != nil {
+		return false, nil
+	}
+	// the task may have finished before the subscription
+	if done, err := x.taskDone(ctx, taskID); done {
+		return true, err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return true, fmt.Errorf("waiting for task id '%s' timed out", taskID)
+		case message, ok := <-messages:
+			if !ok {
+				return false, nil
+			}
+			if message.TaskID != taskID || !message.Done() {
+				continue
+			}
+			if message.Status == ConsumptionFailed {
+				return true, fmt.Errorf("task with id '%s' has status: %s: %s", taskID, StatusEnumFAILURE, message.Message)
+			}
+			return true, nil
+		}
+	}
+}
+
+// taskDone polls the task once, errors other than a failed task are ignored.
+func (x XClient) taskDone(ctx context.Context, taskID string) (bool, error) {
+	innerCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	task, err := x.FetchTask(innerCtx, taskID)
+	if err != nil || task.Status == nil {
+		return false, nil
+	}
+	if *task.Status == StatusEnumFAILURE || *task.Status == StatusEnumREVOKED {
+		return true, fmt.Errorf("task with id '%s' has status: %s", taskID, *task.Status)
+	}
+	return *task.Status == StatusEnumSUCCESS, nil
+}
+
+// forwardStatusHandshake reads the handshake the websocket dialer wrote to
+// conn, sends it through the doer of the client and copies the upgraded
+// connection back and forth.
+func (x XClient) forwardStatusHandshake(ctx context.Context, conn net.Conn, cookies []*http.Cookie) error {
+	reader := bufio.NewReader(conn)
+	handshake, err := http.ReadRequest(reader)
+	if err != nil {
+		return err
+	}
+	req, err := x.newRawRequest(ctx, http.MethodGet, "/ws/status/", nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range handshake.Header {
+		if key == "Connection" || key == "Upgrade" || strings.HasPrefix(key, "Sec-Websocket-") {
+			req.Header[key] = values
+		}
+	}
+	req.Header.Set("Origin", x.endpoint)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return resp.Write(conn)
+	}
+	upgraded, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return fmt.Errorf("failed to connect to status websocket: the http client does not support protocol upgrades")
+	}
+	_, err = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	if err == nil {
+		err = resp.Header.Write(conn)
+	}
+	if err == nil {
+		_, err = io.WriteString(conn, "\r\n")
+	}
+	if err != nil {
+		return err
+	}
+	go func() {
+		io.Copy(upgraded, reader)
+		upgraded.Close()
+	}()
+	io.Copy(conn, upgraded)
+	return nil
+}
+
+// statusCookies returns the session of the client, logging in if the client
+// has basic auth credentials and no session yet.
+func (x XClient) statusCookies(ctx context.Context) ([]*http.Cookie, error) {
+	x.session.mu.Lock()
+	defer x.session.mu.Unlock()
+	if x.session.cookies != nil {
+		return x.session.cookies, nil
+	}
+	probe, err := x.newRawRequest(ctx, http.MethodGet, "/accounts/login/", nil)
+	if err != nil {
+		return nil, err
+	}
+	user, password, ok := probe.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	// django requires the csrf token of the login form
+	cookies := make(map[string]*http.Cookie)
+	loginURL := x.endpoint + "/accounts/login/"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loginURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := x.collectCookies(req, cookies); err != nil {
+		return nil, fmt.Errorf("failed to open login form: %w", err)
+	}
+	csrf := namedCookie(cookies, "csrftoken")
+	if csrf == nil {
+		return nil, fmt.Errorf("failed to log in: no csrf token received")
+	}
+	form := url.Values{
+		"csrfmiddlewaretoken": {csrf.Value},
+		// django-allauth (paperless 2.x) and django (1.x) field names
+		"login":    {user},
+		"username": {user},
+		"password": {password},
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, loginURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", loginURL)
+	req.AddCookie(csrf)
+	if err := x.collectCookies(req, cookies); err != nil {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+	session := namedCookie(cookies, "sessionid")
+	if session == nil {
+		return nil, fmt.Errorf("failed to log in as '%s': no session received", user)
+	}
+	x.session.cookies = []*http.Cookie{session}
+	return x.session.cookies, nil
+}
+
+// collectCookies sends the request and adds the cookies set by the response
+// and the redirects which led to it.
+func (x XClient) collectCookies(req *http.Request, cookies map[string]*http.Cookie) error {
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	responses := []*http.Response{resp}
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		responses = append(responses, r.Response)
+	}
+	// where the redirects lead to does not matter
+	if status := responses[len(responses)-1].StatusCode; status >= http.StatusBadRequest {
+		return apiError("log in", status, nil)
+	}
+	for idx := len(responses) - 1; idx >= 0; idx-- {
+		for _, cookie := range responses[idx].Cookies() {
+			if cookie.MaxAge < 0 || cookie.Value == "" {
+				delete(cookies, cookie.Name)
+				continue
+			}
+			cookies[cookie.Name] = cookie
+		}
+	}
+	return nil
+}
+
+// namedCookie finds the cookie by its name without the configurable
+// PAPERLESS_COOKIE_PREFIX.
+func namedCookie(cookies map[string]*http.Cookie, name string) *http.Cookie {
+	for cookieName, cookie := range cookies {
+		if strings.HasSuffix(cookieName, name) {
+			return &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+		}
+	}
+	return nil
+}
+
+func (s *statusSession) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cookies = nil
+}
@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsumer accepts uploads and finishes their tasks immediately. Files
+// containing "broken" fail to consume.
+type fakeConsumer struct {
+	mu sync.Mutex
+	// rejectUploads answers uploads with 503
+	rejectUploads bool
+	// lateDocuments is the number of fetches of a successful task answered
+	// before its related document is set, as paperless does after the
+	// websocket reported success
+	lateDocuments int
+	fetches       map[string]int
+	uploads       []map[string][]string
+	filenames     []string
+	tasks         map[string]int
+	failures      map[string]string
+	checksums     map[string]int
+	customFields  map[int]interface{}
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{
+		tasks:        make(map[string]int),
+		fetches:      make(map[string]int),
+		failures:     make(map[string]string),
+		checksums:    make(map[string]int),
+		customFields: make(map[int]interface{}),
+	}
+}
+
+func (f *fakeConsumer) register(mux *http.ServeMux) {
+	upgrader := websocket.Upgrader{}
+	// an idle status websocket, tasks are finished when checked first
+	mux.HandleFunc("GET /ws/status/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+	})
+	mux.HandleFunc("POST /api/documents/post_document/{$}", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			writeFakeJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		file, header, err := r.FormFile("document")
+		if err != nil {
+			writeFakeJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		content, _ := io.ReadAll(file)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.rejectUploads {
+			writeFakeJSON(w, http.StatusServiceUnavailable, "maintenance")
+			return
+		}
+		f.uploads = append(f.uploads, r.MultipartForm.Value)
+		f.filenames = append(f.filenames, header.Filename)
+		taskID := fmt.Sprintf("task-%d", len(f.uploads))
+		if bytes.Contains(content, []byte("broken")) {
+			f.failures[taskID] = "corrupt file"
+		} else {
+			f.tasks[taskID] = 100 + len(f.uploads)
+		}
+		writeFakeJSON(w, http.StatusOK, taskID)
+	})
+	mux.HandleFunc("GET /api/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		taskID := r.URL.Query().Get("task_id")
+		task := map[string]interface{}{"task_id": taskID, "status": "SUCCESS"}
+		if reason, ok := f.failures[taskID]; ok {
+			task["status"] = "FAILURE"
+			task["result"] = reason
+		} else if id, ok := f.tasks[taskID]; ok {
+			f.fetches[taskID]++
+			if f.fetches[taskID] > f.lateDocuments {
+				task["related_document"] = strconv.Itoa(id)
+			}
+		} else {
+			writeFakeJSON(w, http.StatusOK, []interface{}{})
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, []interface{}{task})
+	})
+	mux.HandleFunc("GET /api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		results := make([]interface{}, 0)
+		if id, ok := f.checksums[r.URL.Query().Get("checksum__iexact")]; ok {
+			results = append(results, map[string]interface{}{"id": id})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("PATCH /api/documents/{id}/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		f.customFields[id] = body["custom_fields"]
+		f.mu.Unlock()
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+	})
+}
+
+func (f *fakeConsumer) uploadCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+func TestUploadBatch(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.pdf": "%PDF-a", "b.pdf": "%PDF-broken", "c.pdf": "%PDF-c"} {
+		require.NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	manifest := filepath.Join(dir, "manifest.csv")
+	require.NoError(os.WriteFile(manifest, []byte(strings.Join([]string{
+		"path,title,created,tags,correspondent,custom_fields.amount",
+		"a.pdf,Invoice A,2024-03-15,invoice;paid,ACME,12.50",
+		"b.pdf,Broken,,,,",
+		"c.pdf,Letter C,,,,",
+	}, "\n")), 0o644))
+	entries, err := paperless.LoadManifest(manifest)
+	require.NoError(err)
+	require.Len(entries, 3)
+	require.Equal(filepath.Join(dir, "a.pdf"), entries[0].Path)
+	require.Equal([]string{"invoice", "paid"}, entries[0].Tags)
+	require.Equal(map[string]string{"amount": "12.50"}, entries[0].CustomFields)
+
+	collections := newFakeCollections("tags", "correspondents", "document_types", "custom_fields")
+	invoice := collections.add("tags", map[string]interface{}{"name": "invoice"})
+	paid := collections.add("tags", map[string]interface{}{"name": "paid"})
+	acme := collections.add("correspondents", map[string]interface{}{"name": "ACME"})
+	amount := collections.add("custom_fields", map[string]interface{}{"name": "amount", "data_type": "monetary"})
+	consumer := newFakeConsumer()
+	server, mux := newFakeServer(t, collections)
+	consumer.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	journal := filepath.Join(dir, "journal.jsonl")
+	opts := paperless.BatchOptions{Concurrency: 2, JournalPath: journal}
+	results, err := client.UploadBatch(ctx, entries, opts)
+	require.NoError(err)
+	require.Equal(paperless.BatchDone, results[0].State)
+	require.NotZero(results[0].DocumentID)
+	require.Equal(paperless.BatchFailed, results[1].State)
+	require.Equal("corrupt file", results[1].Error)
+	require.Equal(paperless.BatchDone, results[2].State)
+	require.Equal(3, consumer.uploadCount())
+
+	var upload map[string][]string
+	for _, candidate := range consumer.uploads {
+		if candidate["title"][0] == "Invoice A" {
+			upload = candidate
+		}
+	}
+	require.NotNil(upload)
+	require.ElementsMatch([]string{strconv.Itoa(invoice), strconv.Itoa(paid)}, upload["tags"])
+	require.Equal([]string{strconv.Itoa(acme)}, upload["correspondent"])
+	require.Equal([]interface{}{map[string]interface{}{"field": float64(amount), "value": "12.50"}}, consumer.customFields[results[0].DocumentID])
+
+	out := &bytes.Buffer{}
+	require.NoError(paperless.WriteBatchResults(out, results))
+	require.Contains(out.String(), fmt.Sprintf("%s,done,%d,", entries[0].Path, results[0].DocumentID))
+	require.Contains(out.String(), fmt.Sprintf("%s,failed,,corrupt file", entries[1].Path))
+
+	// a second run has nothing left to do
+	results, err = client.UploadBatch(ctx, entries, opts)
+	require.NoError(err)
+	require.Equal(3, consumer.uploadCount())
+	require.Equal(paperless.BatchFailed, results[1].State)
+
+	// a crashed run left one task untracked and one upload unjournaled
+	for name, content := range map[string]string{"d.pdf": "%PDF-d", "e.pdf": "%PDF-e"} {
+		require.NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	checksum, err := paperless.FileChecksum(filepath.Join(dir, "e.pdf"))
+	require.NoError(err)
+	consumer.mu.Lock()
+	consumer.tasks["task-crashed"] = 42
+	consumer.checksums[checksum] = 43
+	consumer.mu.Unlock()
+	file, err := os.OpenFile(journal, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(err)
+	fmt.Fprintf(file, `{"path": %q, "state": "uploaded", "task_id": "task-crashed"}`+"\n", filepath.Join(dir, "d.pdf"))
+	fmt.Fprintf(file, `{"path": %q, "state": "uploading"}`+"\n", filepath.Join(dir, "e.pdf"))
+	// truncated by the crash
+	fmt.Fprintf(file, `{"path": "x`)
+	require.NoError(file.Close())
+
+	entries = append(entries,
+		paperless.ManifestEntry{Path: filepath.Join(dir, "d.pdf")},
+		paperless.ManifestEntry{Path: filepath.Join(dir, "e.pdf")},
+	)
+	results, err = client.UploadBatch(ctx, entries, opts)
+	require.NoError(err)
+	require.Equal(3, consumer.uploadCount())
+	require.Equal(paperless.BatchDone, results[3].State)
+	require.Equal(42, results[3].DocumentID)
+	require.Equal(paperless.BatchSkipped, results[4].State)
+	require.Equal(43, results[4].DocumentID)
+}
+
+func TestWaitForTaskDocumentLate(t *testing.T) {
+	require := require.New(t)
+
+	consumer := newFakeConsumer()
+	consumer.lateDocuments = 2
+	consumer.tasks["task-late"] = 7
+	server, mux := newFakeServer(t, newFakeCollections())
+	consumer.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := client.WaitForTaskDocument(ctx, "task-late", 0)
+	require.NoError(err)
+	require.Equal(7, id)
+	consumer.mu.Lock()
+	defer consumer.mu.Unlock()
+	require.Equal(3, consumer.fetches["task-late"])
+}
+
+func TestUploadBatchConsumed(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*TEST_DOCUMENT_UPLOAD_TIMEOUT)
+	defer cancel()
+
+	seed := testdataDocuments[1]
+	seeded := seededDocument(ctx, t, client, seed)
+	entries := []paperless.ManifestEntry{
+		{Path: seed.Filename, Title: seed.Title},
+		{Path: writeTestPDF(t, "batch.pdf", "batch "+randStr(16)), Title: "batch upload", Created: "2024-03-15"},
+	}
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	opts := paperless.BatchOptions{Concurrency: 2, JournalPath: journal, SkipDuplicates: true}
+	results, err := client.UploadBatch(ctx, entries, opts)
+	require.NoError(err, "failed to upload batch")
+	require.Equal(paperless.BatchSkipped, results[0].State)
+	require.Equal(seeded, results[0].DocumentID)
+	require.Equal(paperless.BatchDone, results[1].State, results[1].Error)
+	require.NotZero(results[1].DocumentID)
+
+	documentResp, err := client.DocumentsRetrieveWithResponse(ctx, results[1].DocumentID, &paperless.DocumentsRetrieveParams{})
+	require.NoError(err, "failed to get document")
+	require.NotNil(documentResp.JSON200, "response json nil (get document)")
+	require.Equal("batch upload", *documentResp.JSON200.Title)
+
+	// the journal resumes without uploading again
+	results, err = client.UploadBatch(ctx, entries, opts)
+	require.NoError(err, "failed to resume batch")
+	require.Equal(paperless.BatchDone, results[1].State)
+}
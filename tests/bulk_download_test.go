@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBulkDocument struct {
+	title       string
+	storagePath string
+	original    string
+	archive     string
+}
+
+// fakeBulkDownloads serves bulk downloads named like paperless: public
+// filenames from the created date and title, or the storage path if
+// formatting is followed.
+type fakeBulkDownloads struct {
+	mu        sync.Mutex
+	documents map[int]fakeBulkDocument
+	requests  []paperless.BulkDownloadRequest
+	unsafe    bool
+}
+
+func md5Hex(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *fakeBulkDownloads) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/documents/{id}/metadata/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		document, ok := f.documents[id]
+		if !ok {
+			writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "No Document matches the given query."})
+			return
+		}
+		metadata := map[string]interface{}{"original_checksum": md5Hex(document.original)}
+		if document.archive != "" {
+			metadata["archive_checksum"] = md5Hex(document.archive)
+		}
+		writeFakeJSON(w, http.StatusOK, metadata)
+	})
+	mux.HandleFunc("POST /api/documents/bulk_download/{$}", func(w http.ResponseWriter, r *http.Request) {
+		var request paperless.BulkDownloadRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.mu.Lock()
+		f.requests = append(f.requests, request)
+		f.mu.Unlock()
+
+		buffer := &bytes.Buffer{}
+		archive := zip.NewWriter(buffer)
+		for _, id := range request.Documents {
+			document := f.documents[id]
+			name := "2024-01-01 " + document.title
+			if *request.FollowFormatting {
+				name = document.storagePath
+			}
+			content, ext := document.original, ".txt"
+			if *request.Content == paperless.ContentEnumArchive && document.archive != "" {
+				content, ext = document.archive, ".pdf"
+			}
+			entry, _ := archive.Create(name + ext)
+			entry.Write([]byte(content))
+		}
+		if f.unsafe {
+			entry, _ := archive.Create("../escaped.txt")
+			entry.Write([]byte("escaped"))
+		}
+		archive.Close()
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(buffer.Bytes())
+	})
+}
+
+func TestBulkDownload(t *testing.T) {
+	require := require.New(t)
+	downloads := &fakeBulkDownloads{documents: map[int]fakeBulkDocument{
+		1: {title: "Invoice", storagePath: "acme/2024/invoice", original: "original 1", archive: "archive 1"},
+		2: {title: "Invoice", storagePath: "globex/2024/invoice", original: "original 2", archive: "archive 2"},
+		3: {title: "Letter", storagePath: "acme/2024/letter", original: "original 3"},
+	}}
+	server, mux := newFakeServer(t, nil)
+	downloads.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	// chunks are merged into one archive, clashing names get a counter
+	buffer := &bytes.Buffer{}
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	manifest, err := client.BulkDownload(ctx, []int{1, 2, 3}, paperless.BulkDownloadOptions{ChunkSize: 2, Manifest: manifestPath}, buffer)
+	require.NoError(err)
+	require.Len(downloads.requests, 2)
+	require.Equal([]int{1, 2}, downloads.requests[0].Documents)
+	require.Equal([]int{3}, downloads.requests[1].Documents)
+	require.Equal(paperless.CompressionEnumNone, *downloads.requests[0].Compression)
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(err)
+	names := make([]string, 0)
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	require.Equal([]string{"2024-01-01 Invoice.pdf", "2024-01-01 Invoice_01.pdf", "2024-01-01 Letter.txt"}, names)
+	require.Equal([]paperless.BulkDownloadFile{
+		{Path: "2024-01-01 Invoice.pdf", DocumentID: 1, Version: "archive", Checksum: md5Hex("archive 1"), Size: 9},
+		{Path: "2024-01-01 Invoice_01.pdf", DocumentID: 2, Version: "archive", Checksum: md5Hex("archive 2"), Size: 9},
+		{Path: "2024-01-01 Letter.txt", DocumentID: 3, Version: "original", Checksum: md5Hex("original 3"), Size: 10},
+	}, manifest.Files)
+
+	written, err := os.ReadFile(manifestPath)
+	require.NoError(err)
+	var decoded paperless.BulkDownloadManifest
+	require.NoError(json.Unmarshal(written, &decoded))
+	require.Equal(*manifest, decoded)
+
+	// extraction into the storage path tree
+	dir := t.TempDir()
+	manifest, err = client.BulkDownload(ctx, []int{1, 2, 3}, paperless.BulkDownloadOptions{
+		Content:          paperless.ContentEnumOriginals,
+		FollowFormatting: true,
+		Dir:              dir,
+	}, nil)
+	require.NoError(err)
+	require.Len(manifest.Files, 3)
+	for _, file := range manifest.Files {
+		require.Equal("original", file.Version)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "globex", "2024", "invoice.txt"))
+	require.NoError(err)
+	require.Equal("original 2", string(content))
+	require.Equal("acme/2024/letter.txt", manifest.Files[2].Path)
+
+	// paths outside the directory are rejected
+	downloads.unsafe = true
+	_, err = client.BulkDownload(ctx, []int{3}, paperless.BulkDownloadOptions{Dir: t.TempDir()}, nil)
+	require.ErrorContains(err, "unsafe path '../escaped.txt'")
+
+	_, err = client.BulkDownload(ctx, []int{3}, paperless.BulkDownloadOptions{}, nil)
+	require.Error(err)
+	_, err = client.BulkDownload(ctx, []int{4}, paperless.BulkDownloadOptions{}, io.Discard)
+	require.ErrorContains(err, "404")
+}
+
+func TestBulkDownloadOriginals(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 6*TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	checksums := make(map[int]string)
+	ids := make([]int, 0, len(testdataDocuments))
+	for _, seed := range testdataDocuments {
+		id := seededDocument(ctx, t, client, seed)
+		ids = append(ids, id)
+		metadataResp, err := client.DocumentsMetadataRetrieveWithResponse(ctx, id)
+		require.NoError(err, "failed to get metadata")
+		require.NotNil(metadataResp.JSON200, "response json nil (get metadata)")
+		checksums[id] = metadataResp.JSON200.OriginalChecksum
+	}
+
+	dir := t.TempDir()
+	archive := &bytes.Buffer{}
+	manifest, err := client.BulkDownload(ctx, ids, paperless.BulkDownloadOptions{
+		Content:   paperless.ContentEnumOriginals,
+		ChunkSize: 1,
+		Dir:       dir,
+	}, archive)
+	require.NoError(err, "failed to download documents")
+	require.Equal(ids, manifest.Documents)
+	require.Len(manifest.Files, len(ids))
+	for _, file := range manifest.Files {
+		require.Equal("original", file.Version, file.Path)
+		require.Equal(checksums[file.DocumentID], file.Checksum, file.Path)
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		require.NoError(err, "file not extracted")
+		require.Equal(file.Checksum, md5Hex(string(content)))
+	}
+
+	// the chunks are merged into one archive
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	require.NoError(err, "invalid zip archive")
+	require.Len(reader.File, len(ids))
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeChecksumServer serves documents with the given original checksums,
+// keyed by id.
+func newFakeChecksumServer(t *testing.T, checksums map[int]string, uploads *atomic.Int32) paperless.XClient {
+	server, mux := newFakeServer(t, nil)
+	mux.HandleFunc("GET /api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		wanted := r.URL.Query().Get("checksum__iexact")
+		results := make([]interface{}, 0)
+		for id := 1; id <= len(checksums); id++ {
+			if wanted == "" || wanted == checksums[id] {
+				results = append(results, map[string]interface{}{"id": id, "title": "doc " + strconv.Itoa(id)})
+			}
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("GET /api/documents/{id}/metadata/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"original_checksum": checksums[id],
+			"original_filename": "scan-" + r.PathValue("id") + ".pdf",
+		})
+	})
+	mux.HandleFunc("POST /api/documents/post_document/", func(w http.ResponseWriter, r *http.Request) {
+		uploads.Add(1)
+		writeFakeJSON(w, http.StatusOK, recorderTestTaskID)
+	})
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(t, err, "failed to create client")
+	return client
+}
+
+func TestFindByChecksum(t *testing.T) {
+	require := require.New(t)
+
+	existing := filepath.Join(t.TempDir(), "existing.pdf")
+	require.NoError(os.WriteFile(existing, []byte("%PDF-existing"), 0o644))
+	fresh := filepath.Join(t.TempDir(), "fresh.pdf")
+	require.NoError(os.WriteFile(fresh, []byte("%PDF-fresh"), 0o644))
+	checksum, err := paperless.FileChecksum(existing)
+	require.NoError(err)
+	require.Len(checksum, 32)
+
+	uploads := &atomic.Int32{}
+	client := newFakeChecksumServer(t, map[int]string{1: "0123", 2: checksum}, uploads)
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	document, err := client.FindByChecksum(ctx, existing)
+	require.NoError(err)
+	require.NotNil(document)
+	require.Equal(2, *document.Id)
+	document, err = client.FindByChecksum(ctx, fresh)
+	require.NoError(err)
+	require.Nil(document)
+
+	opts := paperless.UploadOptions{SkipDuplicates: true, Metadata: &paperless.DocumentCreate{Title: paperless.P("scan")}}
+	result, err := client.UploadDocumentWithOptions(ctx, existing, opts)
+	require.NoError(err)
+	require.True(result.Skipped())
+	require.Equal(2, result.DuplicateOf)
+	require.EqualValues(0, uploads.Load())
+
+	result, err = client.UploadDocumentWithOptions(ctx, fresh, opts)
+	require.NoError(err)
+	require.False(result.Skipped())
+	require.Equal(recorderTestTaskID, result.TaskID)
+	require.EqualValues(1, uploads.Load())
+}
+
+func TestFindDuplicates(t *testing.T) {
+	require := require.New(t)
+	client := newFakeChecksumServer(t, map[int]string{1: "aaa", 2: "bbb", 3: "aaa", 4: "ccc", 5: "bbb", 6: "aaa"}, &atomic.Int32{})
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	report, err := client.FindDuplicates(ctx, paperless.DuplicateOptions{Concurrency: 2})
+	require.NoError(err)
+	require.Equal(6, report.Scanned)
+	require.Len(report.Groups, 2)
+	require.Equal("aaa", report.Groups[0].Checksum)
+	require.Equal([]int{1, 3, 6}, duplicateIDs(report.Groups[0]))
+	require.Equal("bbb", report.Groups[1].Checksum)
+	require.Equal([]int{2, 5}, duplicateIDs(report.Groups[1]))
+	require.Equal("scan-5.pdf", report.Groups[1].Documents[1].OriginalFilename)
+}
+
+func duplicateIDs(group paperless.DuplicateGroup) []int {
+	output := make([]int, 0, len(group.Documents))
+	for _, document := range group.Documents {
+		output = append(output, document.ID)
+	}
+	return output
+}
+
+func TestUploadSeededDuplicate(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	seed := testdataDocuments[0]
+	id := seededDocument(ctx, t, client, seed)
+	document, err := client.FindByChecksum(ctx, seed.Filename)
+	require.NoError(err, "failed to find document by checksum")
+	require.NotNil(document, "seeded document not found by checksum")
+	require.Equal(id, *document.Id)
+
+	result, err := client.UploadDocumentWithOptions(ctx, seed.Filename, paperless.UploadOptions{SkipDuplicates: true})
+	require.NoError(err, "failed to upload document")
+	require.True(result.Skipped(), "duplicate uploaded")
+	require.Equal(id, result.DuplicateOf)
+}
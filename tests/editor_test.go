@@ -0,0 +1,346 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEditor answers bulk edits like paperless: merge, split and edit_pdf
+// queue consumption tasks named after the source documents, which succeed
+// on the next poll of the task list. In place edits regenerate the archive
+// version on the second fetch of the metadata.
+type fakeEditor struct {
+	mu        sync.Mutex
+	notes     *fakeNotes
+	nextID    int
+	documents map[int]map[string]interface{}
+	tasks     []map[string]interface{}
+	edits     []paperless.BulkEditRequest
+	patches   map[int]map[string]interface{}
+	archives  map[int]string
+	archiving map[int]int
+	fail      bool
+	stalled   bool
+}
+
+func newFakeEditor() *fakeEditor {
+	return &fakeEditor{
+		notes:     newFakeNotes(),
+		nextID:    200,
+		documents: make(map[int]map[string]interface{}),
+		patches:   make(map[int]map[string]interface{}),
+		archives:  make(map[int]string),
+		archiving: make(map[int]int),
+		// an earlier split of document 1, which must not be mistaken for a
+		// result of new edits
+		tasks: []map[string]interface{}{{
+			"id": 1, "task_id": "old", "task_file_name": "1_1.pdf", "status": "SUCCESS", "related_document": "99",
+		}},
+	}
+}
+
+func (f *fakeEditor) addDocument(id, pages int, tags []int, fields map[int]string) {
+	customFields := make([]map[string]interface{}, 0)
+	for field, value := range fields {
+		customFields = append(customFields, map[string]interface{}{"field": field, "value": value})
+	}
+	f.documents[id] = map[string]interface{}{"id": id, "title": fmt.Sprintf("doc %d", id), "page_count": pages, "tags": tags, "custom_fields": customFields}
+	f.archives[id] = fmt.Sprintf("archive-%d", id)
+	f.notes.addDocument(id, "")
+}
+
+// queue adds a consumption task for a file, the caller holds the lock.
+func (f *fakeEditor) queue(filename string) {
+	f.nextID++
+	status := "PENDING"
+	result := ""
+	if f.fail {
+		status = "FAILURE"
+		result = filename + ": Not consuming " + filename + ": It is a duplicate."
+	}
+	f.tasks = append(f.tasks, map[string]interface{}{
+		"id": len(f.tasks) + 1, "task_id": fmt.Sprintf("task-%d", f.nextID), "task_file_name": filename,
+		"status": status, "result": result, "related_document": nil, "pending_document": f.nextID,
+	})
+}
+
+func (f *fakeEditor) register(mux *http.ServeMux) {
+	f.notes.metadata = func(id int, metadata map[string]interface{}) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.archiving[id] > 0 && !f.stalled {
+			f.archiving[id]--
+			if f.archiving[id] == 0 {
+				f.archives[id] = fmt.Sprintf("archive-%d-%d", id, len(f.edits))
+			}
+		}
+		metadata["archive_checksum"] = f.archives[id]
+	}
+	f.notes.register(mux)
+	mux.HandleFunc("GET /api/documents/{id}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		document, ok := f.documents[id]
+		if !ok {
+			writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "No Document matches the given query."})
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, document)
+	})
+	mux.HandleFunc("PATCH /api/documents/{id}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		patch := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&patch)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.patches[id] = patch
+		writeFakeJSON(w, http.StatusOK, f.documents[id])
+	})
+	mux.HandleFunc("POST /api/documents/bulk_edit/{$}", func(w http.ResponseWriter, r *http.Request) {
+		var request paperless.BulkEditRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.edits = append(f.edits, request)
+		switch *request.Method {
+		case paperless.MethodEnumRotate, paperless.MethodEnumDeletePages:
+			for _, id := range request.Documents {
+				f.archiving[id] = 2
+			}
+		case paperless.MethodEnumMerge:
+			parts := make([]string, 0)
+			for _, id := range request.Documents {
+				parts = append(parts, strconv.Itoa(id))
+			}
+			f.queue(strings.Join(parts, "_") + "_merged.pdf")
+		case paperless.MethodEnumSplit:
+			for i := range strings.Split(request.Parameters["pages"].(string), ",") {
+				f.queue(fmt.Sprintf("%d_%d.pdf", request.Documents[0], i+1))
+			}
+		case paperless.MethodEnumEditPdf:
+			if request.Parameters["update_document"] == true {
+				f.archiving[request.Documents[0]] = 2
+				break
+			}
+			documents := 0
+			for _, operation := range request.Parameters["operations"].([]interface{}) {
+				documents = max(documents, int(operation.(map[string]interface{})["doc"].(float64))+1)
+			}
+			// queued in reverse, results are still ordered by part
+			for i := documents; i > 0; i-- {
+				f.queue(fmt.Sprintf("%d_edit_%d.pdf", request.Documents[0], i))
+			}
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]string{"result": "OK"})
+	})
+	mux.HandleFunc("GET /api/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		writeFakeJSON(w, http.StatusOK, f.tasks)
+		for _, task := range f.tasks {
+			if task["status"] != "PENDING" {
+				continue
+			}
+			id := task["pending_document"].(int)
+			task["status"] = "SUCCESS"
+			task["related_document"] = strconv.Itoa(id)
+			f.documents[id] = map[string]interface{}{"id": id, "title": task["task_file_name"], "page_count": 1, "tags": []int{9}}
+			f.notes.addDocument(id, "")
+		}
+	})
+}
+
+func TestDocumentEditor(t *testing.T) {
+	require := require.New(t)
+	fake := newFakeEditor()
+	fake.addDocument(1, 4, []int{1, 2}, map[int]string{5: "2024"})
+	fake.addDocument(2, 2, []int{2, 3}, map[int]string{5: "2023", 6: "acme"})
+	fake.notes.mu.Lock()
+	fake.notes.add(1, "checked")
+	fake.notes.add(2, "paid")
+	fake.notes.mu.Unlock()
+	server, mux := newFakeServer(t, nil)
+	fake.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	editor := client.NewDocumentEditor(paperless.DocumentEditorOptions{
+		CarryTags:         true,
+		CarryCustomFields: true,
+		CarryNotes:        true,
+		PollInterval:      10 * time.Millisecond,
+	})
+	merged, err := editor.Merge(ctx, []int{2, 1})
+	require.NoError(err)
+	require.Equal(201, merged)
+	require.Equal([]int{2, 1}, fake.edits[0].Documents)
+	require.Equal([]interface{}{9.0, 2.0, 3.0, 1.0}, fake.patches[merged]["tags"])
+	require.Equal([]interface{}{
+		map[string]interface{}{"field": 5.0, "value": "2023"},
+		map[string]interface{}{"field": 6.0, "value": "acme"},
+	}, fake.patches[merged]["custom_fields"])
+	require.Equal([]string{"paid", "checked"}, fake.notes.texts(merged))
+
+	// pages are validated against the page count
+	_, err = editor.Split(ctx, 1, []paperless.PageRange{{From: 1, To: 2}, {From: 3, To: 5}})
+	require.ErrorContains(err, "page 5 out of range, document 1 has 4 pages")
+	_, err = editor.DeletePages(ctx, 2, []int{1, 2})
+	require.ErrorContains(err, "cannot delete all 2 pages")
+	_, err = editor.EditPDF(ctx, 2, []paperless.PageOperation{{Page: 0}})
+	require.ErrorContains(err, "invalid page 0")
+	_, err = editor.Rotate(ctx, []int{1}, 45)
+	require.ErrorContains(err, "multiple of 90")
+	require.Len(fake.edits, 1)
+
+	ranges, err := paperless.ParsePageRanges("1-2,3-4")
+	require.NoError(err)
+	split, err := editor.Split(ctx, 1, ranges)
+	require.NoError(err)
+	require.Equal([]int{202, 203}, split)
+	require.Equal("1-2,3-4", fake.edits[1].Parameters["pages"])
+	require.Equal([]string{"checked"}, fake.notes.texts(203))
+
+	edited, err := editor.EditPDF(ctx, 1, []paperless.PageOperation{{Page: 1}, {Page: 3, Rotate: 90, Document: 1}})
+	require.NoError(err)
+	require.Equal([]int{205, 204}, edited)
+
+	// in place edits return the edited documents once their archive
+	// versions are regenerated, documents without archive are not waited for
+	fake.mu.Lock()
+	delete(fake.archives, 2)
+	fake.mu.Unlock()
+	rotated, err := editor.Rotate(ctx, []int{1, 2}, 180)
+	require.NoError(err)
+	require.Equal([]int{1, 2}, rotated)
+	require.Equal(180.0, fake.edits[3].Parameters["degrees"])
+	require.Equal("archive-1-4", fake.archives[1])
+	deleted, err := editor.DeletePages(ctx, 1, []int{2, 4})
+	require.NoError(err)
+	require.Equal([]int{1}, deleted)
+	require.Equal([]interface{}{2.0, 4.0}, fake.edits[4].Parameters["pages"])
+	require.Equal("archive-1-5", fake.archives[1])
+	updated, err := client.NewDocumentEditor(paperless.DocumentEditorOptions{
+		UpdateDocument: true,
+		PollInterval:   10 * time.Millisecond,
+	}).EditPDF(ctx, 1, []paperless.PageOperation{{Page: 2}, {Page: 1}})
+	require.NoError(err)
+	require.Equal([]int{1}, updated)
+	require.Equal("archive-1-6", fake.archives[1])
+
+	fake.mu.Lock()
+	fake.stalled = true
+	fake.mu.Unlock()
+	_, err = client.NewDocumentEditor(paperless.DocumentEditorOptions{
+		PollInterval: 10 * time.Millisecond,
+		Timeout:      100 * time.Millisecond,
+	}).Rotate(ctx, []int{1}, 90)
+	require.ErrorContains(err, "timed out: 1 archive versions not updated")
+	fake.mu.Lock()
+	fake.stalled = false
+	fake.mu.Unlock()
+
+	// paperless rotates PDFs only, other documents are skipped
+	fake.mu.Lock()
+	fake.documents[2]["mime_type"] = "image/png"
+	fake.mu.Unlock()
+	rotated, err = editor.Rotate(ctx, []int{1, 2}, 90)
+	require.NoError(err)
+	require.Equal([]int{1}, rotated)
+	require.Equal([]int{1}, fake.edits[len(fake.edits)-1].Documents)
+	edits := len(fake.edits)
+	_, err = editor.Rotate(ctx, []int{2}, 90)
+	require.ErrorContains(err, "none of the documents [2] is a PDF")
+	_, err = editor.DeletePages(ctx, 2, []int{1})
+	require.ErrorContains(err, "document 2, it is not a PDF")
+	require.Len(fake.edits, edits)
+
+	// failed consumption, e.g. a duplicate
+	fake.mu.Lock()
+	fake.fail = true
+	fake.mu.Unlock()
+	_, err = editor.Merge(ctx, []int{1, 2})
+	require.ErrorContains(err, "It is a duplicate")
+}
+
+func TestDocumentEditorUnsupported(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 5, "2.6.3")
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	_, err = client.NegotiateVersion(ctx)
+	require.NoError(err)
+
+	editor := client.NewDocumentEditor(paperless.DocumentEditorOptions{})
+	_, err = editor.Merge(ctx, []int{1, 2})
+	require.ErrorIs(err, paperless.ErrUnsupportedByServer)
+	_, err = editor.Split(ctx, 1, []paperless.PageRange{{From: 1, To: 1}})
+	require.ErrorIs(err, paperless.ErrUnsupportedByServer)
+}
+
+func TestDocumentEditorConsumed(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 12*TEST_DOCUMENT_UPLOAD_TIMEOUT)
+	defer cancel()
+
+	pageCount := func(id int) int {
+		resp, err := client.DocumentsRetrieveWithResponse(ctx, id, &paperless.DocumentsRetrieveParams{})
+		require.NoError(err, "failed to get document")
+		require.NotNil(resp.JSON200, "response json nil (get document)")
+		require.NotNil(resp.JSON200.PageCount, "page count of document %d unknown", id)
+		return *resp.JSON200.PageCount
+	}
+	sources := make([]int, 0, len(testdataDocuments))
+	pages := 0
+	for _, seed := range testdataDocuments {
+		id := seededDocument(ctx, t, client, seed)
+		sources = append(sources, id)
+		pages += pageCount(id)
+	}
+	editor := client.NewDocumentEditor(paperless.DocumentEditorOptions{PollInterval: 500 * time.Millisecond})
+
+	// new documents are found by the files paperless consumes
+	merged, err := editor.Merge(ctx, sources)
+	require.NoError(err, "failed to merge documents")
+	require.Equal(pages, pageCount(merged))
+	split, err := editor.Split(ctx, merged, []paperless.PageRange{{From: 1, To: 1}, {From: 2, To: pages}})
+	require.NoError(err, "failed to split document")
+	require.Len(split, 2)
+	require.Equal(1, pageCount(split[0]))
+	require.Equal(pages-1, pageCount(split[1]))
+
+	// in place edits
+	_, err = editor.Rotate(ctx, []int{merged}, 90)
+	require.NoError(err, "failed to rotate document")
+	_, err = editor.DeletePages(ctx, merged, []int{1})
+	require.NoError(err, "failed to delete pages")
+	require.Equal(pages-1, pageCount(merged))
+
+	edited, err := editor.EditPDF(ctx, merged, []paperless.PageOperation{{Page: 1}, {Page: 1, Rotate: 180, Document: 1}})
+	require.NoError(err, "failed to edit document")
+	require.Len(edited, 2)
+	require.Equal(1, pageCount(edited[1]))
+	updated, err := client.NewDocumentEditor(paperless.DocumentEditorOptions{
+		UpdateDocument: true,
+		PollInterval:   500 * time.Millisecond,
+	}).EditPDF(ctx, merged, []paperless.PageOperation{{Page: 1, Rotate: 90}})
+	require.NoError(err, "failed to edit document in place")
+	require.Equal([]int{merged}, updated)
+	require.Equal(1, pageCount(merged))
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMailer records email requests. Without smtp it answers like paperless
+// without PAPERLESS_EMAIL_HOST, legacy servers only know the single document
+// endpoint.
+type fakeMailer struct {
+	mu       sync.Mutex
+	smtp     bool
+	legacy   bool
+	requests []map[string]interface{}
+}
+
+func (m *fakeMailer) register(mux *http.ServeMux) {
+	handle := func(w http.ResponseWriter, r *http.Request, request map[string]interface{}) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if !m.smtp {
+			writeFakeJSON(w, http.StatusInternalServerError, "Error emailing document(s), check logs for more detail.")
+			return
+		}
+		m.requests = append(m.requests, request)
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"message": "Email sent"})
+	}
+	mux.HandleFunc("POST /api/documents/email/{$}", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		legacy := m.legacy
+		m.mu.Unlock()
+		if legacy {
+			http.NotFound(w, r)
+			return
+		}
+		request := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&request)
+		handle(w, r, request)
+	})
+	mux.HandleFunc("POST /api/documents/{id}/email/", func(w http.ResponseWriter, r *http.Request) {
+		request := map[string]interface{}{"document": r.PathValue("id")}
+		json.NewDecoder(r.Body).Decode(&request)
+		handle(w, r, request)
+	})
+}
+
+func TestEmailDocuments(t *testing.T) {
+	require := require.New(t)
+	mailer := &fakeMailer{smtp: true}
+	server, mux := newFakeServer(t, nil)
+	mailer.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	opts := paperless.EmailOptions{
+		To:                []string{"tax@example.com", "Jane Doe <jane@example.com>", "TAX@example.com"},
+		Subject:           "receipts",
+		Message:           "see attached",
+		UseArchiveVersion: true,
+	}
+	require.NoError(client.EmailDocuments(ctx, []int{1, 2}, opts))
+	require.Len(mailer.requests, 1)
+	require.Equal("tax@example.com,jane@example.com", mailer.requests[0]["addresses"])
+	require.Equal([]interface{}{1.0, 2.0}, mailer.requests[0]["documents"])
+	require.Equal("receipts", mailer.requests[0]["subject"])
+	require.Equal(true, mailer.requests[0]["use_archive_version"])
+
+	// invalid input is rejected before anything is sent
+	for _, invalid := range []paperless.EmailOptions{
+		{Subject: "s", Message: "m"},
+		{To: []string{"not an address"}, Subject: "s", Message: "m"},
+		{To: []string{"a@example.com, b@example.com"}, Subject: "s", Message: "m"},
+		{To: []string{"a@example.com"}, Message: "m"},
+		{To: []string{"a@example.com"}, Subject: "s"},
+	} {
+		require.Error(client.EmailDocuments(ctx, []int{1}, invalid))
+	}
+	bad := opts
+	bad.To = []string{"tax@example.com", "bad"}
+	require.ErrorContains(client.EmailDocuments(ctx, []int{1}, bad), "invalid email recipient 'bad'")
+	require.Error(client.EmailDocuments(ctx, nil, opts))
+	require.Len(mailer.requests, 1)
+
+	// servers without the bulk endpoint email single documents only
+	mailer.mu.Lock()
+	mailer.legacy = true
+	mailer.mu.Unlock()
+	require.NoError(client.EmailDocuments(ctx, []int{3}, opts))
+	require.Len(mailer.requests, 2)
+	require.Equal("3", mailer.requests[1]["document"])
+	require.Equal("tax@example.com,jane@example.com", mailer.requests[1]["addresses"])
+	require.ErrorIs(client.EmailDocuments(ctx, []int{1, 2}, opts), paperless.ErrUnsupportedByServer)
+
+	// paperless without smtp settings
+	mailer.mu.Lock()
+	mailer.legacy = false
+	mailer.smtp = false
+	mailer.mu.Unlock()
+	err = client.EmailDocuments(ctx, []int{1}, opts)
+	require.ErrorIs(err, paperless.ErrEmailFailed)
+	require.ErrorContains(err, "500")
+}
+
+func TestEmailDocumentsUnsupported(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 7, "2.13.5")
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	_, err = client.NegotiateVersion(ctx)
+	require.NoError(err)
+
+	err = client.EmailDocuments(ctx, []int{1}, paperless.EmailOptions{To: []string{"a@example.com"}, Subject: "s", Message: "m"})
+	require.ErrorIs(err, paperless.ErrUnsupportedByServer)
+}
+
+func TestEmailDocumentsWithoutSMTP(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	ids := make([]int, 0, len(testdataDocuments))
+	for _, seed := range testdataDocuments {
+		ids = append(ids, seededDocument(ctx, t, client, seed))
+	}
+	// the test stack has no PAPERLESS_EMAIL_HOST, a request paperless
+	// accepts fails sending
+	err := client.EmailDocuments(ctx, ids, paperless.EmailOptions{
+		To:      []string{"Office <" + TEST_EMAIL + ">"},
+		Subject: "documents",
+		Message: "see attachments",
+	})
+	require.ErrorIs(err, paperless.ErrEmailFailed)
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+const testFilenamePatterns = `
+patterns:
+  - name: scanner
+    pattern: '^(?P<created>\d{4}-\d{2}-\d{2})_(?P<correspondent>[^_]+)_(?P<document_type>[^_]+)_(?P<cf_number>.+)$'
+    title: '{document_type} {cf_number}'
+    timezone: Europe/Berlin
+    tags: [scanned]
+  - name: tagged
+    pattern: '^(?P<title>[^\[]+)\[(?P<tags>[^\]]+)\]$'
+`
+
+func TestFilenameExtractor(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	patterns := filepath.Join(dir, "patterns.yaml")
+	require.NoError(os.WriteFile(patterns, []byte(testFilenamePatterns), 0o644))
+	extractor, err := paperless.LoadFilenameExtractor(patterns)
+	require.NoError(err)
+
+	extracted, err := extractor.Extract("scans/2024-03-15_ACME_Invoice_INV-1234.pdf")
+	require.NoError(err)
+	require.NotNil(extracted)
+	require.Equal("scanner", extracted.Pattern)
+	require.Equal("scans/2024-03-15_ACME_Invoice_INV-1234.pdf", extracted.Path)
+	require.Equal("Invoice INV-1234", extracted.Title)
+	require.Equal("2024-03-15T00:00:00+01:00", extracted.Created)
+	require.Equal("ACME", extracted.Correspondent)
+	require.Equal("Invoice", extracted.DocumentType)
+	require.Equal([]string{"scanned"}, extracted.Tags)
+	require.Equal(map[string]string{"number": "INV-1234"}, extracted.CustomFields)
+
+	extracted, err = extractor.Extract("Lease [contract, home].pdf")
+	require.NoError(err)
+	require.Equal("tagged", extracted.Pattern)
+	require.Equal("Lease", extracted.Title)
+	require.Equal([]string{"contract", "home"}, extracted.Tags)
+
+	extracted, err = extractor.Extract("IMG_0001.jpg")
+	require.NoError(err)
+	require.Nil(extracted)
+
+	_, err = extractor.Extract("2024-13-45_ACME_Invoice_1.pdf")
+	require.ErrorContains(err, "date '2024-13-45'")
+
+	_, err = paperless.NewFilenameExtractor(paperless.FilenamePattern{Pattern: "(?P<broken"})
+	require.ErrorContains(err, "invalid filename pattern 'pattern 1'")
+	_, err = paperless.NewFilenameExtractor(paperless.FilenamePattern{Pattern: ".*", Timezone: "Nowhere/Atlantis"})
+	require.ErrorContains(err, "invalid timezone")
+}
+
+func TestPreviewExtraction(t *testing.T) {
+	require := require.New(t)
+
+	extractor, err := paperless.NewFilenameExtractor(paperless.FilenamePattern{
+		Name:     "scanner",
+		Pattern:  `^(?P<created>\d{8})_(?P<correspondent>[^_]+)_(?P<document_type>[^_]+)_(?P<cf_number>.+)$`,
+		Title:    "{document_type} {cf_number}",
+		Timezone: "UTC",
+		Tags:     []string{"scanned"},
+	})
+	require.NoError(err)
+
+	collections := newFakeCollections("tags", "correspondents", "document_types", "custom_fields")
+	scanned := collections.add("tags", map[string]interface{}{"name": "scanned"})
+	acme := collections.add("correspondents", map[string]interface{}{"name": "ACME"})
+	invoice := collections.add("document_types", map[string]interface{}{"name": "Invoice"})
+	number := collections.add("custom_fields", map[string]interface{}{"name": "number", "data_type": "string"})
+	server, mux := newFakeServer(t, collections)
+	consumer := newFakeConsumer()
+	consumer.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	previews, err := client.PreviewExtraction(ctx, extractor, []string{
+		"20240315_ACME_Invoice_INV-1234.pdf",
+		"20240315_Unknown_Invoice_INV-1235.pdf",
+		"IMG_0001.jpg",
+	})
+	require.NoError(err)
+	require.Len(previews, 3)
+	require.Empty(previews[0].Error)
+	require.Equal("Invoice INV-1234", *previews[0].Metadata.Title)
+	require.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), previews[0].Metadata.Created.UTC())
+	require.Equal(acme, *previews[0].Metadata.Correspondent)
+	require.Equal(invoice, *previews[0].Metadata.DocumentType)
+	require.Equal([]string{strconv.Itoa(scanned)}, previews[0].Metadata.Tags)
+	require.Equal(map[int]string{number: "INV-1234"}, previews[0].CustomFields)
+	require.Contains(previews[0].String(), "Invoice INV-1234")
+	require.Equal("unknown correspondents 'Unknown'", previews[1].Error)
+	require.Nil(previews[2].Extracted)
+	require.Contains(previews[2].String(), "no pattern matches")
+	require.Zero(consumer.uploadCount(), "preview must not upload")
+
+	// extracted entries feed the batch uploader
+	dir := t.TempDir()
+	path := filepath.Join(dir, "20240315_ACME_Invoice_INV-1234.pdf")
+	require.NoError(os.WriteFile(path, []byte("%PDF-extracted"), 0o644))
+	entries, err := extractor.ManifestEntries([]string{path})
+	require.NoError(err)
+	results, err := client.UploadBatch(ctx, entries, paperless.BatchOptions{})
+	require.NoError(err)
+	require.Equal(paperless.BatchDone, results[0].State)
+	require.Equal([]string{"Invoice INV-1234"}, consumer.uploads[0]["title"])
+	require.Equal([]string{strconv.Itoa(acme)}, consumer.uploads[0]["correspondent"])
+	require.Equal([]interface{}{map[string]interface{}{"field": float64(number), "value": "INV-1234"}}, consumer.customFields[results[0].DocumentID])
+}
+
+func TestMetadataFromFilenameResolved(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 6*TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	correspondentResp, err := client.CorrespondentsCreateWithResponse(ctx, paperless.CorrespondentsCreateJSONRequestBody{Name: randStr(16)})
+	require.NoError(err, "failed to create correspondent")
+	require.NotNil(correspondentResp.JSON201, "response json nil (create correspondent)")
+	correspondent := correspondentResp.JSON201
+	defer client.CorrespondentsDestroyWithResponse(ctx, *correspondent.Id)
+	docTypeResp, err := client.DocumentTypesCreateWithResponse(ctx, paperless.DocumentTypesCreateJSONRequestBody{Name: randStr(16)})
+	require.NoError(err, "failed to create document type")
+	require.NotNil(docTypeResp.JSON201, "response json nil (create document type)")
+	docType := docTypeResp.JSON201
+	defer client.DocumentTypesDestroyWithResponse(ctx, *docType.Id)
+
+	extractor, err := paperless.NewFilenameExtractor(paperless.FilenamePattern{
+		Pattern:  `^(?P<created>\d{8})_(?P<correspondent>[^_]+)_(?P<document_type>[^_]+)$`,
+		Title:    "{document_type} of {correspondent}",
+		Timezone: "UTC",
+	})
+	require.NoError(err)
+
+	metadata, _, err := client.MetadataFromFilename(ctx, extractor, "20240315_"+correspondent.Name+"_"+docType.Name+".pdf")
+	require.NoError(err, "failed to resolve extracted metadata")
+	require.NotNil(metadata)
+	require.Equal(docType.Name+" of "+correspondent.Name, *metadata.Title)
+	require.Equal(*correspondent.Id, *metadata.Correspondent)
+	require.Equal(*docType.Id, *metadata.DocumentType)
+	require.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), metadata.Created.UTC())
+
+	_, _, err = client.MetadataFromFilename(ctx, extractor, "20240315_"+randStr(16)+"_"+docType.Name+".pdf")
+	require.ErrorContains(err, "unknown correspondents")
+}
@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeCollections serves paginated CRUD endpoints (/api/<kind>/) from memory.
+type fakeCollections struct {
+	mu      sync.Mutex
+	nextID  int
+	objects map[string]map[int]map[string]interface{}
+	// requests as "METHOD path"
+	requests []string
+	// status codes to answer "METHOD kind" requests with
+	failures map[string]int
+}
+
+func newFakeCollections(kinds ...string) *fakeCollections {
+	output := &fakeCollections{
+		nextID:   100,
+		objects:  make(map[string]map[int]map[string]interface{}),
+		failures: make(map[string]int),
+	}
+	for _, kind := range kinds {
+		output.objects[kind] = make(map[int]map[string]interface{})
+	}
+	return output
+}
+
+// add stores an object and returns its id.
+func (f *fakeCollections) add(kind string, object map[string]interface{}) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	object["id"] = f.nextID
+	stored := make(map[string]interface{})
+	raw, _ := json.Marshal(object)
+	json.Unmarshal(raw, &stored)
+	f.objects[kind][f.nextID] = stored
+	return f.nextID
+}
+
+func (f *fakeCollections) get(kind string, id int) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[kind][id]
+}
+
+func (f *fakeCollections) byName(kind, name string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, object := range f.objects[kind] {
+		if object["name"] == name {
+			return object
+		}
+	}
+	return nil
+}
+
+func (f *fakeCollections) modifications() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := make([]string, 0)
+	for _, request := range f.requests {
+		if request[:4] != "GET " {
+			output = append(output, request)
+		}
+	}
+	return output
+}
+
+// fail makes requests of the method to the kind fail with status.
+func (f *fakeCollections) fail(method, kind string, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method+" "+kind] = status
+}
+
+func (f *fakeCollections) failed(w http.ResponseWriter, r *http.Request, kind string) bool {
+	status, ok := f.failures[r.Method+" "+kind]
+	if ok {
+		writeFakeJSON(w, status, map[string]string{"detail": "injected failure"})
+	}
+	return ok
+}
+
+func (f *fakeCollections) register(mux *http.ServeMux) {
+	for kind := range f.objects {
+		mux.HandleFunc(fmt.Sprintf("/api/%s/", kind), f.handleList(kind))
+		mux.HandleFunc(fmt.Sprintf("/api/%s/{id}/", kind), f.handleObject(kind))
+	}
+}
+
+func (f *fakeCollections) handleList(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		if f.failed(w, r, kind) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			ids := make([]int, 0)
+			for id := range f.objects[kind] {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+			if page < 1 {
+				page = 1
+			}
+			if pageSize < 1 {
+				pageSize = 25
+			}
+			results := make([]interface{}, 0)
+			for idx := (page - 1) * pageSize; idx < len(ids) && idx < page*pageSize; idx++ {
+				results = append(results, f.objects[kind][ids[idx]])
+			}
+			var next interface{}
+			if page*pageSize < len(ids) {
+				next = fmt.Sprintf("http://fake/api/%s/?page=%d", kind, page+1)
+			}
+			writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+				"count":    len(ids),
+				"next":     next,
+				"previous": nil,
+				"all":      ids,
+				"results":  results,
+			})
+		case http.MethodPost:
+			object := make(map[string]interface{})
+			if err := json.NewDecoder(r.Body).Decode(&object); err != nil {
+				writeFakeJSON(w, http.StatusBadRequest, map[string]string{"detail": err.Error()})
+				return
+			}
+			f.nextID++
+			object["id"] = float64(f.nextID)
+			f.objects[kind][f.nextID] = object
+			writeFakeJSON(w, http.StatusCreated, object)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (f *fakeCollections) handleObject(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		if f.failed(w, r, kind) {
+			return
+		}
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		object, ok := f.objects[kind][id]
+		if !ok {
+			writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeFakeJSON(w, http.StatusOK, object)
+		case http.MethodPatch, http.MethodPut:
+			update := make(map[string]interface{})
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				writeFakeJSON(w, http.StatusBadRequest, map[string]string{"detail": err.Error()})
+				return
+			}
+			if r.Method == http.MethodPut {
+				object = map[string]interface{}{"id": object["id"]}
+				f.objects[kind][id] = object
+			}
+			for key, value := range update {
+				object[key] = value
+			}
+			writeFakeJSON(w, http.StatusOK, object)
+		case http.MethodDelete:
+			delete(f.objects[kind], id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// newFakeServer serves the given collections, extra handlers may be
+// registered on the returned mux.
+func newFakeServer(t *testing.T, collections *fakeCollections) (*httptest.Server, *http.ServeMux) {
+	mux := http.NewServeMux()
+	if collections != nil {
+		collections.register(mux)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, mux
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeHistoryServer(t *testing.T) paperless.XClient {
+	client, err := paperless.NewXClientWithToken(newFakeHistoryURL(t), "token")
+	require.NoError(t, err, "failed to create client")
+	return client
+}
+
+func newFakeHistoryURL(t *testing.T) string {
+	collections := newFakeCollections("tags", "correspondents", "document_types", "storage_paths")
+	tagID := collections.add("tags", map[string]interface{}{"name": "invoice"})
+	correspondentID := collections.add("correspondents", map[string]interface{}{"name": "ACME"})
+	server, mux := newFakeServer(t, collections)
+	// paperless answers with a plain list
+	mux.HandleFunc("GET /api/documents/1/history/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, []interface{}{
+			map[string]interface{}{
+				"id": 11, "action": "update", "timestamp": "2026-01-02T10:00:00Z",
+				"actor": map[string]interface{}{"id": 2, "username": "alice"},
+				"changes": map[string]interface{}{
+					"title":         []interface{}{"scan", "Invoice 42"},
+					"correspondent": []interface{}{"None", strconv.Itoa(correspondentID)},
+					"tags":          map[string]interface{}{"type": "m2m", "operation": "add", "objects": []interface{}{strconv.Itoa(tagID)}},
+					"custom_fields": map[string]interface{}{"type": "custom_field", "field": "amount", "value": "12.50"},
+				},
+			},
+			map[string]interface{}{
+				"id": 10, "action": "create", "timestamp": "2026-01-01T10:00:00Z", "actor": nil,
+				"changes": map[string]interface{}{"title": []interface{}{"None", "scan"}},
+			},
+		})
+	})
+	mux.HandleFunc("GET /api/documents/1/notes/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, []interface{}{
+			map[string]interface{}{"id": 1, "note": "paid", "created": "2026-01-03T10:00:00Z"},
+		})
+	})
+	mux.HandleFunc("GET /api/documents/1/share_links/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, []interface{}{
+			map[string]interface{}{"id": 1, "slug": "abc", "created": "2026-01-01T12:00:00Z"},
+		})
+	})
+	mux.HandleFunc("GET /api/documents/2/history/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, []interface{}{
+			map[string]interface{}{
+				"id": 20, "action": "update", "timestamp": "2026-01-01T12:00:00Z", "actor": nil,
+				"changes": map[string]interface{}{
+					"tags": map[string]interface{}{"type": "m2m", "operation": "remove", "objects": []interface{}{strconv.Itoa(tagID)}},
+				},
+			},
+		})
+	})
+	return server.URL
+}
+
+func TestDocumentHistory(t *testing.T) {
+	require := require.New(t)
+	client := newFakeHistoryServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	entries, err := client.DocumentHistory(ctx, 1)
+	require.NoError(err)
+	require.Len(entries, 2)
+
+	require.Equal(paperless.HistoryCreate, entries[0].Action)
+	require.Equal("", entries[0].Actor)
+	require.Equal([]paperless.FieldChange{{Field: "title", After: "scan"}}, entries[0].Changes)
+
+	require.Equal(paperless.HistoryUpdate, entries[1].Action)
+	require.Equal("alice", entries[1].Actor)
+	require.Equal(1, entries[1].DocumentID)
+	require.Equal([]paperless.FieldChange{
+		{Field: "correspondent", After: "ACME"},
+		{Field: "custom_fields.amount", After: "12.50"},
+		{Field: "tags", Added: []string{"invoice"}},
+		{Field: "title", Before: "scan", After: "Invoice 42"},
+	}, entries[1].Changes)
+
+	out := &bytes.Buffer{}
+	require.NoError(paperless.WriteHistoryCSV(out, entries))
+	rows, err := csv.NewReader(out).ReadAll()
+	require.NoError(err)
+	require.Len(rows, 6)
+	require.Equal([]string{"1", "11", "2026-01-02T10:00:00Z", "alice", "update", "tags", "", "", "invoice", ""}, rows[4])
+
+	out.Reset()
+	require.NoError(paperless.WriteHistoryJSONL(out, entries))
+	require.Len(strings.Split(strings.TrimSpace(out.String()), "\n"), 2)
+}
+
+func TestDocumentTimeline(t *testing.T) {
+	require := require.New(t)
+	client := newFakeHistoryServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	events, err := client.DocumentTimeline(ctx, 1)
+	require.NoError(err)
+	kinds := make([]paperless.TimelineKind, 0)
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	require.Equal([]paperless.TimelineKind{
+		paperless.TimelineHistory,
+		paperless.TimelineShareLink,
+		paperless.TimelineHistory,
+		paperless.TimelineNote,
+	}, kinds)
+	require.Equal("abc", *events[1].ShareLink.Slug)
+	require.Equal("paid", *events[3].Note.Note)
+}
+
+func TestDocumentsHistory(t *testing.T) {
+	require := require.New(t)
+	transport := &pathRecorder{}
+	client, err := paperless.NewXClientWithHTTPClient(newFakeHistoryURL(t), &http.Client{Transport: transport})
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	entries, err := client.DocumentsHistory(ctx, []int{1, 2})
+	require.NoError(err)
+	require.Len(entries, 3)
+	require.Equal(2, entries[1].DocumentID, "entries of all documents ordered by time")
+	require.Equal([]paperless.FieldChange{{Field: "tags", Removed: []string{"invoice"}}}, entries[1].Changes)
+	require.Equal(1, transport.count("GET /api/tags/"), "referenced objects fetched per document")
+}
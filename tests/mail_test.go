@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+var testMbox = strings.Join([]string{
+	"From invoices@acme.example Fri Mar 15 09:30:00 2024",
+	"From: ACME Billing <invoices@acme.example>",
+	"To: office@example.org",
+	"Subject: =?UTF-8?Q?Invoice_M=C3=A4rz?=",
+	"Date: Fri, 15 Mar 2024 09:30:00 +0100",
+	"Message-ID: <invoice-1@acme.example>",
+	"MIME-Version: 1.0",
+	`Content-Type: multipart/mixed; boundary="outer"`,
+	"",
+	"--outer",
+	`Content-Type: multipart/related; boundary="inner"`,
+	"",
+	"--inner",
+	"Content-Type: text/plain; charset=utf-8",
+	"Content-Transfer-Encoding: quoted-printable",
+	"",
+	"Please find the invoice attached.",
+	">From now on we bill monthly.",
+	"--inner",
+	`Content-Type: image/png; name="logo.png"`,
+	"Content-Disposition: inline",
+	"Content-Transfer-Encoding: base64",
+	"",
+	"iVBORw0K",
+	"--inner--",
+	"--outer",
+	`Content-Type: application/pdf; name="INV-1.pdf"`,
+	`Content-Disposition: attachment; filename="INV-1.pdf"`,
+	"Content-Transfer-Encoding: base64",
+	"",
+	"JVBERi1pbnZv",
+	"aWNl",
+	"--outer",
+	"Content-Type: text/csv",
+	`Content-Disposition: attachment; filename="items.csv"`,
+	"",
+	"item,amount",
+	"--outer--",
+	"",
+	"From someone@example.org Sat Mar 16 10:00:00 2024",
+	"From: someone@example.org",
+	"Subject: no attachments",
+	"Date: Sat, 16 Mar 2024 10:00:00 +0000",
+	"",
+	"Hello",
+	"",
+}, "\n")
+
+func TestParseMail(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "invoices.mbox")
+	require.NoError(os.WriteFile(path, []byte(testMbox), 0o644))
+	messages, err := paperless.LoadMail(path)
+	require.NoError(err)
+	require.Len(messages, 2)
+
+	msg := messages[0]
+	require.Equal("invoice-1@acme.example", msg.MessageID)
+	require.Equal("Invoice März", msg.Subject)
+	require.Equal("ACME Billing", msg.From.Name)
+	require.Equal("invoices@acme.example", msg.From.Address)
+	require.True(msg.Date.Equal(time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC)))
+	require.Contains(msg.Text, "Please find the invoice attached.")
+	require.Contains(msg.Text, "\nFrom now on", "mboxrd escaping must be undone")
+	require.Len(msg.Attachments, 3)
+	require.Equal(paperless.MailAttachment{Filename: "logo.png", ContentType: "image/png", Inline: true, Content: []byte("\x89PNG\r\n")}, msg.Attachments[0])
+	require.Equal("INV-1.pdf", msg.Attachments[1].Filename)
+	require.Equal("%PDF-invoice", string(msg.Attachments[1].Content))
+	require.False(msg.Attachments[1].Inline)
+	require.Equal("text/csv", msg.Attachments[2].ContentType)
+
+	require.Equal("no attachments", messages[1].Subject)
+	require.Empty(messages[1].Attachments)
+
+	opts := paperless.MailIngestOptions{MimeTypes: []string{"application/*", "image/*"}}
+	require.False(opts.Matches(msg.Attachments[0]), "inline parts need AttachmentTypeEnumN2")
+	require.True(opts.Matches(msg.Attachments[1]))
+	require.False(opts.Matches(msg.Attachments[2]))
+	opts.AttachmentType = paperless.AttachmentTypeEnumN2
+	require.True(opts.Matches(msg.Attachments[0]))
+	opts.FilenameInclude = "*.PDF"
+	require.False(opts.Matches(msg.Attachments[0]))
+	require.True(opts.Matches(msg.Attachments[1]))
+	opts.FilenameExclude = "inv-*"
+	require.False(opts.Matches(msg.Attachments[1]))
+}
+
+func TestIngestMail(t *testing.T) {
+	require := require.New(t)
+
+	messages, err := paperless.ReadMbox(strings.NewReader(testMbox))
+	require.NoError(err)
+
+	collections := newFakeCollections("correspondents")
+	consumer := newFakeConsumer()
+	server, mux := newFakeServer(t, collections)
+	consumer.register(mux)
+	notesMu := sync.Mutex{}
+	notes := make(map[int][]string)
+	mux.HandleFunc("POST /api/documents/{id}/notes/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		notesMu.Lock()
+		defer notesMu.Unlock()
+		notes[id] = append(notes[id], body["note"])
+		writeFakeJSON(w, http.StatusOK, []interface{}{map[string]interface{}{"id": len(notes[id]), "note": body["note"]}})
+	})
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	results, err := client.IngestMail(ctx, messages, paperless.MailIngestOptions{
+		MimeTypes:               []string{"application/pdf"},
+		AssignCorrespondentFrom: paperless.AssignCorrespondentFromEnumN3,
+		Tags:                    []int{7},
+		OriginalNote:            true,
+	})
+	require.NoError(err)
+	require.Len(results, 1)
+	require.Empty(results[0].Error)
+	require.Equal("INV-1.pdf", results[0].Attachment)
+	require.NotZero(results[0].DocumentID)
+
+	require.Equal(1, consumer.uploadCount())
+	upload := consumer.uploads[0]
+	require.Equal("INV-1.pdf", consumer.filenames[0], "original filename changed")
+	require.Equal([]string{"Invoice März"}, upload["title"])
+	require.Equal([]string{"7"}, upload["tags"])
+	require.True(strings.HasPrefix(upload["created"][0], "2024-03-15"), upload["created"][0])
+	created := collections.byName("correspondents", "ACME Billing")
+	require.NotNil(created, "missing correspondent must be created")
+	acme := int(created["id"].(float64))
+	require.Equal([]string{strconv.Itoa(acme)}, upload["correspondent"])
+
+	require.Len(notes[results[0].DocumentID], 1)
+	note := notes[results[0].DocumentID][0]
+	require.Contains(note, `From: "ACME Billing" <invoices@acme.example>`)
+	require.Contains(note, "Message-ID: invoice-1@acme.example")
+	require.Contains(note, "Please find the invoice attached.")
+
+	// titles from filenames, existing correspondents are reused
+	results, err = client.IngestMail(ctx, messages[:1], paperless.MailIngestOptions{
+		FilenameInclude:         "*.pdf",
+		AssignTitleFrom:         paperless.AssignTitleFromEnumN2,
+		AssignCorrespondentFrom: paperless.AssignCorrespondentFromEnumN3,
+	})
+	require.NoError(err)
+	require.Len(results, 1)
+	require.Equal(2, consumer.uploadCount())
+	require.Equal([]string{"INV-1"}, consumer.uploads[1]["title"])
+	require.Equal([]string{strconv.Itoa(acme)}, consumer.uploads[1]["correspondent"])
+	require.Len(notes[results[0].DocumentID], 0)
+}
+
+func TestIngestMailConsumed(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*TEST_DOCUMENT_UPLOAD_TIMEOUT)
+	defer cancel()
+
+	sender := "Sender " + randStr(8)
+	subject := "Mail " + randStr(16)
+	attachment, err := os.ReadFile(writeTestPDF(t, "attachment.pdf", subject))
+	require.NoError(err)
+	msg, err := paperless.ParseMail(strings.NewReader(strings.Join([]string{
+		"From: " + sender + " <sender@example.org>",
+		"To: office@example.org",
+		"Subject: " + subject,
+		"Date: Fri, 15 Mar 2024 09:30:00 +0100",
+		"Message-ID: <" + randStr(16) + "@example.org>",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="outer"`,
+		"",
+		"--outer",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		"Please find the letter attached.",
+		"--outer",
+		`Content-Type: application/pdf; name="letter.pdf"`,
+		`Content-Disposition: attachment; filename="letter.pdf"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString(attachment),
+		"--outer--",
+		"",
+	}, "\r\n")))
+	require.NoError(err, "failed to parse mail")
+
+	results, err := client.IngestMail(ctx, []*paperless.MailMessage{msg}, paperless.MailIngestOptions{
+		AssignCorrespondentFrom: paperless.AssignCorrespondentFromEnumN3,
+		OriginalNote:            true,
+	})
+	require.NoError(err, "failed to ingest mail")
+	require.Len(results, 1)
+	require.Empty(results[0].Error)
+	require.NotZero(results[0].DocumentID)
+
+	documentResp, err := client.DocumentsRetrieveWithResponse(ctx, results[0].DocumentID, &paperless.DocumentsRetrieveParams{})
+	require.NoError(err, "failed to get document")
+	require.NotNil(documentResp.JSON200, "response json nil (get document)")
+	document := documentResp.JSON200
+	require.Equal(subject, *document.Title)
+	require.NotNil(document.Correspondent, "correspondent not assigned")
+	correspondentResp, err := client.CorrespondentsRetrieveWithResponse(ctx, *document.Correspondent, nil)
+	require.NoError(err, "failed to get correspondent")
+	require.NotNil(correspondentResp.JSON200, "response json nil (get correspondent)")
+	require.Equal(sender, correspondentResp.JSON200.Name)
+
+	metadataResp, err := client.DocumentsMetadataRetrieveWithResponse(ctx, results[0].DocumentID)
+	require.NoError(err, "failed to get metadata")
+	require.NotNil(metadataResp.JSON200, "response json nil (get metadata)")
+	require.Equal("letter.pdf", metadataResp.JSON200.OriginalFilename)
+
+	notes, err := client.ListNotes(ctx, results[0].DocumentID)
+	require.NoError(err, "failed to list notes")
+	require.Len(notes, 1)
+	require.Contains(notes[0].Text, "Please find the letter attached.")
+}
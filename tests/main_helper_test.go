@@ -1,8 +1,17 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io/fs"
 	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/burner-account/paperless-ngx-go"
@@ -12,28 +21,123 @@ func baseURL() string {
 	return fmt.Sprintf("http://127.0.0.1:%d", TEST_LOCALHOST_PORT)
 }
 
-func makeClient() (paperless.XClient, error) {
-	return paperless.NewXClientWithCredentials(
+// testMode selects whether tests talk to the compose stack ("live", default),
+// additionally record all exchanges ("record") or replay them ("replay").
+func testMode() string {
+	mode := os.Getenv(TEST_MODE_ENV)
+	if mode == "" {
+		return TEST_MODE_LIVE
+	}
+	return mode
+}
+
+// requireCassettes reports whether replayed tests fail without a cassette,
+// as they do in CI.
+func requireCassettes() bool {
+	return os.Getenv(TEST_REQUIRE_CASSETTES_ENV) != ""
+}
+
+func needsStack() bool {
+	return testMode() != TEST_MODE_REPLAY
+}
+
+func cassettePath(name string) string {
+	name = strings.NewReplacer("/", "_", " ", "_").Replace(name)
+	return filepath.Join(TEST_CASSETTE_DIR, name+".json")
+}
+
+// makeClient creates a client whose traffic is recorded to or replayed from
+// the named cassette, depending on the test mode. The returned recorder is nil
+// in live mode.
+func makeClient(cassette string) (paperless.XClient, *paperless.Recorder, error) {
+	var (
+		httpClient paperless.HttpRequestDoer
+		recorder   *paperless.Recorder
+		err        error
+	)
+	switch testMode() {
+	case TEST_MODE_LIVE:
+	case TEST_MODE_RECORD, TEST_MODE_REPLAY:
+		recorder, err = paperless.NewRecorder(
+			paperless.RecorderMode(testMode()),
+			cassettePath(cassette),
+			nil,
+		)
+		if err != nil {
+			return paperless.XClient{}, nil, err
+		}
+		httpClient = &http.Client{Transport: recorder}
+	default:
+		return paperless.XClient{}, nil, fmt.Errorf("unknown test mode '%s'", testMode())
+	}
+	client, err := paperless.NewXClientWithHTTPClient(
 		baseURL(),
-		TEST_USER,
-		TEST_PASSWORD,
+		httpClient,
+		paperless.MakeAPIVersionRequestEditor(TEST_API_VERSION),
+		paperless.MakeBasicAuthRequestEditor(TEST_USER, TEST_PASSWORD),
 	)
+	return client, recorder, err
 }
 
 func makeTestClient(t *testing.T) paperless.XClient {
-	client, err := makeClient()
+	client, _ := makeTestClientWithDoer(t)
+	return client
+}
+
+// makeTestClientWithDoer also returns an HTTP client sharing the recorder of
+// the client, for requests outside the API like public share links.
+func makeTestClientWithDoer(t *testing.T) (paperless.XClient, paperless.HttpRequestDoer) {
+	seedRand(t.Name())
+	client, recorder, err := makeClient(t.Name())
+	if errors.Is(err, fs.ErrNotExist) && !requireCassettes() {
+		t.Skipf("no recorded cassette for %s", t.Name())
+	}
 	if err != nil {
 		t.Fatalf("failed to create client: %v", err)
 	}
-	return client
+	if recorder == nil {
+		return client, http.DefaultClient
+	}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("failed to save cassette: %v", err)
+		}
+	})
+	return client, &http.Client{Transport: recorder}
 }
 
-var pool = []rune("abcdef1234567890")
+// seededDocument returns the ID of a document uploaded by seedDocuments.
+func seededDocument(ctx context.Context, t *testing.T, client paperless.XClient, seed seedFile) int {
+	resp, err := client.DocumentsListWithResponse(ctx, &paperless.DocumentsListParams{TitleIexact: paperless.P(seed.Title)})
+	if err != nil {
+		t.Fatalf("failed to list documents: %v", err)
+	}
+	if resp.JSON200 == nil || len(resp.JSON200.Results) == 0 || resp.JSON200.Results[0].Id == nil {
+		t.Fatalf("seeded document '%s' not found (status %d)", seed.Title, resp.StatusCode())
+	}
+	return *resp.JSON200.Results[0].Id
+}
+
+var (
+	pool = []rune("abcdef1234567890")
+	rnd  = rand.New(rand.NewSource(rand.Int63()))
+)
+
+// seedRand makes randStr deterministic per test while recording or replaying,
+// so request bodies match the recorded ones.
+func seedRand(name string) {
+	if testMode() == TEST_MODE_LIVE {
+		return
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	rnd = rand.New(rand.NewSource(int64(h.Sum64())))
+}
 
 func randStr(n int) string {
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = pool[rand.Intn(len(pool))]
+		b[i] = pool[rnd.Intn(len(pool))]
 	}
 	return string(b)
 }
@@ -53,3 +157,35 @@ func containsTaskStatus(arr []paperless.StatusEnum, status paperless.StatusEnum)
 	}
 	return false
 }
+
+// writeTestPDF writes a single page PDF showing the text, so uploads of
+// different tests are no duplicates of each other.
+func writeTestPDF(t *testing.T, name, text string) string {
+	stream := fmt.Sprintf("BT /F1 24 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("%PDF-1.4\n")
+	offsets := make([]int, 0, len(objects))
+	for idx, object := range objects {
+		offsets = append(offsets, buffer.Len())
+		fmt.Fprintf(buffer, "%d 0 obj\n%s\nendobj\n", idx+1, object)
+	}
+	xref := buffer.Len()
+	fmt.Fprintf(buffer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write test pdf: %v", err)
+	}
+	return path
+}
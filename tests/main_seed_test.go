@@ -27,10 +27,13 @@ var testdataDocuments []seedFile = []seedFile{
 }
 
 func seedDocuments(ctx context.Context) error {
-	client, err := makeClient()
+	client, recorder, err := makeClient("seed")
 	if err != nil {
 		return fmt.Errorf("failed to create client (seed documents): %w", err)
 	}
+	if recorder != nil {
+		defer recorder.Save()
+	}
 
 	eg := errgroup.Group{}
 
@@ -19,6 +19,18 @@ const (
 	TEST_LOCALHOST_PORT          int           = 8000
 	TEST_REQUEST_TIMEOUT         time.Duration = 2 * time.Second
 	TEST_DOCUMENT_UPLOAD_TIMEOUT time.Duration = 10 * time.Second
+	TEST_API_VERSION             int           = 9
+)
+
+// record/replay config
+const (
+	TEST_MODE_ENV     string = "PAPERLESS_TEST_MODE"
+	TEST_MODE_LIVE    string = "live"
+	TEST_MODE_RECORD  string = "record"
+	TEST_MODE_REPLAY  string = "replay"
+	TEST_CASSETTE_DIR string = "./testdata/cassettes"
+	// set to fail instead of skip tests without a cassette in replay mode
+	TEST_REQUIRE_CASSETTES_ENV string = "PAPERLESS_REQUIRE_CASSETTES"
 )
 
 // paperless-ngx config
@@ -137,6 +149,11 @@ func runRecoverably(ctx context.Context, m *testing.M, stack *compose.DockerComp
 }
 
 func TestMain(m *testing.M) {
+	if !needsStack() {
+		log.Printf("📼 replaying recorded exchanges from %s\n", TEST_CASSETTE_DIR)
+		os.Exit(m.Run())
+	}
+
 	stack, err := compose.NewDockerComposeWith(
 		compose.WithStackReaders(
 			strings.NewReader(dockerCompose()),
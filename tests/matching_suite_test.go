@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+func findLabelScore(report *paperless.SuiteReport, kind paperless.ObjectKind, name string) paperless.LabelScore {
+	for _, score := range report.Scores {
+		if score.Kind == kind && score.Name == name {
+			return score
+		}
+	}
+	return paperless.LabelScore{}
+}
+
+func TestRunLocalMatchingSuite(t *testing.T) {
+	require := require.New(t)
+	corpus, err := paperless.LoadMatchingCorpus("testdata/matching/corpus.yaml")
+	require.NoError(err, "failed to load corpus")
+	state, err := paperless.LoadDesiredState("testdata/matching/rules.yaml")
+	require.NoError(err, "failed to load rules")
+
+	report, err := paperless.RunLocalMatchingSuite(corpus, state.Matcher())
+	require.NoError(err, "failed to run suite")
+	require.Equal(3, report.Documents)
+
+	invoice := findLabelScore(report, paperless.KindTags, "invoice")
+	require.Equal(1, invoice.TruePositives)
+	require.Equal(1, invoice.FalsePositives, "the letter mentions invoices")
+	require.Equal(0.5, invoice.Precision())
+	require.Equal(1.0, invoice.Recall())
+	require.Equal(1.0, findLabelScore(report, paperless.KindCorrespondents, "ACME").F1())
+	require.Len(report.Mistakes, 1)
+	require.Equal("testdata/matching/letter.txt", report.Mistakes[0].Document)
+
+	// a broken rule regresses compared to the baseline
+	state.Objects[paperless.KindCorrespondents][0]["match"] = "acme corp."
+	broken, err := paperless.RunLocalMatchingSuite(corpus, state.Matcher())
+	require.NoError(err, "failed to run suite")
+	regressions := broken.Regressions(report, 0.01)
+	require.Len(regressions, 2, "ACME and total")
+	require.Equal("ACME", regressions[0].Name)
+	require.Empty(report.Regressions(report, 0))
+}
+
+func TestRunMatchingSuiteSuggestions(t *testing.T) {
+	require := require.New(t)
+	collections := newFakeCollections("tags", "correspondents", "document_types")
+	invoiceID := collections.add("tags", map[string]interface{}{"name": "invoice"})
+	acmeID := collections.add("correspondents", map[string]interface{}{"name": "ACME"})
+	collections.add("document_types", map[string]interface{}{"name": "Invoice"})
+	server, mux := newFakeServer(t, collections)
+	mux.HandleFunc("GET /api/documents/{id}/suggestions/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"tags":           []int{invoiceID},
+			"correspondents": []int{acmeID},
+			"document_types": []int{},
+			"storage_paths":  []int{},
+			"dates":          []string{},
+		})
+	})
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	corpus := &paperless.MatchingCorpus{Documents: []paperless.LabeledDocument{
+		{ID: 1, Tags: &[]string{"invoice"}, Correspondent: paperless.P("ACME"), DocumentType: paperless.P("Invoice")},
+		{File: "testdata/matching/letter.txt", Tags: &[]string{}},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	report, err := client.RunMatchingSuite(ctx, corpus, paperless.MatchingSuiteOptions{Source: paperless.SuiteSuggestions})
+	require.NoError(err, "failed to run suite")
+	require.Equal(1, report.Documents)
+	require.Equal([]string{"testdata/matching/letter.txt"}, report.Skipped)
+	require.Equal(1.0, findLabelScore(report, paperless.KindTags, "invoice").F1())
+	require.Equal(0.0, findLabelScore(report, paperless.KindDocumentTypes, "Invoice").Recall())
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+const matchingContent string = `ACME Corporation GmbH
+Rechnung Nr. 2024-0815 für Müller
+
+Total amount: 12,50 EUR
+Thank you for your business with c++ tooling.`
+
+func TestMatchingRuleEvaluate(t *testing.T) {
+	cases := []struct {
+		name        string
+		match       string
+		algorithm   paperless.MatchingAlgorithm
+		insensitive bool
+		matched     bool
+		matches     []string
+	}{
+		{"none never matches", "acme", paperless.MatchingNone, true, false, nil},
+		{"empty match", "  ", paperless.MatchingAny, true, false, nil},
+		{"any", "invoice rechnung", paperless.MatchingAny, true, true, []string{"Rechnung"}},
+		{"any case sensitive", "rechnung", paperless.MatchingAny, false, false, nil},
+		{"any needs word boundaries", "acm corp", paperless.MatchingAny, true, false, nil},
+		{"any unicode word", "müller", paperless.MatchingAny, true, true, []string{"Müller"}},
+		{"unicode is part of words", "ller", paperless.MatchingAny, true, false, nil},
+		{"all", "acme rechnung", paperless.MatchingAll, true, true, []string{"ACME", "Rechnung"}},
+		{"all missing word", "acme invoice", paperless.MatchingAll, true, false, nil},
+		{"quoted words", `"acme   corporation" total`, paperless.MatchingAll, true, true, []string{"ACME Corporation", "Total"}},
+		{"literal", "Total amount", paperless.MatchingLiteral, false, true, []string{"Total amount"}},
+		{"literal is not split", "amount Total", paperless.MatchingLiteral, false, false, nil},
+		{"literal punctuation at the end has no boundary", "c++", paperless.MatchingLiteral, true, false, nil},
+		{"regex", `Nr\. \d{4}-\d+`, paperless.MatchingRegex, false, true, []string{"Nr. 2024-0815"}},
+		{"regex no match", `^Invoice`, paperless.MatchingRegex, true, false, nil},
+		{"fuzzy", "ACME Corporatoin", paperless.MatchingFuzzy, true, true, []string{"acme corporation"}},
+		{"fuzzy too different", "Umbrella Corp", paperless.MatchingFuzzy, true, false, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := paperless.MatchingRule{
+				Kind:          paperless.KindTags,
+				Name:          tc.name,
+				Match:         tc.match,
+				Algorithm:     tc.algorithm,
+				IsInsensitive: tc.insensitive,
+			}
+			result := rule.Evaluate(matchingContent)
+			require.True(t, result.Simulated)
+			require.Equal(t, tc.matched, result.Matched, result.Reason)
+			require.Equal(t, tc.matches, result.Matches, result.Reason)
+			require.NotEmpty(t, result.Reason)
+		})
+	}
+}
+
+func TestMatchingRuleNotSimulated(t *testing.T) {
+	require := require.New(t)
+
+	result := paperless.MatchingRule{Match: `(?<=Nr\. )\d+`, Algorithm: paperless.MatchingRegex}.Evaluate(matchingContent)
+	require.False(result.Simulated, "lookbehinds are python only")
+	require.False(result.Matched)
+
+	result = paperless.MatchingRule{Algorithm: paperless.MatchingAuto}.Evaluate(matchingContent)
+	require.False(result.Simulated)
+}
+
+func TestFetchMatcher(t *testing.T) {
+	require := require.New(t)
+	collections := newFakeCollections("tags", "correspondents", "workflows")
+	collections.add("tags", map[string]interface{}{"name": "invoice", "match": "rechnung invoice", "matching_algorithm": 1, "is_insensitive": true})
+	collections.add("tags", map[string]interface{}{"name": "receipt", "match": "receipt", "matching_algorithm": 1, "is_insensitive": true})
+	collections.add("correspondents", map[string]interface{}{"name": "ACME", "match": "acme corporation", "matching_algorithm": 3, "is_insensitive": true})
+	collections.add("workflows", map[string]interface{}{
+		"name": "flag totals",
+		"triggers": []interface{}{
+			map[string]interface{}{"id": 1, "type": 2, "match": "total", "matching_algorithm": 1, "is_insensitive": true},
+			map[string]interface{}{"id": 2, "type": 1, "filter_filename": "*"},
+		},
+	})
+	server, _ := newFakeServer(t, collections)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	matcher, err := client.FetchMatcher(ctx, paperless.KindTags, paperless.KindCorrespondents, paperless.KindWorkflows)
+	require.NoError(err, "failed to fetch matcher")
+	require.Len(matcher.Rules, 4, "triggers without matching are skipped")
+
+	matched := make([]string, 0)
+	for _, result := range matcher.Match(matchingContent) {
+		matched = append(matched, result.Rule.Name)
+	}
+	require.Equal([]string{"invoice", "ACME", "flag totals trigger 1"}, matched)
+}
+
+func TestMatcherAssignsFirstMatchOnly(t *testing.T) {
+	require := require.New(t)
+	matcher := paperless.Matcher{Rules: []paperless.MatchingRule{
+		{Kind: paperless.KindCorrespondents, Name: "ACME", Match: "acme", Algorithm: paperless.MatchingAny, IsInsensitive: true},
+		{Kind: paperless.KindTags, Name: "total", Match: "total", Algorithm: paperless.MatchingAny, IsInsensitive: true},
+		{Kind: paperless.KindCorrespondents, Name: "ACME Corp", Match: "acme corporation", Algorithm: paperless.MatchingLiteral, IsInsensitive: true},
+		{Kind: paperless.KindTags, Name: "rechnung", Match: "rechnung", Algorithm: paperless.MatchingAny, IsInsensitive: true},
+	}}
+
+	matched := make([]string, 0)
+	for _, result := range matcher.Match(matchingContent) {
+		matched = append(matched, result.Rule.Name)
+	}
+	require.Equal([]string{"ACME", "total", "rechnung"}, matched, "all tags, but only the first correspondent")
+}
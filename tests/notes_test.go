@@ -0,0 +1,295 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotes serves the notes of documents with the plain list answers of
+// paperless, plus document listing and checksum lookup.
+type fakeNotes struct {
+	mu        sync.Mutex
+	nextID    int
+	clock     time.Time
+	notes     map[int][]map[string]interface{}
+	checksums map[int]string
+	// lookups are the checksums documents were listed by
+	lookups []string
+	// metadata, if set, adds to the metadata of a document
+	metadata func(id int, metadata map[string]interface{})
+}
+
+func newFakeNotes() *fakeNotes {
+	return &fakeNotes{
+		nextID:    1,
+		clock:     time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC),
+		notes:     make(map[int][]map[string]interface{}),
+		checksums: make(map[int]string),
+	}
+}
+
+func (f *fakeNotes) addDocument(id int, checksum string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checksums[id] = checksum
+	f.notes[id] = make([]map[string]interface{}, 0)
+}
+
+// add stores a note, the caller holds the lock.
+func (f *fakeNotes) add(document int, text string) {
+	f.clock = f.clock.Add(time.Minute)
+	f.notes[document] = append(f.notes[document], map[string]interface{}{
+		"id":      f.nextID,
+		"note":    text,
+		"created": f.clock,
+		"user":    map[string]interface{}{"id": 1, "username": "admin"},
+	})
+	f.nextID++
+}
+
+func (f *fakeNotes) texts(document int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := make([]string, 0)
+	for _, note := range f.notes[document] {
+		output = append(output, note["note"].(string))
+	}
+	return output
+}
+
+func (f *fakeNotes) register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/documents/{id}/notes/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.notes[id]; !ok {
+			writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.add(id, body["note"])
+		case http.MethodDelete:
+			noteID, _ := strconv.Atoi(r.URL.Query().Get("id"))
+			kept := make([]map[string]interface{}, 0)
+			for _, note := range f.notes[id] {
+				if note["id"] != noteID {
+					kept = append(kept, note)
+				}
+			}
+			f.notes[id] = kept
+		}
+		// newest first, as paperless does
+		output := append([]map[string]interface{}{}, f.notes[id]...)
+		sort.Slice(output, func(i, j int) bool {
+			return output[i]["id"].(int) > output[j]["id"].(int)
+		})
+		writeFakeJSON(w, http.StatusOK, output)
+	})
+	mux.HandleFunc("GET /api/documents/{id}/metadata/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		f.mu.Lock()
+		metadata := map[string]interface{}{"original_checksum": f.checksums[id], "original_filename": "doc.pdf"}
+		f.mu.Unlock()
+		if f.metadata != nil {
+			f.metadata(id, metadata)
+		}
+		writeFakeJSON(w, http.StatusOK, metadata)
+	})
+	mux.HandleFunc("GET /api/documents/{$}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.URL.Query().Has("checksum__iexact") {
+			f.lookups = append(f.lookups, r.URL.Query().Get("checksum__iexact"))
+		}
+		ids := make([]int, 0)
+		for id, checksum := range f.checksums {
+			if query := r.URL.Query().Get("checksum__iexact"); query == "" || query == checksum {
+				ids = append(ids, id)
+			}
+		}
+		sort.Ints(ids)
+		results := make([]interface{}, 0)
+		for _, id := range ids {
+			results = append(results, map[string]interface{}{"id": id, "title": "doc " + strconv.Itoa(id), "notes": f.notes[id]})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+}
+
+func newFakeNotesClient(t *testing.T, notes *fakeNotes) paperless.XClient {
+	server, mux := newFakeServer(t, nil)
+	notes.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(t, err, "failed to create client")
+	return client
+}
+
+func TestNotes(t *testing.T) {
+	require := require.New(t)
+
+	notes := newFakeNotes()
+	notes.addDocument(1, "aaa")
+	client := newFakeNotesClient(t, notes)
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	first, err := client.AddNote(ctx, 1, "first")
+	require.NoError(err)
+	require.Equal("first", first.Text)
+	require.Equal(1, first.DocumentID)
+	require.Equal("admin", first.Username)
+	second, err := client.AddNote(ctx, 1, "second")
+	require.NoError(err)
+	_, err = client.AddNote(ctx, 1, "third")
+	require.NoError(err)
+
+	listed, err := client.ListNotes(ctx, 1)
+	require.NoError(err)
+	require.Len(listed, 3)
+	require.Equal("first", listed[0].Text, "oldest first")
+	require.Equal(second.ID, listed[1].ID)
+
+	// editing keeps the order
+	listed, err = client.UpdateNote(ctx, 1, second.ID, "second, edited")
+	require.NoError(err)
+	require.Equal([]string{"first", "second, edited", "third"}, noteTexts(listed))
+	require.Equal(first.ID, listed[0].ID, "notes before the edited one are kept")
+	require.Equal([]string{"first", "second, edited", "third"}, notes.texts(1))
+
+	require.NoError(client.DeleteNote(ctx, 1, first.ID))
+	require.Equal([]string{"second, edited", "third"}, notes.texts(1))
+
+	_, err = client.UpdateNote(ctx, 1, 999, "missing")
+	require.ErrorContains(err, "note 999 not found")
+	_, err = client.AddNote(ctx, 2, "no such document")
+	require.ErrorContains(err, "404")
+}
+
+func TestExportImportNotes(t *testing.T) {
+	require := require.New(t)
+
+	source := newFakeNotes()
+	source.addDocument(1, "aaa")
+	source.addDocument(2, "bbb")
+	source.addDocument(3, "ccc")
+	source.mu.Lock()
+	source.add(1, "checked by accounting")
+	source.add(1, "paid")
+	source.add(3, "expires 2025")
+	source.mu.Unlock()
+	client := newFakeNotesClient(t, source)
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	exported, err := client.ExportNotes(ctx)
+	require.NoError(err)
+	require.Len(exported, 2, "documents without notes are not exported")
+	require.Equal("aaa", exported[0].Checksum)
+	require.Equal([]string{"checked by accounting", "paid"}, noteTexts(exported[0].Notes))
+
+	markdown := &bytes.Buffer{}
+	require.NoError(paperless.WriteNotesMarkdown(markdown, exported))
+	require.Contains(markdown.String(), "## doc 1\n\ndocument 1, checksum `aaa`")
+	require.Contains(markdown.String(), "admin\n\npaid\n")
+
+	jsonl := &bytes.Buffer{}
+	require.NoError(paperless.WriteNotesJSONL(jsonl, exported))
+	read, err := paperless.ReadNotesJSONL(jsonl)
+	require.NoError(err)
+	require.Equal(exported, read)
+
+	// the migrated archive has other IDs, one note already and lacks "ccc"
+	target := newFakeNotes()
+	target.addDocument(10, "aaa")
+	target.mu.Lock()
+	target.add(10, "paid")
+	target.mu.Unlock()
+	client = newFakeNotesClient(t, target)
+
+	report, err := client.ImportNotes(ctx, read, paperless.NotesImportOptions{DryRun: true})
+	require.NoError(err)
+	require.Equal(1, report.Imported)
+	require.Equal(1, report.Skipped)
+	require.Equal([]string{"ccc"}, report.Missing)
+	require.Equal([]string{"paid"}, target.texts(10))
+
+	report, err = client.ImportNotes(ctx, read, paperless.NotesImportOptions{})
+	require.NoError(err)
+	require.Equal(1, report.Imported)
+	require.Equal([]string{"paid", "checked by accounting"}, target.texts(10))
+
+	report, err = client.ImportNotes(ctx, read, paperless.NotesImportOptions{})
+	require.NoError(err)
+	require.Zero(report.Imported, "imports can be repeated")
+
+	// notes without checksum must not end up at any document
+	target.mu.Lock()
+	target.lookups = nil
+	target.mu.Unlock()
+	report, err = client.ImportNotes(ctx, []paperless.DocumentNotes{{Notes: read[0].Notes}}, paperless.NotesImportOptions{})
+	require.NoError(err)
+	require.Zero(report.Imported)
+	require.Equal([]string{""}, report.Missing)
+	require.Empty(target.lookups, "documents listed without checksum")
+}
+
+func noteTexts(notes []paperless.Note) []string {
+	output := make([]string, 0, len(notes))
+	for _, note := range notes {
+		output = append(output, note.Text)
+	}
+	return output
+}
+
+func TestNotesRoundTrip(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	id := seededDocument(ctx, t, client, testdataDocuments[0])
+	first, err := client.AddNote(ctx, id, "first "+randStr(8))
+	require.NoError(err, "failed to add note")
+	require.Equal(id, first.DocumentID)
+	require.Equal(TEST_USER, first.Username)
+	second, err := client.AddNote(ctx, id, "second "+randStr(8))
+	require.NoError(err, "failed to add note")
+
+	notes, err := client.UpdateNote(ctx, id, first.ID, first.Text+", edited")
+	require.NoError(err, "failed to update note")
+	texts := noteTexts(notes)
+	require.Equal([]string{first.Text + ", edited", second.Text}, texts[len(texts)-2:], "order not kept")
+
+	exported, err := client.ExportNotes(ctx)
+	require.NoError(err, "failed to export notes")
+	var document *paperless.DocumentNotes
+	for idx := range exported {
+		if exported[idx].DocumentID == id {
+			document = &exported[idx]
+		}
+	}
+	require.NotNil(document, "document missing in export")
+	require.Len(document.Checksum, 32)
+	require.Equal(noteTexts(notes), noteTexts(document.Notes))
+
+	for _, note := range notes[len(notes)-2:] {
+		require.NoError(client.DeleteNote(ctx, id, note.ID), "failed to delete note")
+	}
+	notes, err = client.ListNotes(ctx, id)
+	require.NoError(err, "failed to list notes")
+	require.NotContains(noteTexts(notes), second.Text)
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+const reconcileDesiredState string = `
+protected:
+  tags: [inbox]
+tags:
+  - name: invoice
+    color: "#ff0000"
+    matching_algorithm: 0
+  - name: paid
+    parent: invoice
+correspondents:
+  - name: acme
+workflows:
+  - name: tag acme invoices
+    order: 1
+    triggers:
+      - type: 1
+        filter_filename: "*inv*"
+        filter_has_correspondent: acme
+    actions:
+      - type: 1
+        assign_tags: [invoice, paid]
+`
+
+func newReconcileFixture(t *testing.T) (*fakeCollections, paperless.XClient) {
+	collections := newFakeCollections(
+		"tags", "correspondents", "document_types", "storage_paths", "custom_fields",
+		"mail_accounts", "mail_rules", "saved_views", "workflows",
+	)
+	collections.add("tags", map[string]interface{}{"name": "inbox", "is_inbox_tag": true})
+	collections.add("tags", map[string]interface{}{"name": "invoice", "color": "#00ff00", "matching_algorithm": 0})
+	collections.add("tags", map[string]interface{}{"name": "obsolete"})
+	server, _ := newFakeServer(t, collections)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(t, err, "failed to create client")
+	return collections, client
+}
+
+func TestParseDesiredStateInvalid(t *testing.T) {
+	require := require.New(t)
+
+	_, err := paperless.ParseDesiredState([]byte("tags:\n  - name: a\n    colour: red\n  - name: a\n"))
+	require.ErrorContains(err, "tags 'a': unknown field 'colour'")
+	require.ErrorContains(err, "tags 'a': duplicate name")
+
+	_, err = paperless.ParseDesiredState([]byte("workflows:\n  - name: w\n    actions:\n      - assign_tag: [a]\n"))
+	require.ErrorContains(err, "workflows 'w' actions[0]: unknown field 'assign_tag'")
+
+	_, err = paperless.ParseDesiredState([]byte("documents: []\n"))
+	require.ErrorContains(err, "unknown section 'documents'")
+}
+
+func TestReconcile(t *testing.T) {
+	require := require.New(t)
+	collections, client := newReconcileFixture(t)
+	state, err := paperless.ParseDesiredState([]byte(reconcileDesiredState))
+	require.NoError(err, "failed to parse desired state")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	plan, err := client.PlanReconcile(ctx, state, paperless.ReconcileOptions{})
+	require.NoError(err, "failed to plan")
+	require.True(plan.HasChanges(), plan.String())
+	require.Empty(collections.modifications(), "planning must not modify anything")
+
+	actions := make(map[string]paperless.PlanAction)
+	for _, change := range plan.Changes {
+		actions[string(change.Kind)+"/"+change.Name] = change.Action
+	}
+	require.Equal(map[string]paperless.PlanAction{
+		"tags/invoice":                paperless.PlanUpdate,
+		"tags/paid":                   paperless.PlanCreate,
+		"tags/inbox":                  paperless.PlanUnmanaged,
+		"tags/obsolete":               paperless.PlanUnmanaged,
+		"correspondents/acme":         paperless.PlanCreate,
+		"workflows/tag acme invoices": paperless.PlanCreate,
+	}, actions, plan.String())
+
+	_, err = client.ApplyPlan(ctx, plan)
+	require.NoError(err, "failed to apply plan")
+	require.Equal("#ff0000", collections.byName("tags", "invoice")["color"])
+	invoiceID := collections.byName("tags", "invoice")["id"]
+	paidID := collections.byName("tags", "paid")["id"]
+	require.Equal(invoiceID, collections.byName("tags", "paid")["parent"])
+	workflow := collections.byName("workflows", "tag acme invoices")
+	require.NotNil(workflow)
+	action := workflow["actions"].([]interface{})[0].(map[string]interface{})
+	require.ElementsMatch([]interface{}{invoiceID, paidID}, action["assign_tags"])
+	trigger := workflow["triggers"].([]interface{})[0].(map[string]interface{})
+	require.Equal(collections.byName("correspondents", "acme")["id"], trigger["filter_has_correspondent"])
+	require.NotNil(collections.byName("tags", "obsolete"), "unmanaged objects are kept without prune")
+
+	// converged: only the unmanaged tags remain
+	plan, err = client.PlanReconcile(ctx, state, paperless.ReconcileOptions{})
+	require.NoError(err, "failed to plan")
+	require.False(plan.HasChanges(), plan.String())
+	require.True(plan.HasDrift())
+
+	// pruning deletes everything but the protected inbox tag
+	plan, err = client.PlanReconcile(ctx, state, paperless.ReconcileOptions{Prune: true})
+	require.NoError(err, "failed to plan")
+	_, err = client.ApplyPlan(ctx, plan)
+	require.NoError(err, "failed to apply plan")
+	require.Nil(collections.byName("tags", "obsolete"))
+	require.NotNil(collections.byName("tags", "inbox"))
+
+	plan, err = client.PlanReconcile(ctx, state, paperless.ReconcileOptions{Prune: true})
+	require.NoError(err, "failed to plan")
+	require.Len(plan.Changes, 1, plan.String())
+	require.Equal(paperless.PlanUnmanaged, plan.Changes[0].Action)
+}
+
+func TestReconcileUnresolvedReference(t *testing.T) {
+	require := require.New(t)
+	_, client := newReconcileFixture(t)
+	state, err := paperless.ParseDesiredState([]byte("tags:\n  - name: child\n    parent: missing\n"))
+	require.NoError(err, "failed to parse desired state")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	_, _, err = client.Reconcile(ctx, state, paperless.ReconcileOptions{})
+	require.ErrorContains(err, "tags 'child' references unknown tags 'missing'")
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+const recorderTestTaskID string = "0b7c9a7e-3d7e-4c5e-9d1a-2f0c8e6b4a11"
+
+func newFakeUploadServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/documents/post_document/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: "s3cr3t"})
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `"%s"`, recorderTestTaskID)
+	})
+	mux.HandleFunc("GET /api/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(
+			w,
+			`[{"id": 1, "task_id": "%s", "status": "SUCCESS", "date_created": "%s"}]`,
+			r.URL.Query().Get("task_id"),
+			time.Now().Format(time.RFC3339Nano),
+		)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func uploadAndFetch(ctx context.Context, client paperless.XClient) (*paperless.TasksView, error) {
+	taskID, err := client.UploadDocument(ctx, "./testdata/test-01.pdf", "recorded", time.Now(), []int{1, 2})
+	if err != nil {
+		return nil, err
+	}
+	return client.FetchTask(ctx, taskID)
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	require := require.New(t)
+	server := newFakeUploadServer(t)
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	// record against the fake server
+	recorder, err := paperless.NewRecorder(paperless.RecorderModeRecord, cassette, nil)
+	require.NoError(err, "failed to create recorder")
+	client, err := paperless.NewXClientWithHTTPClient(
+		server.URL,
+		&http.Client{Transport: recorder},
+		paperless.MakeTokenAuthRequestEditor("very-secret-token"),
+	)
+	require.NoError(err, "failed to create client")
+
+	recordedTask, err := uploadAndFetch(ctx, client)
+	require.NoError(err, "failed to upload (record)")
+	require.Equal(recorderTestTaskID, recordedTask.TaskId)
+	require.NoError(recorder.Save(), "failed to save cassette")
+
+	raw, err := os.ReadFile(cassette)
+	require.NoError(err, "failed to read cassette")
+	require.NotContains(string(raw), "very-secret-token", "credentials not redacted")
+	require.NotContains(string(raw), "s3cr3t", "csrf token not redacted")
+	require.NotContains(string(raw), recorderTestTaskID, "task id not normalized")
+
+	var recorded paperless.Cassette
+	require.NoError(json.Unmarshal(raw, &recorded))
+	require.Len(recorded.Interactions, 2)
+
+	// replay without any server
+	server.Close()
+	recorder, err = paperless.NewRecorder(paperless.RecorderModeReplay, cassette, nil)
+	require.NoError(err, "failed to create replaying recorder")
+	client, err = paperless.NewXClientWithHTTPClient(
+		server.URL,
+		&http.Client{Transport: recorder},
+		paperless.MakeTokenAuthRequestEditor("another-token"),
+	)
+	require.NoError(err, "failed to create client")
+
+	replayedTask, err := uploadAndFetch(ctx, client)
+	require.NoError(err, "failed to upload (replay)")
+	require.NotNil(replayedTask.Status)
+	require.Equal(paperless.StatusEnumSUCCESS, *replayedTask.Status)
+
+	// requests with different bodies must not match
+	_, err = client.UploadDocument(ctx, "./testdata/squirrel-wikipedia.pdf", "recorded", time.Now(), nil)
+	require.ErrorIs(err, paperless.ErrNoRecordedInteraction)
+}
+
+func TestRecorderStatusWebsocket(t *testing.T) {
+	require := require.New(t)
+	server := newFakeSessionServer(t)
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	// the upgraded connection is passed on while recording
+	recorder, err := paperless.NewRecorder(paperless.RecorderModeRecord, cassette, nil)
+	require.NoError(err, "failed to create recorder")
+	client, err := paperless.NewXClientWithHTTPClient(
+		server.URL,
+		&http.Client{Transport: recorder},
+		paperless.MakeBasicAuthRequestEditor("paperless", "secret"),
+	)
+	require.NoError(err, "failed to create client")
+	messages, err := client.SubscribeStatus(ctx)
+	require.NoError(err, "failed to subscribe (record)")
+	require.Equal("abc", (<-messages).TaskID)
+	require.NoError(recorder.Save(), "failed to save cassette")
+
+	raw, err := os.ReadFile(cassette)
+	require.NoError(err, "failed to read cassette")
+	require.NotContains(string(raw), "secret", "password not redacted")
+
+	// only the handshake is replayed, the connection ends right away
+	server.Close()
+	recorder, err = paperless.NewRecorder(paperless.RecorderModeReplay, cassette, nil)
+	require.NoError(err, "failed to create replaying recorder")
+	client, err = paperless.NewXClientWithHTTPClient(
+		server.URL,
+		&http.Client{Transport: recorder},
+		paperless.MakeBasicAuthRequestEditor("paperless", "secret"),
+	)
+	require.NoError(err, "failed to create client")
+	messages, err = client.SubscribeStatus(ctx)
+	require.NoError(err, "failed to subscribe (replay)")
+	_, ok := <-messages
+	require.False(ok, "replayed connection not closed")
+}
@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+const TEST_RETENTION_POLICY string = `
+legal_hold_tag: legal-hold
+rules:
+  - name: invoices
+    document_type: Invoice
+    max_age: 10y
+  - name: applications
+    tags: [application]
+    max_age: 6m
+    basis: added
+`
+
+type fakeRetentionDocument struct {
+	id           int
+	documentType int
+	tags         []int
+	created      string
+	added        time.Time
+}
+
+// fakeRetentionServer serves the filters used by retention rules, downloads
+// and bulk deletion.
+type fakeRetentionServer struct {
+	mu        sync.Mutex
+	documents []fakeRetentionDocument
+	deleted   []int
+}
+
+func (f *fakeRetentionServer) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		query := r.URL.Query()
+		results := make([]interface{}, 0)
+		for _, document := range f.documents {
+			if value := query.Get("created__lt"); value != "" && document.created >= value {
+				continue
+			}
+			if value := query.Get("added__lt"); value != "" {
+				cutoff, _ := time.Parse(time.RFC3339Nano, value)
+				if !document.added.Before(cutoff) {
+					continue
+				}
+			}
+			if value := query.Get("document_type__id"); value != "" && value != strconv.Itoa(document.documentType) {
+				continue
+			}
+			if value := query.Get("tags__id__all"); value != "" {
+				id, _ := strconv.Atoi(value)
+				found := false
+				for _, tag := range document.tags {
+					found = found || tag == id
+				}
+				if !found {
+					continue
+				}
+			}
+			results = append(results, map[string]interface{}{
+				"id": document.id, "title": "doc " + strconv.Itoa(document.id),
+				"tags": document.tags, "created": document.created, "added": document.added,
+			})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("GET /api/documents/{id}/download/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="invoice.pdf"`)
+		w.Write([]byte("%PDF-" + r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /api/documents/bulk_edit/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var req paperless.BulkEditRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == nil || *req.Method != paperless.MethodEnumDelete {
+			writeFakeJSON(w, http.StatusBadRequest, map[string]interface{}{"method": "unexpected"})
+			return
+		}
+		f.deleted = append(f.deleted, req.Documents...)
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"result": "OK"})
+	})
+}
+
+func TestApplyRetention(t *testing.T) {
+	require := require.New(t)
+
+	policy, err := paperless.ParseRetentionPolicy([]byte(TEST_RETENTION_POLICY))
+	require.NoError(err)
+	require.Equal(paperless.RetentionAge{Years: 10}, policy.Rules[0].MaxAge)
+	require.Equal(paperless.RetentionAge{Months: 6}, policy.Rules[1].MaxAge)
+
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	collections := newFakeCollections("tags", "correspondents", "document_types")
+	hold := collections.add("tags", map[string]interface{}{"name": "legal-hold"})
+	application := collections.add("tags", map[string]interface{}{"name": "application"})
+	invoice := collections.add("document_types", map[string]interface{}{"name": "Invoice"})
+	retention := &fakeRetentionServer{documents: []fakeRetentionDocument{
+		// expired invoice
+		{id: 1, documentType: invoice, created: "2015-03-01", added: now.AddDate(-11, 0, 0)},
+		// expired invoice on legal hold
+		{id: 2, documentType: invoice, tags: []int{hold}, created: "2014-03-01", added: now.AddDate(-12, 0, 0)},
+		// recent invoice
+		{id: 3, documentType: invoice, created: "2020-03-01", added: now.AddDate(-6, 0, 0)},
+		// expired application
+		{id: 4, tags: []int{application}, created: "2025-01-01", added: now.AddDate(0, -7, 0)},
+		// recent application
+		{id: 5, tags: []int{application}, created: "2026-01-01", added: now.AddDate(0, -1, 0)},
+	}}
+	server, mux := newFakeServer(t, collections)
+	retention.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	reportDir := t.TempDir()
+	opts := paperless.RetentionOptions{DryRun: true, ReportDir: reportDir, Now: func() time.Time { return now }}
+	report, err := client.ApplyRetention(ctx, policy, opts)
+	require.NoError(err)
+	require.Len(report.Rules, 2)
+	require.Len(report.Rules[0].Expired, 1)
+	require.Equal(1, report.Rules[0].Expired[0].ID)
+	require.Len(report.Rules[0].Held, 1)
+	require.Equal(2, report.Rules[0].Held[0].ID)
+	require.Len(report.Rules[1].Expired, 1)
+	require.Equal(4, report.Rules[1].Expired[0].ID)
+	require.Empty(report.Deleted)
+	require.Empty(retention.deleted)
+	require.FileExists(report.Path)
+
+	exportDir := t.TempDir()
+	policy.Export = &paperless.RetentionExport{Dir: exportDir}
+	opts.DryRun = false
+	report, err = client.ApplyRetention(ctx, policy, opts)
+	require.NoError(err)
+	require.Equal([]int{1, 4}, report.Deleted)
+	require.Equal([]int{1, 4}, retention.deleted)
+	require.Equal(filepath.Join(exportDir, "1_invoice.pdf"), report.Rules[0].Expired[0].Export)
+	exported, err := os.ReadFile(report.Rules[0].Expired[0].Export)
+	require.NoError(err)
+	require.Equal("%PDF-1", string(exported))
+
+	// a missing legal hold tag must not lift all holds
+	policy.LegalHoldTag = "legal hold"
+	_, err = client.ApplyRetention(ctx, policy, opts)
+	require.ErrorContains(err, "legal hold tag")
+}
+
+func TestApplyRetentionOverlappingRules(t *testing.T) {
+	require := require.New(t)
+	policy, err := paperless.ParseRetentionPolicy([]byte(TEST_RETENTION_POLICY))
+	require.NoError(err)
+
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	collections := newFakeCollections("tags", "correspondents", "document_types")
+	collections.add("tags", map[string]interface{}{"name": "legal-hold"})
+	application := collections.add("tags", map[string]interface{}{"name": "application"})
+	invoice := collections.add("document_types", map[string]interface{}{"name": "Invoice"})
+	retention := &fakeRetentionServer{documents: []fakeRetentionDocument{
+		// expired application, but an invoice kept for 10 years
+		{id: 1, documentType: invoice, tags: []int{application}, created: "2020-03-01", added: now.AddDate(0, -7, 0)},
+		// expired according to both rules
+		{id: 2, documentType: invoice, tags: []int{application}, created: "2015-03-01", added: now.AddDate(-11, 0, 0)},
+		// recent application, expired invoice
+		{id: 3, documentType: invoice, tags: []int{application}, created: "2015-03-01", added: now.AddDate(0, -1, 0)},
+	}}
+	server, mux := newFakeServer(t, collections)
+	retention.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	report, err := client.ApplyRetention(ctx, policy, paperless.RetentionOptions{Now: func() time.Time { return now }})
+	require.NoError(err)
+	require.Equal([]int{2}, report.Deleted)
+	require.Equal([]int{2}, retention.deleted)
+	require.Len(report.Rules[0].Expired, 1, "reported for the rule retaining longest")
+	require.Equal(2, report.Rules[0].Expired[0].ID)
+	require.Empty(report.Rules[1].Expired)
+	require.Len(report.Rules[0].Extended, 1)
+	require.Equal(3, report.Rules[0].Extended[0].ID)
+	require.Len(report.Rules[1].Extended, 1)
+	require.Equal(1, report.Rules[1].Extended[0].ID)
+}
+
+func TestParseRetentionPolicyInvalid(t *testing.T) {
+	for name, policy := range map[string]string{
+		"no rules":  `legal_hold_tag: hold`,
+		"no filter": "rules:\n  - name: all\n    max_age: 1y",
+		"no age":    "rules:\n  - name: invoices\n    document_type: Invoice",
+		"bad age":   "rules:\n  - name: invoices\n    document_type: Invoice\n    max_age: 10 years",
+		"bad basis": "rules:\n  - name: invoices\n    document_type: Invoice\n    max_age: 1y\n    basis: modified",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := paperless.ParseRetentionPolicy([]byte(policy))
+			require.Error(t, err)
+		})
+	}
+}
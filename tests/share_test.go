@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeShareLinks serves share links and the documents they point to.
+type fakeShareLinks struct {
+	mu        sync.Mutex
+	nextID    int
+	links     map[int]map[string]interface{}
+	documents map[int]string
+	trashed   map[int]string
+	deleted   []int
+}
+
+func (f *fakeShareLinks) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/share_links/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		results := make([]interface{}, 0, len(f.links))
+		for _, link := range f.links {
+			results = append(results, link)
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("POST /api/share_links/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.nextID++
+		body["id"] = f.nextID
+		body["slug"] = "slug" + strconv.Itoa(f.nextID)
+		body["created"] = time.Now().UTC().Format(time.RFC3339)
+		f.links[f.nextID] = body
+		writeFakeJSON(w, http.StatusCreated, body)
+	})
+	mux.HandleFunc("DELETE /api/share_links/{id}/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.links, id)
+		f.deleted = append(f.deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/documents/{$}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		results := make([]interface{}, 0)
+		for id, title := range f.documents {
+			results = append(results, map[string]interface{}{"id": id, "title": title})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("GET /api/trash/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		results := make([]interface{}, 0)
+		for id, title := range f.trashed {
+			results = append(results, map[string]interface{}{"id": id, "title": title})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("GET /api/documents/{id}/", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		title, ok := f.documents[id]
+		if !ok {
+			writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "title": title})
+	})
+}
+
+func TestShareLinks(t *testing.T) {
+	require := require.New(t)
+
+	fake := &fakeShareLinks{
+		links:     make(map[int]map[string]interface{}),
+		documents: map[int]string{1: "Invoice", 2: "Contract"},
+		trashed:   map[int]string{5: "Receipt"},
+	}
+	server, mux := newFakeServer(t, nil)
+	fake.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL+"/", "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	now := time.Now()
+	link, err := client.ShareDocument(ctx, 1, paperless.ShareOptions{})
+	require.NoError(err)
+	require.Equal(server.URL+"/share/slug1", link.URL)
+	require.Equal(paperless.FileVersionEnumArchive, link.FileVersion)
+	require.Nil(link.Expiration)
+
+	expiring, err := client.ShareDocument(ctx, 2, paperless.ShareOptions{
+		Expiration:  paperless.P(now.Add(time.Hour)),
+		FileVersion: paperless.FileVersionEnumOriginal,
+	})
+	require.NoError(err)
+	require.Equal(paperless.FileVersionEnumOriginal, expiring.FileVersion)
+	_, err = client.ShareDocument(ctx, 2, paperless.ShareOptions{Expiration: paperless.P(now.Add(-time.Hour))})
+	require.NoError(err)
+	// a link to a document deleted since
+	_, err = client.ShareDocument(ctx, 3, paperless.ShareOptions{})
+	require.NoError(err)
+	// a link to a trashed document, which may be restored
+	_, err = client.ShareDocument(ctx, 5, paperless.ShareOptions{})
+	require.NoError(err)
+
+	links, err := client.ListShareLinks(ctx, paperless.ShareLinkFilter{})
+	require.NoError(err)
+	require.Equal([]int{1, 2, 3, 4, 5}, shareLinkIDs(links))
+	links, err = client.ListShareLinks(ctx, paperless.ShareLinkFilter{Document: 2})
+	require.NoError(err)
+	require.Equal([]int{2, 3}, shareLinkIDs(links))
+	links, err = client.ListShareLinks(ctx, paperless.ShareLinkFilter{Expired: paperless.P(true)})
+	require.NoError(err)
+	require.Equal([]int{3}, shareLinkIDs(links))
+	links, err = client.ListShareLinks(ctx, paperless.ShareLinkFilter{Expired: paperless.P(true), Now: now.Add(2 * time.Hour)})
+	require.NoError(err)
+	require.Equal([]int{2, 3}, shareLinkIDs(links))
+
+	shared, err := client.SharedDocuments(ctx)
+	require.NoError(err)
+	require.Len(shared, 4)
+	require.Equal("Invoice", shared[0].Title)
+	require.Equal([]int{2}, shareLinkIDs(shared[1].Links), "expired links are not shared")
+	require.Empty(shared[2].Title)
+
+	report, err := client.SweepShareLinks(ctx, paperless.ShareSweepOptions{DryRun: true})
+	require.NoError(err)
+	require.Equal([]int{3}, shareLinkIDs(report.Expired))
+	require.Equal([]int{4}, shareLinkIDs(report.Orphaned))
+	require.Empty(fake.deleted)
+	require.Contains(report.String(), "dry run: 1 expired and 1 orphaned")
+
+	report, err = client.SweepShareLinks(ctx, paperless.ShareSweepOptions{})
+	require.NoError(err)
+	require.Equal([]int{3, 4}, fake.deleted)
+	links, err = client.ListShareLinks(ctx, paperless.ShareLinkFilter{})
+	require.NoError(err)
+	require.Equal([]int{1, 2, 5}, shareLinkIDs(links), "link to trashed document swept")
+
+	report, err = client.SweepShareLinks(ctx, paperless.ShareSweepOptions{Trashed: true})
+	require.NoError(err)
+	require.Equal([]int{5}, shareLinkIDs(report.Orphaned))
+	require.Equal([]int{3, 4, 5}, fake.deleted)
+}
+
+func shareLinkIDs(links []paperless.SharedLink) []int {
+	output := make([]int, 0, len(links))
+	for _, link := range links {
+		output = append(output, link.ID)
+	}
+	sort.Ints(output)
+	return output
+}
+
+func TestShareLinkPublicURL(t *testing.T) {
+	require := require.New(t)
+	client, doer := makeTestClientWithDoer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 6*TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	id := seededDocument(ctx, t, client, testdataDocuments[1])
+	link, err := client.ShareDocument(ctx, id, paperless.ShareOptions{FileVersion: paperless.FileVersionEnumOriginal})
+	require.NoError(err, "failed to share document")
+	defer client.DeleteShareLink(ctx, link.ID)
+	require.Equal(baseURL()+"/share/"+link.Slug, link.URL)
+
+	// the link works without credentials
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.URL, nil)
+	require.NoError(err)
+	resp, err := doer.Do(req)
+	require.NoError(err, "failed to fetch shared document")
+	defer resp.Body.Close()
+	require.Equal(http.StatusOK, resp.StatusCode, "invalid response code (fetch shared document)")
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(err, "failed to read shared document")
+	require.True(bytes.HasPrefix(content, []byte("%PDF")), "shared document is no pdf")
+
+	links, err := client.ListShareLinks(ctx, paperless.ShareLinkFilter{Document: id})
+	require.NoError(err, "failed to list share links")
+	require.Contains(shareLinkIDs(links), link.ID)
+	report, err := client.SweepShareLinks(ctx, paperless.ShareSweepOptions{DryRun: true})
+	require.NoError(err, "failed to sweep share links")
+	require.NotContains(shareLinkIDs(report.Expired), link.ID)
+	require.NotContains(shareLinkIDs(report.Orphaned), link.ID)
+}
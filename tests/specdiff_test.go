@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+func findSpecChange(diff *paperless.SpecDiff, location, name string) *paperless.SpecChange {
+	for _, change := range diff.Changes {
+		if change.Location == location && change.Name == name {
+			return &change
+		}
+	}
+	return nil
+}
+
+func TestDiffSpecsPatchedSpec(t *testing.T) {
+	require := require.New(t)
+
+	orig, err := os.ReadFile("../patch/api.yaml.orig")
+	require.NoError(err, "failed to read upstream spec")
+
+	// the patches of api.yaml.diff are exactly the drift between the spec
+	// and the actual server behaviour
+	diff, err := paperless.DiffSpecs(orig, paperless.EmbeddedSpec())
+	require.NoError(err, "failed to diff specs")
+
+	notes := findSpecChange(diff, "GET /api/documents/{id}/notes/", "200 application/json")
+	require.NotNil(notes, "notes listing change missing:\n%s", diff)
+	require.True(notes.Breaking)
+	require.Equal(paperless.SpecChangeChanged, notes.Kind)
+
+	metadata := findSpecChange(diff, "#/components/schemas/Metadata", "original_metadata")
+	require.NotNil(metadata, "metadata change missing:\n%s", diff)
+	require.True(metadata.Breaking)
+}
+
+func TestDetectSpecDrift(t *testing.T) {
+	require := require.New(t)
+
+	live := strings.Replace(
+		string(paperless.EmbeddedSpec()),
+		"      - edit_pdf\n      type: string\n",
+		"      - edit_pdf\n      - remove_password\n      type: string\n",
+		1,
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/schema/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.oai.openapi")
+		w.Write([]byte(live))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	diff, err := client.DetectSpecDrift(ctx, nil)
+	require.NoError(err, "failed to detect drift")
+
+	require.Len(diff.Changes, 1, "unexpected changes:\n%s", diff)
+	change := diff.Changes[0]
+	require.Equal(paperless.SpecElementEnumValue, change.Element)
+	require.Equal(paperless.SpecChangeAdded, change.Kind)
+	require.Equal("#/components/schemas/MethodEnum", change.Location)
+	require.Equal("remove_password", change.Name)
+	require.False(diff.HasBreakingChanges())
+}
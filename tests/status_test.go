@@ -3,8 +3,13 @@ package tests
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,3 +28,227 @@ func TestStatus(t *testing.T) {
 		"invalid response code (get server status)",
 	)
 }
+
+// fakeStatusSocket serves /ws/status/ and the task listing, messages sent to
+// it are pushed to all connected clients.
+type fakeStatusSocket struct {
+	mu      sync.Mutex
+	conns   []*websocket.Conn
+	status  paperless.StatusEnum
+	headers chan http.Header
+}
+
+func (f *fakeStatusSocket) register(mux *http.ServeMux) {
+	upgrader := websocket.Upgrader{}
+	mux.HandleFunc("GET /ws/status/", func(w http.ResponseWriter, r *http.Request) {
+		f.headers <- r.Header.Clone()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.mu.Unlock()
+	})
+	mux.HandleFunc("GET /api/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		writeFakeJSON(w, http.StatusOK, []interface{}{
+			map[string]interface{}{"task_id": r.URL.Query().Get("task_id"), "status": f.status},
+		})
+	})
+}
+
+func (f *fakeStatusSocket) send(t *testing.T, message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+	}
+}
+
+func newFakeStatusServer(t *testing.T) (*fakeStatusSocket, paperless.XClient) {
+	socket := &fakeStatusSocket{status: paperless.StatusEnumSTARTED, headers: make(chan http.Header, 10)}
+	server, mux := newFakeServer(t, nil)
+	socket.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(t, err, "failed to create client")
+	return socket, client
+}
+
+func TestSubscribeStatus(t *testing.T) {
+	require := require.New(t)
+	socket, client := newFakeStatusServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	messages, err := client.SubscribeStatus(ctx)
+	require.NoError(err)
+	header := <-socket.headers
+	require.Equal("Token token", header.Get("Authorization"))
+
+	socket.send(t, `not json`)
+	socket.send(t, `{"type": "status_update", "data": {"filename": "invoice.pdf", "task_id": "abc", "current_progress": 20, "max_progress": 100, "status": "WORKING", "message": "processing", "document_id": null}}`)
+	socket.send(t, `{"type": "documents_deleted", "data": {"documents": [1, 2]}}`)
+
+	message := <-messages
+	require.Equal(paperless.StatusMessageUpdate, message.Type)
+	require.Equal("abc", message.TaskID)
+	require.Equal("invoice.pdf", message.Filename)
+	require.Equal(20, message.CurrentProgress)
+	require.Equal(100, message.MaxProgress)
+	require.Equal(paperless.ConsumptionWorking, message.Status)
+	require.Nil(message.DocumentID)
+	require.False(message.Done())
+
+	message = <-messages
+	require.Equal(paperless.StatusMessageDocumentsDeleted, message.Type)
+	require.Equal([]int{1, 2}, message.Documents)
+
+	cancel()
+	for range messages {
+	}
+}
+
+// pathRecorder remembers the requests sent through it.
+type pathRecorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (p *pathRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req.Method+" "+req.URL.Path)
+	p.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (p *pathRecorder) count(request string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for _, sent := range p.requests {
+		if sent == request {
+			count++
+		}
+	}
+	return count
+}
+
+// newFakeSessionServer accepts the status websocket with a session only, like
+// the channels AuthMiddlewareStack of paperless.
+func newFakeSessionServer(t *testing.T) *httptest.Server {
+	server, mux := newFakeServer(t, nil)
+	mux.HandleFunc("GET /accounts/login/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: "csrf"})
+		w.Write([]byte("<form></form>"))
+	})
+	mux.HandleFunc("POST /accounts/login/", func(w http.ResponseWriter, r *http.Request) {
+		csrf, err := r.Cookie("csrftoken")
+		if err != nil || csrf.Value != r.FormValue("csrfmiddlewaretoken") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.FormValue("login") != "paperless" || r.FormValue("password") != "secret" {
+			w.Write([]byte("<form>invalid credentials</form>"))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sessionid", Value: "session"})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	upgrader := websocket.Upgrader{}
+	mux.HandleFunc("GET /ws/status/", func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie("sessionid")
+		if err != nil || session.Value != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "status_update", "data": {"task_id": "abc", "status": "SUCCESS"}}`))
+		conn.ReadMessage()
+	})
+	return server
+}
+
+func TestSubscribeStatusSession(t *testing.T) {
+	require := require.New(t)
+	server := newFakeSessionServer(t)
+	transport := &pathRecorder{}
+	client, err := paperless.NewXClientWithHTTPClient(server.URL, &http.Client{Transport: transport}, paperless.MakeBasicAuthRequestEditor("paperless", "secret"))
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	for range 2 {
+		messages, err := client.SubscribeStatus(ctx)
+		require.NoError(err)
+		message := <-messages
+		require.Equal("abc", message.TaskID)
+		require.True(message.Done())
+	}
+	require.Equal(2, transport.count("GET /ws/status/"), "websocket not dialed through the http client")
+	require.Equal(1, transport.count("POST /accounts/login/"), "session not kept")
+
+	client, err = paperless.NewXClientWithHTTPClient(server.URL, nil, paperless.MakeBasicAuthRequestEditor("paperless", "wrong"))
+	require.NoError(err, "failed to create client")
+	_, err = client.SubscribeStatus(ctx)
+	require.ErrorContains(err, "failed to log in as 'paperless'")
+
+	client, err = paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	_, err = client.SubscribeStatus(ctx)
+	require.ErrorContains(err, "403")
+}
+
+func TestWaitForTaskStatus(t *testing.T) {
+	require := require.New(t)
+	socket, client := newFakeStatusServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- client.WaitForTask(ctx, "abc", time.Minute)
+	}()
+	<-socket.headers
+	// the task listing is checked once after connecting
+	time.Sleep(100 * time.Millisecond)
+	socket.send(t, `{"type": "status_update", "data": {"task_id": "other", "status": "SUCCESS"}}`)
+	socket.send(t, `{"type": "status_update", "data": {"task_id": "abc", "status": "SUCCESS", "message": "finished", "document_id": 7}}`)
+	select {
+	case err := <-done:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		require.FailNow("task not reported via websocket")
+	}
+
+	go func() {
+		done <- client.WaitForTask(ctx, "def", time.Minute)
+	}()
+	<-socket.headers
+	time.Sleep(100 * time.Millisecond)
+	socket.send(t, `{"type": "status_update", "data": {"task_id": "def", "status": "FAILED", "message": "duplicate document"}}`)
+	err := <-done
+	require.ErrorContains(err, "duplicate document")
+}
+
+func TestWaitForTaskPollingFallback(t *testing.T) {
+	require := require.New(t)
+	server, mux := newFakeServer(t, nil)
+	mux.HandleFunc("GET /api/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, []interface{}{
+			map[string]interface{}{"task_id": r.URL.Query().Get("task_id"), "status": paperless.StatusEnumSUCCESS},
+		})
+	})
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	// no websocket, the task is polled every 2s
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(client.WaitForTask(ctx, "abc", 0))
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTrash serves the trash listing and applies restore and empty requests.
+type fakeTrash struct {
+	mu       sync.Mutex
+	trashed  map[int]time.Time
+	restored []int
+	emptied  []int
+}
+
+func (f *fakeTrash) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/trash/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		results := make([]interface{}, 0)
+		for id, deleted := range f.trashed {
+			results = append(results, map[string]interface{}{"id": id, "title": "doc", "deleted_at": deleted})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("POST /api/trash/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var req paperless.TrashRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Action == nil {
+			writeFakeJSON(w, http.StatusBadRequest, map[string]interface{}{"action": "required"})
+			return
+		}
+		for _, id := range req.Documents {
+			delete(f.trashed, id)
+		}
+		if *req.Action == paperless.Restore {
+			f.restored = append(f.restored, req.Documents...)
+		} else {
+			f.emptied = append(f.emptied, req.Documents...)
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"result": "OK"})
+	})
+}
+
+func TestTrash(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	trash := &fakeTrash{trashed: map[int]time.Time{
+		1: now.Add(-40 * 24 * time.Hour),
+		2: now.Add(-10 * 24 * time.Hour),
+		3: now.Add(-time.Hour),
+	}}
+	server, mux := newFakeServer(t, nil)
+	trash.register(mux)
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	trashed, err := client.ListTrash(ctx)
+	require.NoError(err)
+	require.Len(trashed, 3)
+
+	// dry runs change nothing
+	report, err := client.RestoreFromTrash(ctx, []int{3, 9}, paperless.TrashOptions{DryRun: true})
+	require.NoError(err)
+	require.True(report.DryRun)
+	require.Len(report.Documents, 1)
+	require.Equal([]int{9}, report.Missing)
+	require.Empty(trash.restored)
+
+	report, err = client.RestoreFromTrash(ctx, []int{3}, paperless.TrashOptions{})
+	require.NoError(err)
+	require.Equal(paperless.TrashRestore, report.Operation)
+	require.Equal([]int{3}, trash.restored)
+
+	sweep := paperless.TrashSweepOptions{OlderThan: 30 * 24 * time.Hour, Now: func() time.Time { return now }}
+	sweep.DryRun = true
+	report, err = client.SweepTrash(ctx, sweep)
+	require.NoError(err)
+	require.Len(report.Documents, 1)
+	require.Equal(1, report.Documents[0].ID)
+	require.Empty(trash.emptied)
+
+	sweep.DryRun = false
+	_, err = client.SweepTrash(ctx, sweep)
+	require.NoError(err)
+	require.Equal([]int{1}, trash.emptied)
+
+	// nothing past the retention period, nothing is sent
+	report, err = client.SweepTrash(ctx, sweep)
+	require.NoError(err)
+	require.Empty(report.Documents)
+	require.Equal([]int{1}, trash.emptied)
+
+	report, err = client.EmptyTrash(ctx, nil, paperless.TrashOptions{})
+	require.NoError(err)
+	require.Len(report.Documents, 1)
+	require.Equal([]int{1, 2}, trash.emptied)
+}
+
+func TestTrashUnsupported(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 5, "2.9.0")
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	_, err = client.NegotiateVersion(ctx)
+	require.NoError(err)
+
+	_, err = client.EmptyTrash(ctx, nil, paperless.TrashOptions{DryRun: true})
+	require.ErrorIs(err, paperless.ErrUnsupportedByServer)
+}
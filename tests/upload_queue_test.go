@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadQueue(t *testing.T) {
+	require := require.New(t)
+
+	consumer := newFakeConsumer()
+	server, mux := newFakeServer(t, nil)
+	consumer.register(mux)
+	healthy := atomic.Bool{}
+	mux.HandleFunc("GET /api/status/", func(w http.ResponseWriter, r *http.Request) {
+		database := "ERROR"
+		if healthy.Load() {
+			database = "OK"
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"database": map[string]interface{}{"status": database},
+			"tasks":    map[string]interface{}{"redis_status": "OK", "celery_status": "OK"},
+		})
+	})
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	files := t.TempDir()
+	// the name of a file does not clash with the metadata of the queue
+	for name, content := range map[string]string{"a.pdf": "%PDF-a", "item.json": "%PDF-b"} {
+		require.NoError(os.WriteFile(filepath.Join(files, name), []byte(content), 0o644))
+	}
+	dir := filepath.Join(t.TempDir(), "queue")
+	delivered := atomic.Int32{}
+	errs := make(chan error, 100)
+	opts := paperless.UploadQueueOptions{
+		PollInterval: 10 * time.Millisecond,
+		MaxAttempts:  2,
+		OnDelivered: func(item paperless.QueuedUpload, taskID string) {
+			delivered.Add(1)
+		},
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+	queue, err := client.OpenUploadQueue(dir, opts)
+	require.NoError(err)
+	_, err = queue.Enqueue(filepath.Join(files, "a.pdf"), &paperless.DocumentCreate{Title: paperless.P("A")})
+	require.NoError(err)
+	id, err := queue.UploadDocument(filepath.Join(files, "item.json"), "B", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), []int{3})
+	require.NoError(err)
+	payload, err := os.ReadFile(filepath.Join(dir, "pending", id, "payload"))
+	require.NoError(err)
+	require.Equal("%PDF-b", string(payload))
+
+	// paperless is down, nothing is delivered
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-errs
+		cancel()
+	}()
+	err = queue.Flush(ctx)
+	require.ErrorContains(err, "2 uploads pending")
+	metrics, err := queue.Metrics()
+	require.NoError(err)
+	require.Equal(2, metrics.Depth)
+	require.Positive(metrics.OldestAge)
+	require.Contains(metrics.LastError, "database status 'ERROR'")
+	require.Zero(consumer.uploadCount())
+
+	// the queue survives a restart and is delivered once paperless is up
+	queue, err = client.OpenUploadQueue(dir, opts)
+	require.NoError(err)
+	pending, err := queue.Pending()
+	require.NoError(err)
+	require.Len(pending, 2)
+	require.Equal("a.pdf", pending[0].Filename)
+	healthy.Store(true)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(queue.Flush(ctx))
+	require.Equal(2, consumer.uploadCount())
+	require.Equal([]string{"A"}, consumer.uploads[0]["title"])
+	require.Equal([]string{"B"}, consumer.uploads[1]["title"])
+	require.Equal([]string{"3"}, consumer.uploads[1]["tags"])
+	require.Equal([]string{"a.pdf", "item.json"}, consumer.filenames)
+	metrics, err = queue.Metrics()
+	require.NoError(err)
+	require.Equal(0, metrics.Depth)
+	require.Equal(2, metrics.Delivered)
+	require.Equal(int32(2), delivered.Load())
+
+	// rejected uploads are given up on after MaxAttempts and can be retried
+	consumer.mu.Lock()
+	consumer.rejectUploads = true
+	consumer.mu.Unlock()
+	_, err = queue.Enqueue(filepath.Join(files, "a.pdf"), nil)
+	require.NoError(err)
+	err = queue.Flush(ctx)
+	require.ErrorContains(err, "1 queued uploads failed")
+	failed, err := queue.Failed()
+	require.NoError(err)
+	require.Len(failed, 1)
+	require.Equal(2, failed[0].Attempts)
+	require.Contains(failed[0].LastError, "503")
+
+	consumer.mu.Lock()
+	consumer.rejectUploads = false
+	consumer.mu.Unlock()
+	require.NoError(queue.Retry())
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- queue.Run(runCtx)
+	}()
+	require.Eventually(func() bool {
+		return delivered.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+	require.ErrorIs(<-done, context.Canceled)
+	require.Equal(3, consumer.uploadCount())
+	metrics, err = queue.Metrics()
+	require.NoError(err)
+	require.Equal(0, metrics.Depth)
+	require.Equal(0, metrics.Failed)
+}
+
+func newQueueConsumer(t *testing.T) (*fakeConsumer, paperless.XClient) {
+	consumer := newFakeConsumer()
+	server, mux := newFakeServer(t, nil)
+	consumer.register(mux)
+	mux.HandleFunc("GET /api/status/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+			"database": map[string]interface{}{"status": "OK"},
+			"tasks":    map[string]interface{}{"redis_status": "OK", "celery_status": "OK"},
+		})
+	})
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(t, err, "failed to create client")
+	return consumer, client
+}
+
+func TestUploadQueueConcurrentDelivery(t *testing.T) {
+	require := require.New(t)
+	consumer, client := newQueueConsumer(t)
+
+	file := filepath.Join(t.TempDir(), "a.pdf")
+	require.NoError(os.WriteFile(file, []byte("%PDF-a"), 0o644))
+	dir := filepath.Join(t.TempDir(), "queue")
+	opts := paperless.UploadQueueOptions{PollInterval: 10 * time.Millisecond}
+	queues := make([]*paperless.UploadQueue, 4)
+	for idx := range queues {
+		queue, err := client.OpenUploadQueue(dir, opts)
+		require.NoError(err)
+		queues[idx] = queue
+	}
+	for range 20 {
+		_, err := queues[0].Enqueue(file, nil)
+		require.NoError(err)
+	}
+
+	// several processes flushing the same queue deliver every upload once
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errs := make(chan error, len(queues))
+	for _, queue := range queues {
+		go func() {
+			errs <- queue.Flush(ctx)
+		}()
+	}
+	for range queues {
+		require.NoError(<-errs)
+	}
+	require.Equal(20, consumer.uploadCount())
+}
+
+func TestUploadQueueCrashedDelivery(t *testing.T) {
+	require := require.New(t)
+	consumer, client := newQueueConsumer(t)
+
+	files := t.TempDir()
+	for name, content := range map[string]string{"a.pdf": "%PDF-a", "b.pdf": "%PDF-b"} {
+		require.NoError(os.WriteFile(filepath.Join(files, name), []byte(content), 0o644))
+	}
+	dir := filepath.Join(t.TempDir(), "queue")
+	opts := paperless.UploadQueueOptions{PollInterval: 10 * time.Millisecond, ClaimTimeout: time.Minute}
+	queue, err := client.OpenUploadQueue(dir, opts)
+	require.NoError(err)
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		_, err = queue.Enqueue(filepath.Join(files, name), nil)
+		require.NoError(err)
+	}
+	pending, err := queue.Pending()
+	require.NoError(err)
+
+	// a process crashed after sending a.pdf, which paperless consumed, and
+	// another one is still sending b.pdf
+	for idx, claimed := range []time.Time{time.Now().Add(-time.Hour), time.Now()} {
+		item := pending[idx]
+		item.Claimed = claimed
+		item.Uploading = true
+		raw, err := json.Marshal(item)
+		require.NoError(err)
+		require.NoError(os.WriteFile(filepath.Join(dir, "pending", item.ID, "item.json"), raw, 0o644))
+		require.NoError(os.Rename(filepath.Join(dir, "pending", item.ID), filepath.Join(dir, "inflight", item.ID)))
+	}
+	consumer.mu.Lock()
+	consumer.checksums[md5Hex("%PDF-a")] = 101
+	consumer.mu.Unlock()
+	// enqueues of other processes are left alone
+	require.NoError(os.Mkdir(filepath.Join(dir, "incoming", "enqueue"), 0o755))
+
+	queue, err = client.OpenUploadQueue(dir, opts)
+	require.NoError(err)
+	require.DirExists(filepath.Join(dir, "incoming", "enqueue"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remaining := make(chan error)
+	go func() {
+		remaining <- queue.Flush(ctx)
+	}()
+	// the stale claim is delivered again and skipped as duplicate, the
+	// live one is waited for
+	require.Eventually(func() bool {
+		metrics, err := queue.Metrics()
+		return err == nil && metrics.Delivered == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.ErrorContains(<-remaining, "1 uploads pending")
+	require.Zero(consumer.uploadCount(), "consumed upload sent again")
+	entries, err := os.ReadDir(filepath.Join(dir, "inflight"))
+	require.NoError(err)
+	require.Len(entries, 1, "live claim taken over")
+}
+
+func TestUploadQueueDelivered(t *testing.T) {
+	require := require.New(t)
+	client := makeTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*TEST_DOCUMENT_UPLOAD_TIMEOUT)
+	defer cancel()
+
+	require.NoError(client.Healthy(ctx), "test stack not healthy")
+	taskIDs := make(chan string, 1)
+	queue, err := client.OpenUploadQueue(filepath.Join(t.TempDir(), "queue"), paperless.UploadQueueOptions{
+		PollInterval: 100 * time.Millisecond,
+		OnDelivered: func(item paperless.QueuedUpload, taskID string) {
+			taskIDs <- taskID
+		},
+	})
+	require.NoError(err)
+	_, err = queue.Enqueue(writeTestPDF(t, "queued.pdf", "queued "+randStr(16)), &paperless.DocumentCreate{Title: paperless.P("queued upload")})
+	require.NoError(err)
+
+	require.NoError(queue.Flush(ctx), "failed to flush queue")
+	metrics, err := queue.Metrics()
+	require.NoError(err)
+	require.Equal(0, metrics.Depth)
+	require.Equal(1, metrics.Delivered)
+	require.NoError(waitForTask(ctx, client, <-taskIDs), "queued upload not consumed")
+}
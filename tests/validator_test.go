@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeNotesServer answers notes listings either the way paperless does
+// (plain array) or the way the upstream spec claims (paginated object).
+func newFakeNotesServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/documents/1/notes/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": 1, "note": "checked", "created": "2025-11-14T01:17:02.029944+01:00", "user": {"id": 3, "username": "test"}}]`))
+	})
+	mux.HandleFunc("GET /api/documents/2/notes/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"count": 1, "next": null, "previous": null, "results": []}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestValidatingTransport(t *testing.T) {
+	require := require.New(t)
+	server := newFakeNotesServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	violations := make([]paperless.SpecViolation, 0)
+	transport, err := paperless.NewValidatingTransport(nil, paperless.ValidationOptions{
+		OnViolation: func(v paperless.SpecViolation) {
+			violations = append(violations, v)
+		},
+	})
+	require.NoError(err, "failed to create validating transport")
+	client, err := paperless.NewXClientWithHTTPClient(server.URL, &http.Client{Transport: transport})
+	require.NoError(err, "failed to create client")
+
+	notesResp, err := client.DocumentsNotesListWithResponse(ctx, 1, nil)
+	require.NoError(err, "failed to list notes")
+	require.Len(notesResp.JSON200, 1)
+	require.Empty(violations, "conforming response reported")
+
+	_, err = client.DocumentsNotesListWithResponse(ctx, 2, nil)
+	require.Error(err, "paginated notes decoded into array")
+	require.NotEmpty(violations, "drifted response not reported")
+	require.Equal(paperless.SpecViolationResponse, violations[0].Direction)
+	require.Equal("documents_notes_list", violations[0].OperationID)
+	require.Equal(http.StatusOK, violations[0].StatusCode)
+}
+
+func TestValidatingTransportStrict(t *testing.T) {
+	require := require.New(t)
+	server := newFakeNotesServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	transport, err := paperless.NewValidatingTransport(nil, paperless.ValidationOptions{
+		Strict: true,
+	})
+	require.NoError(err, "failed to create validating transport")
+	client, err := paperless.NewXClientWithHTTPClient(server.URL, &http.Client{Transport: transport})
+	require.NoError(err, "failed to create client")
+
+	_, err = client.DocumentsNotesListWithResponse(ctx, 2, nil)
+	require.ErrorIs(err, paperless.ErrSpecViolation)
+
+	var violationErr *paperless.SpecViolationError
+	require.ErrorAs(err, &violationErr)
+	require.NotEmpty(violationErr.Violations)
+}
+
+func TestValidatingTransportStreams(t *testing.T) {
+	require := require.New(t)
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/documents/1/download/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("rest"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	server2 := newFakeNotesServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	violations := make([]paperless.SpecViolation, 0)
+	transport, err := paperless.NewValidatingTransport(nil, paperless.ValidationOptions{
+		MaxBodySize: 16,
+		OnViolation: func(v paperless.SpecViolation) {
+			violations = append(violations, v)
+		},
+	})
+	require.NoError(err, "failed to create validating transport")
+
+	// downloads are handed on before the server finished sending them
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/documents/1/download/", nil)
+	require.NoError(err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(err)
+	start := make([]byte, 5)
+	_, err = io.ReadFull(resp.Body, start)
+	require.NoError(err)
+	require.Equal("%PDF-", string(start))
+	close(release)
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	require.Equal("rest", string(rest))
+	resp.Body.Close()
+
+	// json bodies above the limit are passed on unvalidated and complete
+	client, err := paperless.NewXClientWithHTTPClient(server2.URL, &http.Client{Transport: transport})
+	require.NoError(err, "failed to create client")
+	_, err = client.DocumentsNotesListWithResponse(ctx, 2, nil)
+	require.ErrorContains(err, "cannot unmarshal object")
+	require.Empty(violations)
+}
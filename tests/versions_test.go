@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeVersionedServer reports the given versions and echoes the requested
+// API version of status requests.
+func newFakeVersionedServer(t *testing.T, apiVersion int, version string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Api-Version", fmt.Sprintf("%d", apiVersion))
+		w.Header().Set("X-Version", version)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("GET /api/status/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Api-Version", fmt.Sprintf("%d", apiVersion))
+		w.Header().Set("X-Version", version)
+		w.Header().Set("X-Requested-Accept", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNegotiateVersionOlderServer(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 7, "2.8.3")
+
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	// unknown server, everything assumed to be supported
+	require.True(client.SupportsMethod(paperless.MethodEnumEditPdf))
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	version, err := client.NegotiateVersion(ctx)
+	require.NoError(err, "failed to negotiate version")
+	require.Equal(7, version)
+
+	statusResp, err := client.StatusRetrieveWithResponse(ctx)
+	require.NoError(err, "failed to get status")
+	require.Equal("application/json; version=7", statusResp.HTTPResponse.Header.Get("X-Requested-Accept"))
+
+	info := client.ServerInfo()
+	require.Equal(7, info.APIVersion)
+	require.Equal("2.8.3", info.Version)
+	require.Equal(7, info.NegotiatedAPIVersion)
+
+	require.True(client.SupportsMethod(paperless.MethodEnumRotate))
+	require.True(client.SupportsMethod(paperless.MethodEnumDeletePages))
+	require.False(client.SupportsMethod(paperless.MethodEnumEditPdf))
+	require.ErrorIs(client.RequireMethod(paperless.MethodEnumEditPdf), paperless.ErrUnsupportedByServer)
+	require.False(client.Supports(paperless.FeatureTrash))
+	require.False(client.Supports(paperless.FeatureCreatedAsDate))
+	require.ErrorIs(client.RequireFeature(paperless.FeatureTrash), paperless.ErrUnsupportedByServer)
+}
+
+func TestNegotiateVersionNewerServer(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 12, "3.1.0-dev")
+
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	version, err := client.NegotiateVersion(ctx)
+	require.NoError(err, "failed to negotiate version")
+	require.Equal(9, version, "client must not request versions it does not know")
+
+	require.True(client.SupportsMethod(paperless.MethodEnumEditPdf))
+	require.True(client.Supports(paperless.FeatureCreatedAsDate))
+	require.NoError(client.RequireFeature(paperless.FeatureTrash))
+}
+
+func TestNegotiateVersionExplicitVersion(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 5, "2.1.0")
+
+	client, err := paperless.NewXClientWithHTTPClient(server.URL, nil,
+		paperless.MakeAPIVersionRequestEditor(9),
+		paperless.MakeTokenAuthRequestEditor("token"),
+	)
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	statusResp, err := client.StatusRetrieveWithResponse(ctx)
+	require.NoError(err, "failed to get status")
+	require.Equal("application/json; version=9", statusResp.HTTPResponse.Header.Get("X-Requested-Accept"))
+
+	// the negotiated version replaces the explicit one
+	version, err := client.NegotiateVersion(ctx)
+	require.NoError(err, "failed to negotiate version")
+	require.Equal(5, version)
+	statusResp, err = client.StatusRetrieveWithResponse(ctx)
+	require.NoError(err, "failed to get status")
+	require.Equal("application/json; version=5", statusResp.HTTPResponse.Header.Get("X-Requested-Accept"))
+
+	require.True(client.Supports(paperless.FeatureWorkflows))
+	require.False(client.Supports(paperless.FeatureTrash))
+}
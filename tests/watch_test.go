@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocuments serves the document and trash listings with the filters
+// used by Watch.
+type fakeDocuments struct {
+	mu        sync.Mutex
+	documents map[int]map[string]interface{}
+	trash     map[int]map[string]interface{}
+}
+
+func (f *fakeDocuments) add(id int, added time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.documents[id] = map[string]interface{}{"id": id, "title": "doc " + strconv.Itoa(id), "added": added, "modified": added, "notes": []interface{}{}}
+}
+
+func (f *fakeDocuments) addNote(id int, created time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	document := f.documents[id]
+	document["notes"] = append(document["notes"].([]interface{}), map[string]interface{}{"id": 1, "note": "checked", "created": created})
+	document["modified"] = created
+}
+
+func (f *fakeDocuments) moveToTrash(id int, deleted time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	document := f.documents[id]
+	delete(f.documents, id)
+	document["deleted_at"] = deleted
+	f.trash[id] = document
+}
+
+func (f *fakeDocuments) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		query := r.URL.Query()
+		results := make([]map[string]interface{}, 0)
+		for _, document := range f.documents {
+			if !fakeAfter(document["added"].(time.Time), query.Get("added__gt")) ||
+				!fakeAfter(document["modified"].(time.Time), query.Get("modified__gt")) {
+				continue
+			}
+			results = append(results, document)
+		}
+		field := query.Get("ordering")
+		descending := len(field) > 0 && field[0] == '-'
+		if descending {
+			field = field[1:]
+		}
+		if field == "" {
+			field = "added"
+		}
+		sort.Slice(results, func(i, j int) bool {
+			before := results[i][field].(time.Time).Before(results[j][field].(time.Time))
+			return before != descending
+		})
+		if size, err := strconv.Atoi(query.Get("page_size")); err == nil && size < len(results) {
+			results = results[:size]
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("GET /api/trash/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		results := make([]map[string]interface{}, 0)
+		for _, document := range f.trash {
+			results = append(results, document)
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": len(results), "results": results})
+	})
+	mux.HandleFunc("GET /api/documents/{id}/history/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"count": 1, "results": []interface{}{
+			map[string]interface{}{"id": 1, "action": "update", "timestamp": time.Now(), "changes": map[string]interface{}{}},
+		}})
+	})
+}
+
+func fakeAfter(value time.Time, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	since, err := time.Parse(time.RFC3339Nano, filter)
+	return err == nil && value.After(since)
+}
+
+func receiveChanges(t *testing.T, events <-chan paperless.ChangeEvent, count int) []paperless.ChangeEvent {
+	output := make([]paperless.ChangeEvent, 0, count)
+	timeout := time.After(5 * time.Second)
+	for len(output) < count {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "change feed closed")
+			output = append(output, event)
+		case <-timeout:
+			require.FailNow(t, "timeout waiting for changes", "received %d of %d", len(output), count)
+		}
+	}
+	return output
+}
+
+func TestWatch(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	documents := &fakeDocuments{documents: make(map[int]map[string]interface{}), trash: make(map[int]map[string]interface{})}
+	documents.add(1, start)
+	documents.add(3, start.Add(-time.Hour))
+	server, mux := newFakeServer(t, nil)
+	documents.register(mux)
+
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	store := paperless.FileCheckpointStore{Path: filepath.Join(t.TempDir(), "checkpoint.json")}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.Watch(ctx, paperless.WatchOptions{Interval: 10 * time.Millisecond, Store: store, IncludeHistory: true})
+	require.NoError(err)
+
+	// existing documents are not reported
+	checkpoint, err := store.Load(ctx)
+	require.NoError(err)
+	require.NotNil(checkpoint)
+	require.True(start.Equal(checkpoint.Added))
+
+	documents.add(2, start.Add(time.Minute))
+	documents.addNote(1, start.Add(2*time.Minute))
+	documents.moveToTrash(3, start.Add(3*time.Minute))
+
+	changes := receiveChanges(t, events, 4)
+	types := make([]paperless.ChangeType, 0)
+	for _, change := range changes {
+		types = append(types, change.Type)
+		change.Ack()
+	}
+	require.ElementsMatch([]paperless.ChangeType{paperless.DocumentAdded, paperless.DocumentModified, paperless.NoteAdded, paperless.DocumentTrashed}, types)
+	require.Equal(paperless.DocumentAdded, changes[0].Type)
+	require.Equal(2, changes[0].DocumentID)
+	require.Equal(paperless.DocumentTrashed, changes[3].Type)
+	require.Equal(3, changes[3].DocumentID)
+	for _, change := range changes {
+		switch change.Type {
+		case paperless.NoteAdded:
+			require.Equal("checked", *change.Note.Note)
+		case paperless.DocumentModified:
+			require.Len(change.History, 1)
+		}
+	}
+	require.Eventually(func() bool {
+		checkpoint, err := store.Load(ctx)
+		return err == nil && checkpoint.Trashed.Equal(start.Add(3*time.Minute))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// unacknowledged changes are delivered again after a restart
+	documents.add(4, start.Add(4*time.Minute))
+	change := receiveChanges(t, events, 1)[0]
+	require.Equal(4, change.DocumentID)
+	cancel()
+	for range events {
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	events, err = client.Watch(ctx, paperless.WatchOptions{Interval: 10 * time.Millisecond, Store: store})
+	require.NoError(err)
+	change = receiveChanges(t, events, 1)[0]
+	require.Equal(paperless.DocumentAdded, change.Type)
+	require.Equal(4, change.DocumentID)
+	change.Ack()
+}
+
+func TestWatchTypes(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	documents := &fakeDocuments{documents: make(map[int]map[string]interface{}), trash: make(map[int]map[string]interface{})}
+	documents.add(1, start)
+	server, mux := newFakeServer(t, nil)
+	documents.register(mux)
+
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	since := start.Add(-time.Second)
+	events, err := client.Watch(ctx, paperless.WatchOptions{
+		Interval: 10 * time.Millisecond,
+		Since:    &since,
+		Types:    []paperless.ChangeType{paperless.NoteAdded},
+	})
+	require.NoError(err)
+
+	documents.addNote(1, start.Add(time.Minute))
+	change := receiveChanges(t, events, 1)[0]
+	require.Equal(paperless.NoteAdded, change.Type)
+	require.Equal(1, change.DocumentID)
+	change.Ack()
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+const TEST_WEBHOOK_SECRET string = "s3cret"
+
+func postWebhook(t *testing.T, target, contentType string, body []byte, secret string) int {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	if secret != "" {
+		req.Header.Set(paperless.DefaultWebhookSecretHeader, secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebhookReceiver(t *testing.T) {
+	require := require.New(t)
+
+	received := make(chan paperless.ReceivedEvent, 10)
+	receiver := paperless.NewWebhookReceiver(paperless.WebhookReceiverOptions{Secret: TEST_WEBHOOK_SECRET}).
+		On(paperless.EventDocumentAdded, func(ctx context.Context, event paperless.ReceivedEvent) error {
+			received <- event
+			return nil
+		}).
+		On(paperless.EventDocumentUpdated, func(ctx context.Context, event paperless.ReceivedEvent) error {
+			return errors.New("erp unavailable")
+		})
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// params as json, as configured by paperless.WebhookEvent
+	status := postWebhook(t, server.URL, "application/json",
+		[]byte(`{"event": "document_added", "title": "Invoice", "doc_url": "https://paperless.localdomain/documents/42/details", "correspondent": "ACME", "custom": "x"}`),
+		TEST_WEBHOOK_SECRET)
+	require.Equal(http.StatusNoContent, status)
+	event := <-received
+	require.Equal(paperless.EventDocumentAdded, event.Type)
+	require.Equal(42, event.DocumentID)
+	require.Equal("Invoice", event.Title)
+	require.Equal("ACME", event.Correspondent)
+	require.Equal("x", event.Params["custom"])
+
+	// params as form data
+	form := url.Values{"event": {"document_added"}, "doc_id": {"7"}, "title": {"Receipt"}}
+	status = postWebhook(t, server.URL, "application/x-www-form-urlencoded", []byte(form.Encode()), TEST_WEBHOOK_SECRET)
+	require.Equal(http.StatusNoContent, status)
+	event = <-received
+	require.Equal(7, event.DocumentID)
+	require.Equal("Receipt", event.Title)
+
+	// include_document sends multipart
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("event", "document_added")
+	writer.WriteField("doc_url", "http://paperless/documents/3/details")
+	part, err := writer.CreateFormFile("file", "scan.pdf")
+	require.NoError(err)
+	part.Write([]byte("%PDF-1.4"))
+	writer.Close()
+	status = postWebhook(t, server.URL, writer.FormDataContentType(), body.Bytes(), TEST_WEBHOOK_SECRET)
+	require.Equal(http.StatusNoContent, status)
+	event = <-received
+	require.Equal(3, event.DocumentID)
+	require.Equal("scan.pdf", event.AttachmentName)
+	require.Equal([]byte("%PDF-1.4"), event.Attachment)
+
+	require.Equal(http.StatusUnauthorized, postWebhook(t, server.URL, "application/json", []byte(`{"event": "document_added"}`), "wrong"))
+	require.Equal(http.StatusUnauthorized, postWebhook(t, server.URL, "application/json", []byte(`{"event": "document_added"}`), ""))
+	require.Equal(http.StatusBadRequest, postWebhook(t, server.URL, "application/json", []byte(`not json`), TEST_WEBHOOK_SECRET))
+	require.Equal(http.StatusInternalServerError, postWebhook(t, server.URL, "application/json", []byte(`{"event": "document_updated"}`), TEST_WEBHOOK_SECRET))
+	require.Empty(received)
+}
+
+func TestWebhookReceiverEnrich(t *testing.T) {
+	require := require.New(t)
+	paperlessServer, mux := newFakeServer(t, nil)
+	mux.HandleFunc("GET /api/documents/{id}/", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "42" {
+			writeFakeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"id": 42, "title": "Invoice", "content": "ACME"})
+	})
+	client, err := paperless.NewXClientWithToken(paperlessServer.URL, "token")
+	require.NoError(err)
+
+	var enriched *paperless.Document
+	receiver := paperless.NewWebhookReceiver(paperless.WebhookReceiverOptions{Client: &client}).
+		OnAny(func(ctx context.Context, event paperless.ReceivedEvent) error {
+			enriched = event.Document
+			return nil
+		})
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	require.Equal(http.StatusNoContent, postWebhook(t, server.URL, "application/json", []byte(`{"event": "document_updated", "doc_id": "42"}`), ""))
+	require.NotNil(enriched)
+	require.Equal("ACME", *enriched.Content)
+	require.Equal(http.StatusBadGateway, postWebhook(t, server.URL, "application/json", []byte(`{"event": "document_updated", "doc_id": "43"}`), ""))
+}
+
+func TestWebhookEventOption(t *testing.T) {
+	require := require.New(t)
+	request, err := paperless.NewWorkflow("notify").
+		OnDocumentAdded().
+		SendWebhook("https://erp.localdomain/paperless", paperless.WebhookEvent(paperless.EventDocumentAdded, TEST_WEBHOOK_SECRET)).
+		Build()
+	require.NoError(err)
+	webhook := request.Actions[0].Webhook
+	require.True(*webhook.UseParams)
+	params := webhook.Params.(map[string]string)
+	require.Equal("document_added", params["event"])
+	require.True(strings.HasPrefix(params["doc_url"], "{"))
+	require.Equal(map[string]string{paperless.DefaultWebhookSecretHeader: TEST_WEBHOOK_SECRET}, webhook.Headers)
+}
+
+func TestWebhookEventOptionKeepsHeaders(t *testing.T) {
+	require := require.New(t)
+	request, err := paperless.NewWorkflow("notify").
+		OnDocumentAdded().
+		SendWebhook("https://erp.localdomain/paperless",
+			paperless.WebhookHeaders(map[string]string{"Authorization": "Bearer erp"}),
+			paperless.WebhookEvent(paperless.EventDocumentAdded, TEST_WEBHOOK_SECRET)).
+		Build()
+	require.NoError(err)
+	require.Equal(map[string]string{
+		"Authorization":                      "Bearer erp",
+		paperless.DefaultWebhookSecretHeader: TEST_WEBHOOK_SECRET,
+	}, request.Actions[0].Webhook.Headers)
+}
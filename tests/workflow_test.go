@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/burner-account/paperless-ngx-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowBuilderValidation(t *testing.T) {
+	require := require.New(t)
+
+	_, err := paperless.NewWorkflow("empty").Build()
+	require.ErrorIs(err, paperless.ErrInvalidWorkflow)
+	require.ErrorContains(err, "at least one trigger required")
+	require.ErrorContains(err, "at least one action required")
+
+	_, err = paperless.NewWorkflow("no filter").
+		OnConsumption(paperless.HasTags(1)).
+		AssignTags(2).
+		Build()
+	require.ErrorContains(err, "consumption triggers require a filename, path or mail rule filter")
+	require.ErrorContains(err, "no content, tags or metadata before consumption")
+
+	_, err = paperless.NewWorkflow("bad actions").
+		OnDocumentAdded(paperless.Content(paperless.MatchAny, "", true)).
+		SendWebhook("https://hooks.localdomain", paperless.WebhookParams(map[string]string{"a": "b"}), paperless.WebhookBody("x")).
+		Email(nil, "subject", "body").
+		Build()
+	require.ErrorContains(err, "requires a match")
+	require.ErrorContains(err, "action 1: webhook sends either params or a body")
+	require.ErrorContains(err, "action 2: email requires recipients, subject and body")
+
+	request, err := paperless.NewWorkflow("Invoices").
+		OnConsumption(paperless.Filename("*inv*"), paperless.Sources(paperless.SourceMailFetch)).
+		AssignTags(1).
+		SetCustomField(7, "EUR12.00").
+		AssignTags(2).
+		RemoveTags(3).
+		Build()
+	require.NoError(err)
+	require.Len(request.Triggers, 1)
+	require.Equal([]paperless.SourcesEnum{paperless.SourceMailFetch}, request.Triggers[0].Sources)
+	require.Len(request.Actions, 2, "consecutive assignments share one action")
+	require.Equal([]int{1, 2}, request.Actions[0].AssignTags)
+	require.Equal([]int{7}, request.Actions[0].AssignCustomFields)
+	require.Equal(map[string]interface{}{"7": "EUR12.00"}, request.Actions[0].AssignCustomFieldsValues)
+	require.Equal(paperless.ActionRemoval, *request.Actions[1].Type)
+}
+
+func newWorkflowFixture(t *testing.T, failWorkflows bool) (*fakeCollections, paperless.XClient) {
+	collections := newFakeCollections("workflows", "workflow_triggers", "workflow_actions")
+	server, _ := newFakeServer(t, collections)
+	if failWorkflows {
+		collections.fail(http.MethodPost, "workflows", http.StatusBadRequest)
+	}
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(t, err, "failed to create client")
+	return collections, client
+}
+
+func TestSaveWorkflow(t *testing.T) {
+	require := require.New(t)
+	collections, client := newWorkflowFixture(t, false)
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	workflow, err := client.SaveWorkflow(ctx, paperless.NewWorkflow("Invoices").
+		OnConsumption(paperless.Filename("*inv*")).
+		AssignTags(1).
+		SendWebhook("https://hooks.localdomain/paperless", paperless.WebhookParams(map[string]string{"title": "{doc_title}"})))
+	require.NoError(err, "failed to save workflow")
+	require.NotNil(workflow.Id)
+	require.Len(workflow.Triggers, 1)
+	require.Len(workflow.Actions, 2)
+	require.NotNil(workflow.Triggers[0].Id, "triggers are created upfront")
+	require.Len(collections.objects["workflow_triggers"], 1)
+	require.Len(collections.objects["workflow_actions"], 2)
+
+	// saving again replaces triggers and actions of the same workflow
+	updated, err := client.SaveWorkflow(ctx, paperless.NewWorkflow("Invoices").
+		OnDocumentAdded(paperless.HasTags(1)).
+		AssignCorrespondent(5))
+	require.NoError(err, "failed to update workflow")
+	require.Equal(*workflow.Id, *updated.Id)
+	require.Len(collections.objects["workflows"], 1)
+	require.Len(collections.objects["workflow_triggers"], 1)
+	require.Len(collections.objects["workflow_actions"], 1)
+	require.Nil(collections.get("workflow_triggers", *workflow.Triggers[0].Id), "replaced trigger deleted")
+}
+
+func TestSaveWorkflowRollback(t *testing.T) {
+	require := require.New(t)
+	collections, client := newWorkflowFixture(t, true)
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	_, err := client.SaveWorkflow(ctx, paperless.NewWorkflow("Invoices").
+		OnConsumption(paperless.Filename("*inv*")).
+		OnDocumentUpdated().
+		AssignTags(1).
+		Email([]string{"a@localdomain", "b@localdomain"}, "subject", "body"))
+	require.ErrorContains(err, "invalid response code (create workflow): 400")
+	require.Empty(collections.objects["workflow_triggers"], "created triggers rolled back")
+	require.Empty(collections.objects["workflow_actions"], "created actions rolled back")
+}
+
+func TestSaveWorkflowCleanupErrors(t *testing.T) {
+	require := require.New(t)
+	collections, client := newWorkflowFixture(t, true)
+	collections.fail(http.MethodDelete, "workflow_actions", http.StatusForbidden)
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+
+	_, err := client.SaveWorkflow(ctx, paperless.NewWorkflow("Invoices").
+		OnConsumption(paperless.Filename("*inv*")).
+		AssignTags(1))
+	require.ErrorContains(err, "invalid response code (create workflow): 400")
+	require.ErrorContains(err, "failed to roll back action")
+	require.ErrorContains(err, "invalid response code (delete workflow action): 403")
+	require.Empty(collections.objects["workflow_triggers"], "created triggers rolled back")
+
+	// replaced actions which cannot be deleted are reported with the workflow
+	collections, client = newWorkflowFixture(t, false)
+	_, err = client.SaveWorkflow(ctx, paperless.NewWorkflow("Invoices").
+		OnConsumption(paperless.Filename("*inv*")).
+		AssignTags(1))
+	require.NoError(err)
+	collections.fail(http.MethodDelete, "workflow_actions", http.StatusForbidden)
+	updated, err := client.SaveWorkflow(ctx, paperless.NewWorkflow("Invoices").
+		OnDocumentAdded(paperless.HasTags(1)).
+		AssignCorrespondent(5))
+	require.ErrorContains(err, "failed to delete replaced action")
+	require.NotNil(updated, "saved workflow returned")
+	require.Len(collections.objects["workflow_triggers"], 1, "replaced trigger deleted")
+}
+
+func TestSaveWorkflowUnsupported(t *testing.T) {
+	require := require.New(t)
+	server := newFakeVersionedServer(t, 5, "2.11.0")
+	client, err := paperless.NewXClientWithToken(server.URL, "token")
+	require.NoError(err, "failed to create client")
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_REQUEST_TIMEOUT)
+	defer cancel()
+	_, err = client.NegotiateVersion(ctx)
+	require.NoError(err)
+
+	_, err = client.SaveWorkflow(ctx, paperless.NewWorkflow("hook").
+		OnDocumentAdded().
+		SendWebhook("https://hooks.localdomain"))
+	require.ErrorIs(err, paperless.ErrUnsupportedByServer)
+}
@@ -0,0 +1,190 @@
+package paperless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type TrashOperation string
+
+const (
+	TrashRestore TrashOperation = "restore"
+	TrashEmpty   TrashOperation = "empty"
+)
+
+type TrashOptions struct {
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
+}
+
+type TrashReportItem struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashReport summarizes a trash operation.
+type TrashReport struct {
+	Operation TrashOperation    `json:"operation"`
+	DryRun    bool              `json:"dry_run"`
+	Documents []TrashReportItem `json:"documents"`
+	// Missing holds requested IDs not found in the trash.
+	Missing []int `json:"missing,omitempty"`
+}
+
+func (r *TrashReport) String() string {
+	verb := map[TrashOperation]string{TrashRestore: "restored", TrashEmpty: "deleted permanently"}[r.Operation]
+	if r.DryRun {
+		verb = "would be " + verb
+	}
+	lines := []string{fmt.Sprintf("%d documents %s", len(r.Documents), verb)}
+	for _, item := range r.Documents {
+		lines = append(lines, fmt.Sprintf("  %d %q (trashed %s)", item.ID, item.Title, item.DeletedAt.Format(time.RFC3339)))
+	}
+	if len(r.Missing) > 0 {
+		lines = append(lines, fmt.Sprintf("not in trash: %v", r.Missing))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ListTrashPage returns one page of trashed documents. The generated client
+// has no response type for the trash listing.
+func (x XClient) ListTrashPage(ctx context.Context, page, pageSize int) (*PaginatedDocumentList, error) {
+	if err := x.RequireFeature(FeatureTrash); err != nil {
+		return nil, err
+	}
+	resp, err := x.TrashListWithResponse(ctx, &TrashListParams{Page: P(page), PageSize: P(pageSize)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, apiError("list trash", resp.StatusCode(), resp.Body)
+	}
+	list := &PaginatedDocumentList{}
+	err = json.Unmarshal(resp.Body, list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode trash: %w", err)
+	}
+	return list, nil
+}
+
+// ListTrash returns all trashed documents.
+func (x XClient) ListTrash(ctx context.Context) ([]Document, error) {
+	output := make([]Document, 0)
+	for page := 1; ; page++ {
+		list, err := x.ListTrashPage(ctx, page, listPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range list.Results {
+			if document.Id != nil {
+				output = append(output, document)
+			}
+		}
+		if list.Next == nil {
+			return output, nil
+		}
+	}
+}
+
+// RestoreFromTrash restores the given trashed documents.
+func (x XClient) RestoreFromTrash(ctx context.Context, ids []int, opts TrashOptions) (*TrashReport, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no documents to restore")
+	}
+	return x.trashOperation(ctx, TrashRestore, func(document Document) bool {
+		return containsID(ids, *document.Id)
+	}, ids, opts)
+}
+
+// EmptyTrash deletes the given trashed documents permanently, all of them if
+// ids is empty.
+func (x XClient) EmptyTrash(ctx context.Context, ids []int, opts TrashOptions) (*TrashReport, error) {
+	return x.trashOperation(ctx, TrashEmpty, func(document Document) bool {
+		return len(ids) == 0 || containsID(ids, *document.Id)
+	}, ids, opts)
+}
+
+type TrashSweepOptions struct {
+	TrashOptions
+	// OlderThan is the retention period, documents trashed longer ago are
+	// deleted permanently.
+	OlderThan time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// SweepTrash deletes documents permanently which have been in the trash for
+// longer than the retention period.
+func (x XClient) SweepTrash(ctx context.Context, opts TrashSweepOptions) (*TrashReport, error) {
+	if opts.OlderThan <= 0 {
+		return nil, fmt.Errorf("invalid retention period %s", opts.OlderThan)
+	}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	cutoff := now().Add(-opts.OlderThan)
+	return x.trashOperation(ctx, TrashEmpty, func(document Document) bool {
+		return document.DeletedAt != nil && document.DeletedAt.Before(cutoff)
+	}, nil, opts.TrashOptions)
+}
+
+// trashOperation applies the operation to all trashed documents selected. An
+// empty selection is not sent, paperless would empty the whole trash.
+func (x XClient) trashOperation(ctx context.Context, operation TrashOperation, selected func(Document) bool, requested []int, opts TrashOptions) (*TrashReport, error) {
+	trashed, err := x.ListTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &TrashReport{Operation: operation, DryRun: opts.DryRun, Documents: make([]TrashReportItem, 0)}
+	ids := make([]int, 0)
+	for _, document := range trashed {
+		if !selected(document) {
+			continue
+		}
+		item := TrashReportItem{ID: *document.Id}
+		if document.Title != nil {
+			item.Title = *document.Title
+		}
+		if document.DeletedAt != nil {
+			item.DeletedAt = *document.DeletedAt
+		}
+		report.Documents = append(report.Documents, item)
+		ids = append(ids, *document.Id)
+	}
+	for _, id := range requested {
+		if !containsID(ids, id) {
+			report.Missing = append(report.Missing, id)
+		}
+	}
+	sort.Slice(report.Documents, func(i, j int) bool {
+		return report.Documents[i].ID < report.Documents[j].ID
+	})
+	if opts.DryRun || len(ids) == 0 {
+		return report, nil
+	}
+
+	action := TrashActionEnum(operation)
+	resp, err := x.TrashCreateWithResponse(ctx, TrashRequest{Action: &action, Documents: ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s trash: %w", operation, err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, apiError(string(operation)+" trash", resp.StatusCode(), resp.Body)
+	}
+	return report, nil
+}
+
+func containsID(ids []int, id int) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
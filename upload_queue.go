@@ -0,0 +1,553 @@
+package paperless
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueuePollInterval time.Duration = 30 * time.Second
+	defaultQueueMaxBackoff   time.Duration = 10 * time.Minute
+	defaultQueueMaxAttempts  int           = 10
+	defaultQueueClaimTimeout time.Duration = time.Hour
+
+	queuePending  string = "pending"
+	queueInflight string = "inflight"
+	queueFailed   string = "failed"
+	queueIncoming string = "incoming"
+	queueItemFile string = "item.json"
+	// the copy of the file, its name is kept in the metadata only
+	queuePayloadFile string = "payload"
+)
+
+type UploadQueueOptions struct {
+	// PollInterval between health checks while paperless is unavailable,
+	// defaults to 30s. It is also the base of the retry backoff.
+	PollInterval time.Duration
+	// MaxBackoff caps the delay between attempts of an upload, defaults to
+	// 10m.
+	MaxBackoff time.Duration
+	// MaxAttempts moves uploads rejected that often to the failed
+	// directory, defaults to 10.
+	MaxAttempts int
+	// ClaimTimeout is the time after which uploads claimed by a process
+	// which crashed are delivered again and interrupted enqueues are
+	// removed, defaults to 1h. It has to exceed the longest upload.
+	ClaimTimeout time.Duration
+	// OnDelivered is called once paperless accepted an upload.
+	OnDelivered func(item QueuedUpload, taskID string)
+	// OnError is called for failed health checks and attempts.
+	OnError func(err error)
+}
+
+// QueuedUpload is an upload waiting for delivery.
+type QueuedUpload struct {
+	ID          string          `json:"id"`
+	Filename    string          `json:"filename"`
+	Metadata    *DocumentCreate `json:"metadata,omitempty"`
+	Enqueued    time.Time       `json:"enqueued"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+	// Claimed is when a process took the upload for delivery.
+	Claimed time.Time `json:"claimed,omitempty"`
+	// Uploading is set before the file is sent, an upload found with it
+	// set may have been delivered by a process which crashed.
+	Uploading bool `json:"uploading,omitempty"`
+}
+
+type QueueMetrics struct {
+	// Depth is the number of pending uploads, including those being
+	// delivered.
+	Depth int `json:"depth"`
+	// OldestAge is the age of the oldest pending upload.
+	OldestAge time.Duration `json:"oldest_age"`
+	// Failed is the number of uploads given up on.
+	Failed int `json:"failed"`
+	// Delivered counts the uploads delivered since the queue was opened.
+	Delivered    int       `json:"delivered"`
+	LastDelivery time.Time `json:"last_delivery,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// UploadQueue is a durable queue of uploads backed by a directory. Every
+// upload is a directory with a copy of the file and its metadata, moved into
+// place atomically, so neither a crash nor an outage of paperless loses it.
+// Uploads are claimed by moving them to the inflight directory, so several
+// processes (or Run and Flush) can share a queue without delivering an upload
+// twice. Uploads which may have been sent before, by an earlier attempt or a
+// process which crashed, skip files paperless has already.
+type UploadQueue struct {
+	client XClient
+	dir    string
+	opts   UploadQueueOptions
+
+	mu           sync.Mutex
+	wake         chan struct{}
+	delivered    int
+	lastDelivery time.Time
+	lastError    string
+}
+
+// OpenUploadQueue opens or creates the queue in dir.
+func (x XClient) OpenUploadQueue(dir string, opts UploadQueueOptions) (*UploadQueue, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultQueuePollInterval
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultQueueMaxBackoff
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultQueueMaxAttempts
+	}
+	if opts.ClaimTimeout <= 0 {
+		opts.ClaimTimeout = defaultQueueClaimTimeout
+	}
+	for _, sub := range []string{queuePending, queueInflight, queueFailed, queueIncoming} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create upload queue: %w", err)
+		}
+	}
+	// leftovers of enqueues interrupted by a crash, younger ones may still
+	// be written by another process
+	incoming, _ := os.ReadDir(filepath.Join(dir, queueIncoming))
+	for _, entry := range incoming {
+		info, err := entry.Info()
+		if err == nil && time.Since(info.ModTime()) > opts.ClaimTimeout {
+			os.RemoveAll(filepath.Join(dir, queueIncoming, entry.Name()))
+		}
+	}
+	return &UploadQueue{client: x, dir: dir, opts: opts, wake: make(chan struct{}, 1)}, nil
+}
+
+// Enqueue copies the file into the queue.
+func (q *UploadQueue) Enqueue(path string, metadata *DocumentCreate) (*QueuedUpload, error) {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	now := time.Now()
+	item := &QueuedUpload{
+		// sorts in order of enqueueing
+		ID:          fmt.Sprintf("%019d-%s", now.UnixNano(), hex.EncodeToString(suffix)),
+		Filename:    filepath.Base(path),
+		Metadata:    metadata,
+		Enqueued:    now,
+		NextAttempt: now,
+	}
+	tmp := filepath.Join(q.dir, queueIncoming, item.ID)
+	err := os.Mkdir(tmp, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue '%s': %w", path, err)
+	}
+	err = copyFile(path, filepath.Join(tmp, queuePayloadFile))
+	if err == nil {
+		err = writeQueueItem(tmp, item)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(q.dir, queuePending, item.ID))
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, fmt.Errorf("failed to enqueue '%s': %w", path, err)
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return item, nil
+}
+
+// UploadDocument enqueues an upload with the arguments of
+// XClient.UploadDocument and returns the ID of the queued upload.
+func (q *UploadQueue) UploadDocument(filepath, title string, created time.Time, tagIDs []int) (string, error) {
+	tags := make([]string, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		tags = append(tags, strconv.Itoa(tagID))
+	}
+	item, err := q.Enqueue(filepath, &DocumentCreate{Title: P(title), Created: P(created), Tags: tags})
+	if err != nil {
+		return "", err
+	}
+	return item.ID, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeQueueItem replaces the metadata of an item atomically.
+func writeQueueItem(dir string, item *QueuedUpload) error {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, queueItemFile+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(raw)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, queueItemFile))
+}
+
+func (q *UploadQueue) list(sub string) ([]QueuedUpload, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload queue: %w", err)
+	}
+	output := make([]QueuedUpload, 0, len(entries))
+	for _, entry := range entries {
+		raw, err := os.ReadFile(filepath.Join(q.dir, sub, entry.Name(), queueItemFile))
+		if errors.Is(err, os.ErrNotExist) {
+			// moved by another process meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queued upload '%s': %w", entry.Name(), err)
+		}
+		var item QueuedUpload
+		err = json.Unmarshal(raw, &item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode queued upload '%s': %w", entry.Name(), err)
+		}
+		output = append(output, item)
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].ID < output[j].ID
+	})
+	return output, nil
+}
+
+// Pending returns the uploads waiting for delivery, oldest first.
+func (q *UploadQueue) Pending() ([]QueuedUpload, error) {
+	return q.list(queuePending)
+}
+
+// Failed returns the uploads given up on, see UploadQueueOptions.MaxAttempts.
+func (q *UploadQueue) Failed() ([]QueuedUpload, error) {
+	return q.list(queueFailed)
+}
+
+// Retry moves failed uploads back into the queue.
+func (q *UploadQueue) Retry() error {
+	failed, err := q.Failed()
+	if err != nil {
+		return err
+	}
+	for _, item := range failed {
+		item.Attempts = 0
+		item.NextAttempt = time.Now()
+		err = writeQueueItem(filepath.Join(q.dir, queueFailed, item.ID), &item)
+		if err == nil {
+			err = os.Rename(filepath.Join(q.dir, queueFailed, item.ID), filepath.Join(q.dir, queuePending, item.ID))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to retry queued upload '%s': %w", item.ID, err)
+		}
+	}
+	return nil
+}
+
+func (q *UploadQueue) Metrics() (QueueMetrics, error) {
+	pending, err := q.Pending()
+	if err != nil {
+		return QueueMetrics{}, err
+	}
+	inflight, err := q.list(queueInflight)
+	if err != nil {
+		return QueueMetrics{}, err
+	}
+	pending = append(pending, inflight...)
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ID < pending[j].ID
+	})
+	failed, err := os.ReadDir(filepath.Join(q.dir, queueFailed))
+	if err != nil {
+		return QueueMetrics{}, fmt.Errorf("failed to read upload queue: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	metrics := QueueMetrics{
+		Depth:        len(pending),
+		Failed:       len(failed),
+		Delivered:    q.delivered,
+		LastDelivery: q.lastDelivery,
+		LastError:    q.lastError,
+	}
+	if len(pending) > 0 {
+		metrics.OldestAge = time.Since(pending[0].Enqueued)
+	}
+	return metrics, nil
+}
+
+// Healthy reports whether paperless accepts uploads: database, redis and
+// celery are up.
+func (x XClient) Healthy(ctx context.Context) error {
+	resp, err := x.StatusRetrieveWithResponse(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch status: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return apiError("fetch status", resp.StatusCode(), resp.Body)
+	}
+	for _, check := range []struct{ name, status, detail string }{
+		{"database", resp.JSON200.Database.Status, resp.JSON200.Database.Error},
+		{"redis", resp.JSON200.Tasks.RedisStatus, resp.JSON200.Tasks.RedisError},
+		{"celery", resp.JSON200.Tasks.CeleryStatus, ""},
+	} {
+		if check.status != "OK" {
+			return fmt.Errorf("paperless unhealthy: %s status '%s' %s", check.name, check.status, check.detail)
+		}
+	}
+	return nil
+}
+
+// Run delivers uploads until the context is done.
+func (q *UploadQueue) Run(ctx context.Context) error {
+	for {
+		_, err := q.deliver(ctx, false)
+		wait := q.opts.PollInterval
+		if err == nil {
+			if next, ok := q.nextAttempt(); ok {
+				wait = min(max(time.Until(next), 0), q.opts.PollInterval)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Flush delivers all pending uploads regardless of their backoff, waiting
+// for paperless to become healthy. It returns once the queue is empty, the
+// context is done or uploads were given up on.
+func (q *UploadQueue) Flush(ctx context.Context) error {
+	for {
+		remaining, err := q.deliver(ctx, true)
+		if err == nil && remaining == 0 {
+			failed, err := q.Failed()
+			if err != nil {
+				return err
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("%d queued uploads failed, last error: %s", len(failed), failed[len(failed)-1].LastError)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to flush upload queue, %d uploads pending: %w", remaining, ctx.Err())
+		case <-time.After(q.opts.PollInterval):
+		}
+	}
+}
+
+func (q *UploadQueue) nextAttempt() (time.Time, bool) {
+	pending, err := q.Pending()
+	if err != nil || len(pending) == 0 {
+		return time.Time{}, false
+	}
+	next := pending[0].NextAttempt
+	for _, item := range pending[1:] {
+		if item.NextAttempt.Before(next) {
+			next = item.NextAttempt
+		}
+	}
+	return next, true
+}
+
+// deliver attempts all due uploads once paperless is healthy and returns the
+// number of uploads left. It stops at the first failed attempt.
+func (q *UploadQueue) deliver(ctx context.Context, ignoreBackoff bool) (int, error) {
+	if err := q.releaseStaleClaims(); err != nil {
+		return 0, q.failed(err)
+	}
+	pending, err := q.Pending()
+	if err != nil {
+		return 0, q.failed(err)
+	}
+	if len(pending) == 0 {
+		return q.depth()
+	}
+	if err := q.client.Healthy(ctx); err != nil {
+		if ctx.Err() != nil {
+			return len(pending), ctx.Err()
+		}
+		return len(pending), q.failed(err)
+	}
+	for idx, item := range pending {
+		if !ignoreBackoff && time.Now().Before(item.NextAttempt) {
+			continue
+		}
+		err := q.attempt(ctx, item)
+		if err != nil {
+			return len(pending) - idx, q.failed(err)
+		}
+	}
+	return q.depth()
+}
+
+// depth counts the pending uploads and those being delivered.
+func (q *UploadQueue) depth() (int, error) {
+	output := 0
+	for _, sub := range []string{queuePending, queueInflight} {
+		entries, err := os.ReadDir(filepath.Join(q.dir, sub))
+		if err != nil {
+			return 0, q.failed(fmt.Errorf("failed to read upload queue: %w", err))
+		}
+		output += len(entries)
+	}
+	return output, nil
+}
+
+// releaseStaleClaims moves uploads claimed longer than ClaimTimeout ago back
+// into the queue, their process crashed.
+func (q *UploadQueue) releaseStaleClaims() error {
+	inflight, err := q.list(queueInflight)
+	if err != nil {
+		return err
+	}
+	for _, item := range inflight {
+		if time.Since(item.Claimed) <= q.opts.ClaimTimeout {
+			continue
+		}
+		err := os.Rename(filepath.Join(q.dir, queueInflight, item.ID), filepath.Join(q.dir, queuePending, item.ID))
+		// another process may have released it first
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to release queued upload '%s': %w", item.ID, err)
+		}
+	}
+	return nil
+}
+
+// claim moves the upload to the inflight directory, false means another
+// process or goroutine took it.
+func (q *UploadQueue) claim(item *QueuedUpload) (bool, error) {
+	dir := filepath.Join(q.dir, queueInflight, item.ID)
+	err := os.Rename(filepath.Join(q.dir, queuePending, item.ID), dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim queued upload '%s': %w", item.ID, err)
+	}
+	// reread, the listing may be outdated
+	raw, err := os.ReadFile(filepath.Join(dir, queueItemFile))
+	if err == nil {
+		err = json.Unmarshal(raw, item)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read queued upload '%s': %w", item.ID, err)
+	}
+	return true, nil
+}
+
+func (q *UploadQueue) attempt(ctx context.Context, item QueuedUpload) error {
+	claimed, err := q.claim(&item)
+	if err != nil || !claimed {
+		return err
+	}
+	dir := filepath.Join(q.dir, queueInflight, item.ID)
+	// an upload sent before may have been delivered without being recorded
+	skipDuplicates := item.Uploading || item.Attempts > 0
+	item.Claimed = time.Now()
+	item.Uploading = true
+	if err := writeQueueItem(dir, &item); err != nil {
+		return q.release(item, fmt.Errorf("failed to record attempt of queued upload '%s': %w", item.ID, err))
+	}
+	result, err := q.client.UploadDocumentWithOptions(ctx, filepath.Join(dir, queuePayloadFile), UploadOptions{
+		Metadata:       item.Metadata,
+		SkipDuplicates: skipDuplicates,
+		Filename:       item.Filename,
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// the marker stays, the upload may have been sent
+			return q.release(item, err)
+		}
+		item.Uploading = false
+		item.Attempts++
+		item.LastError = err.Error()
+		item.NextAttempt = time.Now().Add(min(q.opts.PollInterval<<min(item.Attempts-1, 16), q.opts.MaxBackoff))
+		return q.release(item, fmt.Errorf("failed to deliver queued upload '%s': %w", item.Filename, err))
+	}
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove delivered upload '%s': %w", item.ID, err)
+	}
+	q.mu.Lock()
+	q.delivered++
+	q.lastDelivery = time.Now()
+	q.mu.Unlock()
+	if q.opts.OnDelivered != nil {
+		q.opts.OnDelivered(item, result.TaskID)
+	}
+	return nil
+}
+
+// release records the failed attempt and moves the upload back into the
+// queue, or to the failed directory after MaxAttempts.
+func (q *UploadQueue) release(item QueuedUpload, cause error) error {
+	dir := filepath.Join(q.dir, queueInflight, item.ID)
+	item.Claimed = time.Time{}
+	target := filepath.Join(q.dir, queuePending, item.ID)
+	if item.Attempts >= q.opts.MaxAttempts {
+		target = filepath.Join(q.dir, queueFailed, item.ID)
+	}
+	err := writeQueueItem(dir, &item)
+	if err == nil {
+		err = os.Rename(dir, target)
+	}
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("failed to record attempt of queued upload '%s': %w", item.ID, err))
+	}
+	return cause
+}
+
+func (q *UploadQueue) failed(err error) error {
+	q.mu.Lock()
+	q.lastError = err.Error()
+	q.mu.Unlock()
+	if q.opts.OnError != nil {
+		q.opts.OnError(err)
+	}
+	return err
+}
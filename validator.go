@@ -0,0 +1,337 @@
+package paperless
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+//go:embed api.yaml
+var embeddedSpec []byte
+
+var (
+	specOnce   sync.Once
+	specDoc    *openapi3.T
+	specRouter routers.Router
+	specErr    error
+)
+
+// EmbeddedSpec returns the (patched) OpenAPI spec the client was generated from.
+func EmbeddedSpec() []byte {
+	return bytes.Clone(embeddedSpec)
+}
+
+func loadEmbeddedSpec() (*openapi3.T, routers.Router, error) {
+	specOnce.Do(func() {
+		loader := openapi3.NewLoader()
+		specDoc, specErr = loader.LoadFromData(embeddedSpec)
+		if specErr != nil {
+			specErr = fmt.Errorf("failed to load embedded spec: %w", specErr)
+			return
+		}
+		specRouter, specErr = legacy.NewRouter(
+			specDoc,
+			// upstream ships defaults and examples violating their own schemas
+			openapi3.DisableExamplesValidation(),
+			openapi3.DisableSchemaDefaultsValidation(),
+		)
+		if specErr != nil {
+			specErr = fmt.Errorf("failed to create router for embedded spec: %w", specErr)
+		}
+	})
+	return specDoc, specRouter, specErr
+}
+
+var ErrSpecViolation = errors.New("exchange violates api spec")
+
+type SpecViolationDirection string
+
+const (
+	SpecViolationRequest  SpecViolationDirection = "request"
+	SpecViolationResponse SpecViolationDirection = "response"
+)
+
+// SpecViolation describes a single mismatch between an exchange and api.yaml.
+type SpecViolation struct {
+	Direction   SpecViolationDirection `json:"direction"`
+	Method      string                 `json:"method"`
+	Path        string                 `json:"path"`
+	OperationID string                 `json:"operation_id,omitempty"`
+	StatusCode  int                    `json:"status_code,omitempty"`
+	// Field is the JSON pointer of the offending value, if known.
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (v SpecViolation) String() string {
+	field := ""
+	if v.Field != "" {
+		field = fmt.Sprintf(" at '%s'", v.Field)
+	}
+	status := ""
+	if v.StatusCode != 0 {
+		status = fmt.Sprintf(" (%d)", v.StatusCode)
+	}
+	return fmt.Sprintf("%s %s %s%s%s: %s", v.Direction, v.Method, v.Path, status, field, v.Reason)
+}
+
+// SpecViolationError is returned by a strict ValidatingTransport.
+type SpecViolationError struct {
+	Violations []SpecViolation
+}
+
+func (e *SpecViolationError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for idx, violation := range e.Violations {
+		lines[idx] = violation.String()
+	}
+	return fmt.Sprintf("%s: %s", ErrSpecViolation, strings.Join(lines, "; "))
+}
+
+func (e *SpecViolationError) Is(target error) bool {
+	return target == ErrSpecViolation
+}
+
+type ValidationOptions struct {
+	// Strict makes the transport fail exchanges with violations instead of
+	// only reporting them.
+	Strict bool
+	// OnViolation is called for every violation found.
+	OnViolation func(SpecViolation)
+	// SkipRequests disables validation of outgoing requests.
+	SkipRequests bool
+	// SkipResponses disables validation of incoming responses.
+	SkipResponses bool
+	// ReportUnknownRoutes reports requests to paths missing from the spec.
+	ReportUnknownRoutes bool
+	// MaxBodySize limits the json bodies buffered for validation, defaults
+	// to 1 MiB. Larger and non-json bodies are streamed and only their
+	// status and headers are validated.
+	MaxBodySize int64
+}
+
+const defaultMaxValidatedBodySize int64 = 1 << 20
+
+// ValidatingTransport is a http.RoundTripper which checks every exchange
+// against the embedded api.yaml, so spec drift of new paperless releases
+// shows up before decoding fails or data is silently dropped.
+type ValidatingTransport struct {
+	next   http.RoundTripper
+	router routers.Router
+	opts   ValidationOptions
+}
+
+// NewValidatingTransport wraps next (http.DefaultTransport if nil).
+func NewValidatingTransport(next http.RoundTripper, opts ValidationOptions) (*ValidatingTransport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultMaxValidatedBodySize
+	}
+	_, router, err := loadEmbeddedSpec()
+	if err != nil {
+		return nil, err
+	}
+	return &ValidatingTransport{
+		next:   next,
+		router: router,
+		opts:   opts,
+	}, nil
+}
+
+func (v *ValidatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		if v.opts.ReportUnknownRoutes {
+			violations := []SpecViolation{{
+				Direction: SpecViolationRequest,
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				Reason:    "no matching operation in spec",
+			}}
+			if err := v.report(violations); err != nil {
+				return nil, err
+			}
+		}
+		return v.next.RoundTrip(req)
+	}
+
+	filterOpts := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    filterOpts,
+	}
+
+	if !v.opts.SkipRequests {
+		requestOpts := *filterOpts
+		input.Options = &requestOpts
+		var reqBody []byte
+		buffered := false
+		if req.Body != nil && req.Body != http.NoBody {
+			reqBody, buffered, err = v.buffer(req.Header, req.ContentLength, &req.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %w", err)
+			}
+			requestOpts.ExcludeRequestBody = !buffered
+		}
+		err = openapi3filter.ValidateRequest(req.Context(), input)
+		if buffered {
+			// the validator consumes the body
+			req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+		input.Options = filterOpts
+		if err != nil {
+			if err := v.report(newSpecViolations(SpecViolationRequest, req, route, 0, err)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resp, err := v.next.RoundTrip(req)
+	if err != nil || v.opts.SkipResponses {
+		return resp, err
+	}
+
+	respBody, buffered, err := v.buffer(resp.Header, resp.ContentLength, &resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	responseOpts := *filterOpts
+	// file downloads, large listings and the like, only check status codes
+	// and headers
+	responseOpts.ExcludeResponseBody = !buffered
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Options:                &responseOpts,
+	}
+	if buffered {
+		responseInput.SetBodyBytes(respBody)
+	}
+	err = openapi3filter.ValidateResponse(req.Context(), responseInput)
+	if err != nil {
+		if err := v.report(newSpecViolations(SpecViolationResponse, req, route, resp.StatusCode, err)); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// buffer reads json bodies of at most MaxBodySize for validation and
+// replaces body with a reader of the same content. Other bodies are left
+// untouched, bodies turning out larger than announced are stitched back
+// together.
+func (v *ValidatingTransport) buffer(header http.Header, length int64, body *io.ReadCloser) ([]byte, bool, error) {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "application/json" || length > v.opts.MaxBodySize {
+		return nil, false, nil
+	}
+	original := *body
+	content, err := io.ReadAll(io.LimitReader(original, v.opts.MaxBodySize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(content)) > v.opts.MaxBodySize {
+		*body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(content), original), original}
+		return nil, false, nil
+	}
+	original.Close()
+	*body = io.NopCloser(bytes.NewReader(content))
+	return content, true, nil
+}
+
+func (v *ValidatingTransport) report(violations []SpecViolation) error {
+	if v.opts.OnViolation != nil {
+		for _, violation := range violations {
+			v.opts.OnViolation(violation)
+		}
+	}
+	if v.opts.Strict && len(violations) > 0 {
+		return &SpecViolationError{Violations: violations}
+	}
+	return nil
+}
+
+func newSpecViolations(direction SpecViolationDirection, req *http.Request, route *routers.Route, status int, err error) []SpecViolation {
+	base := SpecViolation{
+		Direction:  direction,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		StatusCode: status,
+	}
+	if route != nil && route.Operation != nil {
+		base.OperationID = route.Operation.OperationID
+	}
+	output := make([]SpecViolation, 0)
+	for _, leaf := range flattenValidationError(err) {
+		violation := base
+		var schemaErr *openapi3.SchemaError
+		if errors.As(leaf, &schemaErr) {
+			if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+				violation.Field = "/" + strings.Join(pointer, "/")
+			}
+			violation.Reason = schemaErr.Reason
+		} else {
+			violation.Reason = leaf.Error()
+		}
+		output = append(output, violation)
+	}
+	return output
+}
+
+// flattenValidationError unpacks the nested multi errors of kin-openapi into
+// one error per offending value.
+func flattenValidationError(err error) []error {
+	var multi openapi3.MultiError
+	switch typed := err.(type) {
+	case openapi3.MultiError:
+		multi = typed
+	case *openapi3filter.RequestError:
+		if inner, ok := typed.Err.(openapi3.MultiError); ok {
+			multi = inner
+		} else if typed.Err != nil {
+			if _, ok := typed.Err.(*openapi3.SchemaError); ok {
+				return []error{typed.Err}
+			}
+		}
+	case *openapi3filter.ResponseError:
+		if inner, ok := typed.Err.(openapi3.MultiError); ok {
+			multi = inner
+		} else if typed.Err != nil {
+			if _, ok := typed.Err.(*openapi3.SchemaError); ok {
+				return []error{typed.Err}
+			}
+		}
+	}
+	if multi == nil {
+		return []error{err}
+	}
+	output := make([]error, 0, len(multi))
+	for _, inner := range multi {
+		output = append(output, flattenValidationError(inner)...)
+	}
+	return output
+}
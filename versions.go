@@ -0,0 +1,276 @@
+package paperless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// lowest API version the client accepts, helpers check capabilities
+	// before using features of newer versions
+	minAPIVersion int = 1
+	// API version the client is generated for
+	maxAPIVersion int = defaultAPIVersion
+)
+
+const (
+	headerAPIVersion string = "X-Api-Version"
+	headerVersion    string = "X-Version"
+)
+
+var (
+	ErrUnsupportedByServer = errors.New("not supported by server")
+	ErrNoCommonAPIVersion  = errors.New("no api version supported by both client and server")
+)
+
+type Feature string
+
+const (
+	FeatureTrash                  Feature = "trash"
+	FeatureWorkflows              Feature = "workflows"
+	FeatureWorkflowEmailAction    Feature = "workflow_email_action"
+	FeatureWorkflowWebhookAction  Feature = "workflow_webhook_action"
+	FeatureDocumentEmail          Feature = "document_email"
+	FeatureCustomFieldBulkEdit    Feature = "custom_field_bulk_edit"
+	FeaturePDFEditing             Feature = "pdf_editing"
+	FeatureCreatedAsDate          Feature = "created_as_date"
+	FeatureCustomFieldQueryFilter Feature = "custom_field_query"
+)
+
+// minimum paperless-ngx releases (X-Version) of bulk edit methods, methods
+// missing here have been around since the first ngx releases
+var methodSince = map[MethodEnum]string{
+	MethodEnumSetPermissions:     "1.14.0",
+	MethodEnumRotate:             "2.7.0",
+	MethodEnumMerge:              "2.7.0",
+	MethodEnumSplit:              "2.7.0",
+	MethodEnumDeletePages:        "2.8.0",
+	MethodEnumModifyCustomFields: "2.15.0",
+	MethodEnumEditPdf:            "2.17.0",
+}
+
+// minimum paperless-ngx releases (X-Version) of features
+var featureSince = map[Feature]string{
+	FeatureWorkflows:              "2.0.0",
+	FeatureTrash:                  "2.10.0",
+	FeatureDocumentEmail:          "2.14.0",
+	FeatureWorkflowEmailAction:    "2.14.0",
+	FeatureWorkflowWebhookAction:  "2.14.0",
+	FeatureCustomFieldQueryFilter: "2.13.0",
+	FeatureCustomFieldBulkEdit:    "2.15.0",
+	FeaturePDFEditing:             "2.7.0",
+}
+
+// minimum API versions (X-Api-Version) of features
+var featureSinceAPI = map[Feature]int{
+	FeatureCreatedAsDate: 9,
+}
+
+// ServerInfo holds what the client learned about the server from response
+// headers. Zero values mean 'unknown'.
+type ServerInfo struct {
+	// APIVersion is the highest API version the server supports.
+	APIVersion int
+	// Version is the paperless-ngx release of the server.
+	Version string
+	// NegotiatedAPIVersion is the API version requested by the client.
+	NegotiatedAPIVersion int
+}
+
+type serverInfoStore struct {
+	mu         sync.RWMutex
+	info       ServerInfo
+	negotiated bool
+}
+
+func newServerInfoStore() *serverInfoStore {
+	return &serverInfoStore{
+		info: ServerInfo{NegotiatedAPIVersion: defaultAPIVersion},
+	}
+}
+
+func (s *serverInfoStore) get() ServerInfo {
+	if s == nil {
+		return ServerInfo{NegotiatedAPIVersion: defaultAPIVersion}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.info
+}
+
+func (s *serverInfoStore) observe(header http.Header) {
+	apiVersion, _ := strconv.Atoi(header.Get(headerAPIVersion))
+	version := header.Get(headerVersion)
+	if apiVersion == 0 && version == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if apiVersion != 0 {
+		s.info.APIVersion = apiVersion
+	}
+	if version != "" {
+		s.info.Version = version
+	}
+}
+
+func (s *serverInfoStore) setNegotiated(version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info.NegotiatedAPIVersion = version
+	s.negotiated = true
+}
+
+// requestEditor requests the negotiated API version.
+func (s *serverInfoStore) requestEditor() RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Accept", fmt.Sprintf("application/json; version=%d", s.get().NegotiatedAPIVersion))
+		return nil
+	}
+}
+
+// negotiatedEditor requests the negotiated API version once NegotiateVersion
+// succeeded, overriding a version set by earlier editors such as
+// MakeAPIVersionRequestEditor.
+func (s *serverInfoStore) negotiatedEditor() RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		s.mu.RLock()
+		negotiated, version := s.negotiated, s.info.NegotiatedAPIVersion
+		s.mu.RUnlock()
+		if negotiated {
+			req.Header.Set("Accept", fmt.Sprintf("application/json; version=%d", version))
+		}
+		return nil
+	}
+}
+
+// versionTrackingDoer records the version headers of every response.
+type versionTrackingDoer struct {
+	next  HttpRequestDoer
+	store *serverInfoStore
+}
+
+func (d versionTrackingDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.next.Do(req)
+	if err == nil {
+		d.store.observe(resp.Header)
+	}
+	return resp, err
+}
+
+// ServerInfo returns the versions seen in responses so far.
+func (x XClient) ServerInfo() ServerInfo {
+	return x.server.get()
+}
+
+// NegotiateVersion asks the server for the API versions it supports and picks
+// the highest one supported by client and server for all further requests.
+func (x XClient) NegotiateVersion(ctx context.Context) (int, error) {
+	req, err := x.newRawRequest(ctx, http.MethodGet, "/api/", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create version request: %w", err)
+	}
+	// without an explicit version paperless answers with its defaults
+	req.Header.Set("Accept", "application/json")
+	resp, err := x.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to negotiate version: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("invalid response code (negotiate version): %d", resp.StatusCode)
+	}
+
+	serverMax := x.server.get().APIVersion
+	if serverMax == 0 {
+		return 0, fmt.Errorf("server did not send '%s' header", headerAPIVersion)
+	}
+	version := min(serverMax, maxAPIVersion)
+	if version < minAPIVersion {
+		return 0, fmt.Errorf("%w: client %d-%d, server up to %d", ErrNoCommonAPIVersion, minAPIVersion, maxAPIVersion, serverMax)
+	}
+	x.server.setNegotiated(version)
+	return version, nil
+}
+
+// SupportsMethod tells whether the server knows the bulk edit method. If the
+// server version is unknown yet, support is assumed.
+func (x XClient) SupportsMethod(method MethodEnum) bool {
+	since, ok := methodSince[method]
+	if !ok {
+		return true
+	}
+	return versionAtLeast(x.server.get().Version, since)
+}
+
+// Supports tells whether the server provides the feature. If the server
+// version is unknown yet, support is assumed.
+func (x XClient) Supports(feature Feature) bool {
+	info := x.server.get()
+	if since, ok := featureSinceAPI[feature]; ok && info.APIVersion != 0 {
+		if min(info.APIVersion, info.NegotiatedAPIVersion) < since {
+			return false
+		}
+	}
+	since, ok := featureSince[feature]
+	if !ok {
+		return true
+	}
+	return versionAtLeast(info.Version, since)
+}
+
+// RequireMethod returns ErrUnsupportedByServer if the server does not know the
+// bulk edit method.
+func (x XClient) RequireMethod(method MethodEnum) error {
+	if x.SupportsMethod(method) {
+		return nil
+	}
+	return fmt.Errorf("bulk edit method '%s' requires paperless-ngx %s, server runs %s: %w",
+		method, methodSince[method], x.server.get().Version, ErrUnsupportedByServer)
+}
+
+// RequireFeature returns ErrUnsupportedByServer if the server does not provide
+// the feature.
+func (x XClient) RequireFeature(feature Feature) error {
+	if x.Supports(feature) {
+		return nil
+	}
+	info := x.server.get()
+	return fmt.Errorf("feature '%s' not available on paperless-ngx %s (api version %d): %w",
+		feature, info.Version, info.APIVersion, ErrUnsupportedByServer)
+}
+
+// versionAtLeast compares dotted release versions, unknown versions pass.
+func versionAtLeast(version, since string) bool {
+	if version == "" {
+		return true
+	}
+	have := parseReleaseVersion(version)
+	want := parseReleaseVersion(since)
+	for idx := range want {
+		if have[idx] != want[idx] {
+			return have[idx] > want[idx]
+		}
+	}
+	return true
+}
+
+func parseReleaseVersion(version string) [3]int {
+	var output [3]int
+	version = strings.TrimPrefix(version, "v")
+	// drop suffixes like '-dev' or '+build'
+	if idx := strings.IndexAny(version, "-+ "); idx >= 0 {
+		version = version[:idx]
+	}
+	for idx, part := range strings.SplitN(version, ".", 3) {
+		output[idx], _ = strconv.Atoi(part)
+	}
+	return output
+}
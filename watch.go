@@ -0,0 +1,386 @@
+package paperless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type ChangeType string
+
+const (
+	DocumentAdded    ChangeType = "document_added"
+	DocumentModified ChangeType = "document_modified"
+	DocumentTrashed  ChangeType = "document_trashed"
+	NoteAdded        ChangeType = "note_added"
+)
+
+const defaultWatchInterval time.Duration = 30 * time.Second
+
+// WatchCheckpoint is the position of a change feed: the newest added,
+// modified and trashed timestamps delivered so far.
+type WatchCheckpoint struct {
+	Added    time.Time `json:"added"`
+	Modified time.Time `json:"modified"`
+	Trashed  time.Time `json:"trashed"`
+}
+
+// CheckpointStore persists the checkpoint of a change feed across restarts.
+type CheckpointStore interface {
+	// Load returns nil if nothing has been saved yet.
+	Load(ctx context.Context) (*WatchCheckpoint, error)
+	Save(ctx context.Context, checkpoint WatchCheckpoint) error
+}
+
+// MemoryCheckpointStore keeps the checkpoint for the lifetime of the process.
+type MemoryCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint *WatchCheckpoint
+}
+
+func (s *MemoryCheckpointStore) Load(ctx context.Context) (*WatchCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpoint == nil {
+		return nil, nil
+	}
+	checkpoint := *s.checkpoint
+	return &checkpoint, nil
+}
+
+func (s *MemoryCheckpointStore) Save(ctx context.Context, checkpoint WatchCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = &checkpoint
+	return nil
+}
+
+// FileCheckpointStore keeps the checkpoint in a JSON file.
+type FileCheckpointStore struct {
+	Path string
+}
+
+func (s FileCheckpointStore) Load(ctx context.Context) (*WatchCheckpoint, error) {
+	raw, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	checkpoint := &WatchCheckpoint{}
+	err = json.Unmarshal(raw, checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint '%s': %w", s.Path, err)
+	}
+	return checkpoint, nil
+}
+
+func (s FileCheckpointStore) Save(ctx context.Context, checkpoint WatchCheckpoint) error {
+	raw, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	// write and rename, a crash must not leave a truncated checkpoint
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// ChangeEvent is a change of a document. Every event must be acknowledged
+// via Ack once processed; the checkpoint only moves past a poll once all of
+// its events are acknowledged, which makes delivery at-least-once across
+// restarts.
+type ChangeEvent struct {
+	Type       ChangeType
+	DocumentID int
+	// Time of the change: added, modified, deleted or note creation time.
+	Time     time.Time
+	Document *Document
+	Note     *Notes
+	// History holds the audit log entries since the last checkpoint if
+	// WatchOptions.IncludeHistory is set (modifications only).
+	History []LogEntry
+
+	ack func()
+}
+
+// Ack confirms the event has been processed. Calling it more than once is
+// harmless.
+func (e ChangeEvent) Ack() {
+	if e.ack != nil {
+		e.ack()
+	}
+}
+
+type WatchOptions struct {
+	// Interval between polls, defaults to 30s.
+	Interval time.Duration
+	// Store defaults to a MemoryCheckpointStore.
+	Store CheckpointStore
+	// Since is used without stored checkpoint, defaults to the newest changes
+	// on the server, i.e. only future changes are reported.
+	Since *time.Time
+	// Types limits the reported change types, defaults to all.
+	Types []ChangeType
+	// IncludeHistory fetches the audit log entries of modified documents.
+	IncludeHistory bool
+	// OnError is called for failed polls, which are retried next interval.
+	OnError func(err error)
+}
+
+// Watch reports document changes by polling the documents (added__gt,
+// modified__gt) and the trash. The channel is closed once ctx is done.
+func (x XClient) Watch(ctx context.Context, opts WatchOptions) (<-chan ChangeEvent, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWatchInterval
+	}
+	if opts.Store == nil {
+		opts.Store = &MemoryCheckpointStore{}
+	}
+	checkpoint, err := opts.Store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil {
+		checkpoint, err = x.initialCheckpoint(ctx, opts.Since)
+		if err != nil {
+			return nil, err
+		}
+		err = opts.Store.Save(ctx, *checkpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+	wanted := make(map[ChangeType]bool)
+	for _, changeType := range opts.Types {
+		wanted[changeType] = true
+	}
+
+	events := make(chan ChangeEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			next, batch, err := x.pollChanges(ctx, *checkpoint, opts.IncludeHistory)
+			if err != nil && ctx.Err() == nil && opts.OnError != nil {
+				opts.OnError(err)
+			}
+			if err == nil && x.deliver(ctx, events, batch, wanted) {
+				if err := opts.Store.Save(ctx, next); err != nil {
+					if opts.OnError != nil {
+						opts.OnError(err)
+					}
+				} else {
+					checkpoint = &next
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return events, nil
+}
+
+// deliver sends the batch and waits for all acknowledgements. It returns
+// false if ctx ended before.
+func (x XClient) deliver(ctx context.Context, events chan<- ChangeEvent, batch []ChangeEvent, wanted map[ChangeType]bool) bool {
+	pending := sync.WaitGroup{}
+	for _, event := range batch {
+		if len(wanted) > 0 && !wanted[event.Type] {
+			continue
+		}
+		pending.Add(1)
+		once := sync.Once{}
+		event.ack = func() { once.Do(pending.Done) }
+		select {
+		case <-ctx.Done():
+			return false
+		case events <- event:
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-done:
+		return true
+	}
+}
+
+func (x XClient) initialCheckpoint(ctx context.Context, since *time.Time) (*WatchCheckpoint, error) {
+	if since != nil {
+		return &WatchCheckpoint{Added: *since, Modified: *since, Trashed: *since}, nil
+	}
+	// the clocks of client and server may differ, start at the server's
+	// newest changes
+	checkpoint := &WatchCheckpoint{}
+	for _, ordering := range []string{"-added", "-modified"} {
+		resp, err := x.DocumentsListWithResponse(ctx, &DocumentsListParams{
+			Ordering: P(ordering),
+			PageSize: P(1),
+			Fields:   []string{"id", "added", "modified"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("list documents", resp.StatusCode(), resp.Body)
+		}
+		for _, document := range resp.JSON200.Results {
+			if document.Added != nil && document.Added.After(checkpoint.Added) {
+				checkpoint.Added = *document.Added
+			}
+			if document.Modified != nil && document.Modified.After(checkpoint.Modified) {
+				checkpoint.Modified = *document.Modified
+			}
+		}
+	}
+	if x.Supports(FeatureTrash) {
+		trashed, err := x.ListTrash(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range trashed {
+			if document.DeletedAt != nil && document.DeletedAt.After(checkpoint.Trashed) {
+				checkpoint.Trashed = *document.DeletedAt
+			}
+		}
+	}
+	return checkpoint, nil
+}
+
+// pollChanges returns the changes after the checkpoint, ordered by time,
+// and the checkpoint after them.
+func (x XClient) pollChanges(ctx context.Context, checkpoint WatchCheckpoint, includeHistory bool) (WatchCheckpoint, []ChangeEvent, error) {
+	next := checkpoint
+	batch := make([]ChangeEvent, 0)
+
+	added, err := x.listDocuments(ctx, &DocumentsListParams{AddedGt: P(checkpoint.Added), Ordering: P("added")})
+	if err != nil {
+		return checkpoint, nil, err
+	}
+	addedIDs := make(map[int]bool)
+	for idx := range added {
+		document := &added[idx]
+		if document.Id == nil || document.Added == nil {
+			continue
+		}
+		addedIDs[*document.Id] = true
+		batch = append(batch, ChangeEvent{Type: DocumentAdded, DocumentID: *document.Id, Time: *document.Added, Document: document})
+		if document.Added.After(next.Added) {
+			next.Added = *document.Added
+		}
+	}
+
+	modified, err := x.listDocuments(ctx, &DocumentsListParams{ModifiedGt: P(checkpoint.Modified), Ordering: P("modified")})
+	if err != nil {
+		return checkpoint, nil, err
+	}
+	for idx := range modified {
+		document := &modified[idx]
+		if document.Modified != nil && document.Modified.After(next.Modified) {
+			next.Modified = *document.Modified
+		}
+		// adding notes touches modified as well
+		for noteIdx := range document.Notes {
+			note := &document.Notes[noteIdx]
+			if note.Created != nil && note.Created.After(checkpoint.Modified) {
+				batch = append(batch, ChangeEvent{Type: NoteAdded, DocumentID: *document.Id, Time: *note.Created, Document: document, Note: note})
+			}
+		}
+		if addedIDs[*document.Id] || document.Modified == nil {
+			continue
+		}
+		event := ChangeEvent{Type: DocumentModified, DocumentID: *document.Id, Time: *document.Modified, Document: document}
+		if includeHistory {
+			event.History, err = x.historySince(ctx, *document.Id, checkpoint.Modified)
+			if err != nil {
+				return checkpoint, nil, err
+			}
+		}
+		batch = append(batch, event)
+	}
+
+	if x.Supports(FeatureTrash) {
+		trashed, err := x.ListTrash(ctx)
+		if err != nil {
+			return checkpoint, nil, err
+		}
+		for idx := range trashed {
+			document := &trashed[idx]
+			if document.DeletedAt == nil || !document.DeletedAt.After(checkpoint.Trashed) {
+				continue
+			}
+			batch = append(batch, ChangeEvent{Type: DocumentTrashed, DocumentID: *document.Id, Time: *document.DeletedAt, Document: document})
+			if document.DeletedAt.After(next.Trashed) {
+				next.Trashed = *document.DeletedAt
+			}
+		}
+	}
+
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].Time.Before(batch[j].Time)
+	})
+	return next, batch, nil
+}
+
+func (x XClient) listDocuments(ctx context.Context, params *DocumentsListParams) ([]Document, error) {
+	output := make([]Document, 0)
+	params.PageSize = P(listPageSize)
+	for page := 1; ; page++ {
+		params.Page = P(page)
+		resp, err := x.DocumentsListWithResponse(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("list documents", resp.StatusCode(), resp.Body)
+		}
+		for _, document := range resp.JSON200.Results {
+			if document.Id != nil {
+				output = append(output, document)
+			}
+		}
+		if resp.JSON200.Next == nil {
+			return output, nil
+		}
+	}
+}
+
+func (x XClient) historySince(ctx context.Context, id int, since time.Time) ([]LogEntry, error) {
+	entries, err := x.listHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]LogEntry, 0)
+	for _, entry := range entries {
+		if entry.Timestamp.After(since) {
+			output = append(output, entry)
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,288 @@
+package paperless
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type WebhookEventType string
+
+const (
+	EventConsumptionStarted WebhookEventType = "consumption_started"
+	EventDocumentAdded      WebhookEventType = "document_added"
+	EventDocumentUpdated    WebhookEventType = "document_updated"
+	EventScheduled          WebhookEventType = "scheduled"
+)
+
+const (
+	DefaultWebhookSecretHeader string = "X-Paperless-Webhook-Secret"
+	// default limit of webhook bodies, include_document sends the file
+	defaultWebhookMaxBytes int64 = 64 << 20
+)
+
+var ErrWebhookUnauthorized = errors.New("invalid webhook secret")
+
+// paperless has no placeholder for the trigger, so the event type is sent
+// along as a fixed param
+var webhookEventParams = map[string]string{
+	"title":             "{doc_title}",
+	"doc_url":           "{doc_url}",
+	"correspondent":     "{correspondent}",
+	"document_type":     "{document_type}",
+	"owner":             "{owner_username}",
+	"original_filename": "{original_filename}",
+	"added":             "{added}",
+	"created":           "{created}",
+}
+
+// WebhookEvent configures a webhook action to send the params the
+// WebhookReceiver understands. Headers of other options are kept, except for
+// a DefaultWebhookSecretHeader, which the secret replaces. E.g.
+//
+//	paperless.NewWorkflow("notify").
+//		OnDocumentAdded().
+//		SendWebhook("https://erp.localdomain/paperless", paperless.WebhookEvent(paperless.EventDocumentAdded, secret))
+func WebhookEvent(event WebhookEventType, secret string) WebhookOption {
+	return func(w *WorkflowActionWebhookRequest) {
+		params := map[string]string{"event": string(event)}
+		for key, value := range webhookEventParams {
+			params[key] = value
+		}
+		w.UseParams = P(true)
+		w.AsJson = P(true)
+		w.Params = params
+		if secret != "" {
+			w.Headers = mergeWebhookMap(w.Headers, map[string]string{DefaultWebhookSecretHeader: secret})
+		}
+	}
+}
+
+// mergeWebhookMap adds values to the string map of a webhook request, which
+// the generated client only knows as interface{}.
+func mergeWebhookMap(existing interface{}, values map[string]string) map[string]string {
+	output := make(map[string]string)
+	if existing, ok := existing.(map[string]string); ok {
+		for key, value := range existing {
+			output[key] = value
+		}
+	}
+	for key, value := range values {
+		output[key] = value
+	}
+	return output
+}
+
+// ReceivedEvent is a decoded webhook call. Fields are empty if the workflow
+// did not send them.
+type ReceivedEvent struct {
+	Type             WebhookEventType
+	DocumentID       int
+	Title            string
+	DocumentURL      string
+	Correspondent    string
+	DocumentType     string
+	Owner            string
+	OriginalFilename string
+	Added            string
+	Created          string
+	// Params holds all params as sent, including custom ones.
+	Params map[string]string
+	// Body is the raw body of webhooks sending a body instead of params.
+	Body []byte
+	// Attachment is the document file of webhooks with include_document.
+	Attachment     []byte
+	AttachmentName string
+	// Document is fetched from the server if enrichment is enabled.
+	Document *Document
+}
+
+type WebhookCallback func(ctx context.Context, event ReceivedEvent) error
+
+type WebhookReceiverOptions struct {
+	// Secret expected in SecretHeader, empty disables the check.
+	Secret       string
+	SecretHeader string
+	// Client enables enrichment of events with the referenced document.
+	Client *XClient
+	// MaxBytes limits request bodies, defaults to 64MiB.
+	MaxBytes int64
+	// OnError is called for rejected calls and failing callbacks.
+	OnError func(r *http.Request, err error)
+}
+
+// WebhookReceiver is an http.Handler for webhooks sent by workflow actions.
+// Calls are answered with 204 once all callbacks succeeded, with 500
+// otherwise so the failure shows up in the paperless log.
+type WebhookReceiver struct {
+	opts      WebhookReceiverOptions
+	callbacks map[WebhookEventType][]WebhookCallback
+	any       []WebhookCallback
+}
+
+func NewWebhookReceiver(opts WebhookReceiverOptions) *WebhookReceiver {
+	if opts.SecretHeader == "" {
+		opts.SecretHeader = DefaultWebhookSecretHeader
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = defaultWebhookMaxBytes
+	}
+	return &WebhookReceiver{
+		opts:      opts,
+		callbacks: make(map[WebhookEventType][]WebhookCallback),
+	}
+}
+
+// On registers a callback for an event type. Callbacks must not be registered
+// while serving.
+func (r *WebhookReceiver) On(event WebhookEventType, callback WebhookCallback) *WebhookReceiver {
+	r.callbacks[event] = append(r.callbacks[event], callback)
+	return r
+}
+
+// OnAny registers a callback for all events, including unknown types.
+func (r *WebhookReceiver) OnAny(callback WebhookCallback) *WebhookReceiver {
+	r.any = append(r.any, callback)
+	return r
+}
+
+func (r *WebhookReceiver) fail(w http.ResponseWriter, req *http.Request, status int, err error) {
+	if r.opts.OnError != nil {
+		r.opts.OnError(req, err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (r *WebhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		r.fail(w, req, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		return
+	}
+	if r.opts.Secret != "" {
+		given := req.Header.Get(r.opts.SecretHeader)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(r.opts.Secret)) != 1 {
+			r.fail(w, req, http.StatusUnauthorized, ErrWebhookUnauthorized)
+			return
+		}
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, r.opts.MaxBytes)
+	event, err := DecodeWebhook(req)
+	if err != nil {
+		r.fail(w, req, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := req.Context()
+	if r.opts.Client != nil && event.DocumentID != 0 {
+		resp, err := r.opts.Client.DocumentsRetrieveWithResponse(ctx, event.DocumentID, &DocumentsRetrieveParams{})
+		if err != nil {
+			r.fail(w, req, http.StatusBadGateway, fmt.Errorf("failed to fetch document %d: %w", event.DocumentID, err))
+			return
+		}
+		if resp.JSON200 == nil {
+			r.fail(w, req, http.StatusBadGateway, apiError("fetch document", resp.StatusCode(), resp.Body))
+			return
+		}
+		event.Document = resp.JSON200
+	}
+
+	callbacks := append(append([]WebhookCallback{}, r.callbacks[event.Type]...), r.any...)
+	for _, callback := range callbacks {
+		if err := callback(ctx, *event); err != nil {
+			r.fail(w, req, http.StatusInternalServerError, fmt.Errorf("callback for %s failed: %w", event.Type, err))
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// paperless document urls end in /documents/<id>/details
+var documentURLPattern = regexp.MustCompile(`/documents/(\d+)(/|$)`)
+
+// DecodeWebhook decodes params sent as JSON or form data, or keeps the raw
+// body of webhooks configured with a body.
+func DecodeWebhook(req *http.Request) (*ReceivedEvent, error) {
+	event := &ReceivedEvent{Params: make(map[string]string)}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		err := req.ParseMultipartForm(8 << 20)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode multipart webhook: %w", err)
+		}
+		collectForm(event.Params, req.MultipartForm.Value)
+		for _, files := range req.MultipartForm.File {
+			if len(files) == 0 {
+				continue
+			}
+			file, err := files[0].Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attachment: %w", err)
+			}
+			event.Attachment, err = io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attachment: %w", err)
+			}
+			event.AttachmentName = files[0].Filename
+			break
+		}
+	case "application/x-www-form-urlencoded":
+		err := req.ParseForm()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode form webhook: %w", err)
+		}
+		collectForm(event.Params, req.PostForm)
+	default:
+		raw, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook: %w", err)
+		}
+		event.Body = raw
+		var params map[string]interface{}
+		if json.Unmarshal(raw, &params) == nil {
+			for key, value := range params {
+				if text, ok := value.(string); ok {
+					event.Params[key] = text
+				} else {
+					encoded, _ := json.Marshal(value)
+					event.Params[key] = string(encoded)
+				}
+			}
+		} else if mediaType == "application/json" {
+			return nil, fmt.Errorf("webhook body is no json object")
+		}
+	}
+
+	event.Type = WebhookEventType(event.Params["event"])
+	event.Title = event.Params["title"]
+	event.DocumentURL = event.Params["doc_url"]
+	event.Correspondent = event.Params["correspondent"]
+	event.DocumentType = event.Params["document_type"]
+	event.Owner = event.Params["owner"]
+	event.OriginalFilename = event.Params["original_filename"]
+	event.Added = event.Params["added"]
+	event.Created = event.Params["created"]
+	if id, err := strconv.Atoi(event.Params["doc_id"]); err == nil {
+		event.DocumentID = id
+	} else if match := documentURLPattern.FindStringSubmatch(event.DocumentURL); match != nil {
+		event.DocumentID, _ = strconv.Atoi(match[1])
+	}
+	return event, nil
+}
+
+func collectForm(params map[string]string, values url.Values) {
+	for key, list := range values {
+		params[key] = strings.Join(list, ",")
+	}
+}
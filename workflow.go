@@ -0,0 +1,580 @@
+package paperless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidWorkflow = errors.New("invalid workflow")
+
+const (
+	TriggerConsumption     WorkflowTriggerTypeEnum = WorkflowTriggerTypeEnumN1
+	TriggerDocumentAdded   WorkflowTriggerTypeEnum = WorkflowTriggerTypeEnumN2
+	TriggerDocumentUpdated WorkflowTriggerTypeEnum = WorkflowTriggerTypeEnumN3
+	TriggerScheduled       WorkflowTriggerTypeEnum = WorkflowTriggerTypeEnumN4
+)
+
+const (
+	SourceConsumeFolder SourcesEnum = SourcesEnumN1
+	SourceAPIUpload     SourcesEnum = SourcesEnumN2
+	SourceMailFetch     SourcesEnum = SourcesEnumN3
+	SourceWebUI         SourcesEnum = SourcesEnumN4
+)
+
+const (
+	ActionAssignment WorkflowActionTypeEnum = WorkflowActionTypeEnumN1
+	ActionRemoval    WorkflowActionTypeEnum = WorkflowActionTypeEnumN2
+	ActionEmail      WorkflowActionTypeEnum = WorkflowActionTypeEnumN3
+	ActionWebhook    WorkflowActionTypeEnum = WorkflowActionTypeEnumN4
+)
+
+const (
+	MatchNone    WorkflowTriggerMatchingAlgorithmEnum = WorkflowTriggerMatchingAlgorithmEnumN0
+	MatchAny     WorkflowTriggerMatchingAlgorithmEnum = WorkflowTriggerMatchingAlgorithmEnumN1
+	MatchAll     WorkflowTriggerMatchingAlgorithmEnum = WorkflowTriggerMatchingAlgorithmEnumN2
+	MatchLiteral WorkflowTriggerMatchingAlgorithmEnum = WorkflowTriggerMatchingAlgorithmEnumN3
+	MatchRegex   WorkflowTriggerMatchingAlgorithmEnum = WorkflowTriggerMatchingAlgorithmEnumN4
+	MatchFuzzy   WorkflowTriggerMatchingAlgorithmEnum = WorkflowTriggerMatchingAlgorithmEnumN5
+)
+
+// TriggerOption narrows down the documents a trigger fires for.
+type TriggerOption func(*WorkflowTriggerRequest)
+
+// Filename matches the original filename, wildcards like '*inv*' are allowed.
+func Filename(pattern string) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterFilename = P(pattern) }
+}
+
+// Path matches the path of consumed documents, wildcards are allowed.
+func Path(pattern string) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterPath = P(pattern) }
+}
+
+// FromMailRule limits consumption triggers to documents fetched by the rule.
+func FromMailRule(id int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterMailrule = P(id) }
+}
+
+// Sources limits consumption triggers to the given sources, defaults to all.
+func Sources(sources ...SourcesEnum) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.Sources = sources }
+}
+
+// Content matches the document content like the matching of tags does.
+func Content(algorithm WorkflowTriggerMatchingAlgorithmEnum, match string, insensitive bool) TriggerOption {
+	return func(t *WorkflowTriggerRequest) {
+		t.MatchingAlgorithm = P(algorithm)
+		t.Match = P(match)
+		t.IsInsensitive = P(insensitive)
+	}
+}
+
+func HasTags(ids ...int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterHasTags = ids }
+}
+
+func HasAllTags(ids ...int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterHasAllTags = ids }
+}
+
+func HasNotTags(ids ...int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterHasNotTags = ids }
+}
+
+func HasCorrespondent(id int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterHasCorrespondent = P(id) }
+}
+
+func HasDocumentType(id int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterHasDocumentType = P(id) }
+}
+
+func HasStoragePath(id int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.FilterHasStoragePath = P(id) }
+}
+
+// OffsetDays shifts scheduled triggers, negative values fire before the date.
+func OffsetDays(days int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) { t.ScheduleOffsetDays = P(days) }
+}
+
+// Recurring repeats scheduled triggers every interval days.
+func Recurring(intervalDays int) TriggerOption {
+	return func(t *WorkflowTriggerRequest) {
+		t.ScheduleIsRecurring = P(true)
+		t.ScheduleRecurringIntervalDays = P(intervalDays)
+	}
+}
+
+// WebhookOption configures what a webhook action sends.
+type WebhookOption func(*WorkflowActionWebhookRequest)
+
+// WebhookParams sends the params (placeholders allowed) instead of a body.
+func WebhookParams(params map[string]string) WebhookOption {
+	return func(w *WorkflowActionWebhookRequest) {
+		w.UseParams = P(true)
+		w.Params = params
+	}
+}
+
+// WebhookBody sends the body, placeholders allowed.
+func WebhookBody(body string) WebhookOption {
+	return func(w *WorkflowActionWebhookRequest) { w.Body = P(body) }
+}
+
+func WebhookHeaders(headers map[string]string) WebhookOption {
+	return func(w *WorkflowActionWebhookRequest) { w.Headers = headers }
+}
+
+// WebhookAsJSON sends params as JSON instead of form data.
+func WebhookAsJSON() WebhookOption {
+	return func(w *WorkflowActionWebhookRequest) { w.AsJson = P(true) }
+}
+
+func WebhookIncludeDocument() WebhookOption {
+	return func(w *WorkflowActionWebhookRequest) { w.IncludeDocument = P(true) }
+}
+
+// EmailOption configures an email action.
+type EmailOption func(*WorkflowActionEmailRequest)
+
+func EmailIncludeDocument() EmailOption {
+	return func(e *WorkflowActionEmailRequest) { e.IncludeDocument = P(true) }
+}
+
+// WorkflowBuilder assembles a workflow with its triggers and actions, e.g.
+//
+//	paperless.NewWorkflow("Invoices").
+//		OnConsumption(paperless.Filename("*inv*")).
+//		AssignTags(invoiceTagID).
+//		SetCustomField(amountFieldID, "EUR0.00").
+//		SendWebhook("https://hooks.localdomain/paperless").
+//		Email([]string{"accounting@localdomain"}, "New invoice", "{doc_title}")
+//
+// Consecutive assignments end up in one assignment action, consecutive
+// removals in one removal action.
+type WorkflowBuilder struct {
+	request WorkflowRequest
+}
+
+func NewWorkflow(name string) *WorkflowBuilder {
+	return &WorkflowBuilder{
+		request: WorkflowRequest{
+			Name:     name,
+			Triggers: make([]WorkflowTriggerRequest, 0),
+			Actions:  make([]WorkflowActionRequest, 0),
+		},
+	}
+}
+
+func (b *WorkflowBuilder) Order(order int) *WorkflowBuilder {
+	b.request.Order = P(order)
+	return b
+}
+
+func (b *WorkflowBuilder) Enabled(enabled bool) *WorkflowBuilder {
+	b.request.Enabled = P(enabled)
+	return b
+}
+
+func (b *WorkflowBuilder) trigger(triggerType WorkflowTriggerTypeEnum, opts []TriggerOption) *WorkflowTriggerRequest {
+	trigger := WorkflowTriggerRequest{Type: triggerType}
+	for _, opt := range opts {
+		opt(&trigger)
+	}
+	b.request.Triggers = append(b.request.Triggers, trigger)
+	return &b.request.Triggers[len(b.request.Triggers)-1]
+}
+
+// OnConsumption fires when consumption of a new document starts. At least one
+// of Filename, Path or FromMailRule is required.
+func (b *WorkflowBuilder) OnConsumption(opts ...TriggerOption) *WorkflowBuilder {
+	trigger := b.trigger(TriggerConsumption, opts)
+	if len(trigger.Sources) == 0 {
+		trigger.Sources = []SourcesEnum{SourceConsumeFolder, SourceAPIUpload, SourceMailFetch, SourceWebUI}
+	}
+	return b
+}
+
+// OnDocumentAdded fires after a document has been consumed.
+func (b *WorkflowBuilder) OnDocumentAdded(opts ...TriggerOption) *WorkflowBuilder {
+	b.trigger(TriggerDocumentAdded, opts)
+	return b
+}
+
+// OnDocumentUpdated fires whenever a document is saved.
+func (b *WorkflowBuilder) OnDocumentUpdated(opts ...TriggerOption) *WorkflowBuilder {
+	b.trigger(TriggerDocumentUpdated, opts)
+	return b
+}
+
+// OnSchedule fires relative to a date field of documents.
+func (b *WorkflowBuilder) OnSchedule(field ScheduleDateFieldEnum, opts ...TriggerOption) *WorkflowBuilder {
+	trigger := b.trigger(TriggerScheduled, opts)
+	trigger.ScheduleDateField = P(field)
+	return b
+}
+
+// OnCustomFieldDate fires relative to the date stored in a custom field.
+func (b *WorkflowBuilder) OnCustomFieldDate(fieldID int, opts ...TriggerOption) *WorkflowBuilder {
+	trigger := b.trigger(TriggerScheduled, opts)
+	trigger.ScheduleDateField = P(ScheduleDateFieldEnumCustomField)
+	trigger.ScheduleDateCustomField = P(fieldID)
+	return b
+}
+
+// action returns the last action if it has the given type, a new one
+// otherwise.
+func (b *WorkflowBuilder) action(actionType WorkflowActionTypeEnum) *WorkflowActionRequest {
+	if n := len(b.request.Actions); n > 0 && *b.request.Actions[n-1].Type == actionType {
+		return &b.request.Actions[n-1]
+	}
+	b.request.Actions = append(b.request.Actions, WorkflowActionRequest{Type: P(actionType)})
+	return &b.request.Actions[len(b.request.Actions)-1]
+}
+
+// AssignTitle sets the title, a Jinja2 template like '{{ correspondent }} {{ created }}'.
+func (b *WorkflowBuilder) AssignTitle(template string) *WorkflowBuilder {
+	b.action(ActionAssignment).AssignTitle = P(template)
+	return b
+}
+
+func (b *WorkflowBuilder) AssignTags(ids ...int) *WorkflowBuilder {
+	action := b.action(ActionAssignment)
+	action.AssignTags = append(action.AssignTags, ids...)
+	return b
+}
+
+func (b *WorkflowBuilder) AssignCorrespondent(id int) *WorkflowBuilder {
+	b.action(ActionAssignment).AssignCorrespondent = P(id)
+	return b
+}
+
+func (b *WorkflowBuilder) AssignDocumentType(id int) *WorkflowBuilder {
+	b.action(ActionAssignment).AssignDocumentType = P(id)
+	return b
+}
+
+func (b *WorkflowBuilder) AssignStoragePath(id int) *WorkflowBuilder {
+	b.action(ActionAssignment).AssignStoragePath = P(id)
+	return b
+}
+
+func (b *WorkflowBuilder) AssignOwner(id int) *WorkflowBuilder {
+	b.action(ActionAssignment).AssignOwner = P(id)
+	return b
+}
+
+// SetCustomField adds the custom field to documents, with value unless nil.
+func (b *WorkflowBuilder) SetCustomField(fieldID int, value interface{}) *WorkflowBuilder {
+	action := b.action(ActionAssignment)
+	action.AssignCustomFields = append(action.AssignCustomFields, fieldID)
+	if value == nil {
+		return b
+	}
+	values, _ := action.AssignCustomFieldsValues.(map[string]interface{})
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	values[strconv.Itoa(fieldID)] = value
+	action.AssignCustomFieldsValues = values
+	return b
+}
+
+func (b *WorkflowBuilder) RemoveTags(ids ...int) *WorkflowBuilder {
+	action := b.action(ActionRemoval)
+	action.RemoveTags = append(action.RemoveTags, ids...)
+	return b
+}
+
+func (b *WorkflowBuilder) RemoveAllTags() *WorkflowBuilder {
+	b.action(ActionRemoval).RemoveAllTags = P(true)
+	return b
+}
+
+func (b *WorkflowBuilder) RemoveCorrespondents(ids ...int) *WorkflowBuilder {
+	action := b.action(ActionRemoval)
+	action.RemoveCorrespondents = append(action.RemoveCorrespondents, ids...)
+	return b
+}
+
+func (b *WorkflowBuilder) RemoveDocumentTypes(ids ...int) *WorkflowBuilder {
+	action := b.action(ActionRemoval)
+	action.RemoveDocumentTypes = append(action.RemoveDocumentTypes, ids...)
+	return b
+}
+
+func (b *WorkflowBuilder) RemoveCustomFields(ids ...int) *WorkflowBuilder {
+	action := b.action(ActionRemoval)
+	action.RemoveCustomFields = append(action.RemoveCustomFields, ids...)
+	return b
+}
+
+// SendWebhook adds a webhook action, placeholders like '{doc_url}' are
+// allowed in params and body.
+func (b *WorkflowBuilder) SendWebhook(url string, opts ...WebhookOption) *WorkflowBuilder {
+	webhook := &WorkflowActionWebhookRequest{Url: url}
+	for _, opt := range opts {
+		opt(webhook)
+	}
+	b.request.Actions = append(b.request.Actions, WorkflowActionRequest{
+		Type:    P(ActionWebhook),
+		Webhook: webhook,
+	})
+	return b
+}
+
+// Email adds an email action, placeholders like '{doc_title}' are allowed in
+// subject and body.
+func (b *WorkflowBuilder) Email(to []string, subject, body string, opts ...EmailOption) *WorkflowBuilder {
+	email := &WorkflowActionEmailRequest{
+		To:      strings.Join(to, ","),
+		Subject: subject,
+		Body:    body,
+	}
+	for _, opt := range opts {
+		opt(email)
+	}
+	b.request.Actions = append(b.request.Actions, WorkflowActionRequest{
+		Type:  P(ActionEmail),
+		Email: email,
+	})
+	return b
+}
+
+// Build validates the workflow and returns the request. Errors wrap
+// ErrInvalidWorkflow.
+func (b *WorkflowBuilder) Build() (WorkflowRequest, error) {
+	problems := make([]error, 0)
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+	if strings.TrimSpace(b.request.Name) == "" {
+		invalid("missing name")
+	}
+	if len(b.request.Triggers) == 0 {
+		invalid("at least one trigger required")
+	}
+	if len(b.request.Actions) == 0 {
+		invalid("at least one action required")
+	}
+
+	for idx, trigger := range b.request.Triggers {
+		location := fmt.Sprintf("trigger %d", idx+1)
+		if trigger.Type == TriggerConsumption {
+			if trigger.FilterFilename == nil && trigger.FilterPath == nil && trigger.FilterMailrule == nil {
+				invalid("%s: consumption triggers require a filename, path or mail rule filter", location)
+			}
+			if trigger.MatchingAlgorithm != nil || trigger.FilterHasTags != nil || trigger.FilterHasAllTags != nil ||
+				trigger.FilterHasNotTags != nil || trigger.FilterHasCorrespondent != nil ||
+				trigger.FilterHasDocumentType != nil || trigger.FilterHasStoragePath != nil {
+				invalid("%s: documents have no content, tags or metadata before consumption", location)
+			}
+		} else {
+			if trigger.FilterMailrule != nil || trigger.FilterPath != nil {
+				invalid("%s: path and mail rule filters only apply to consumption triggers", location)
+			}
+			if trigger.Sources != nil {
+				invalid("%s: sources only apply to consumption triggers", location)
+			}
+		}
+		if trigger.MatchingAlgorithm != nil {
+			algorithm := *trigger.MatchingAlgorithm
+			if algorithm < MatchNone || algorithm > MatchFuzzy {
+				invalid("%s: unknown matching algorithm %d", location, algorithm)
+			}
+			if algorithm != MatchNone && (trigger.Match == nil || *trigger.Match == "") {
+				invalid("%s: matching algorithm %d requires a match", location, algorithm)
+			}
+		}
+		if trigger.Type != TriggerScheduled && (trigger.ScheduleOffsetDays != nil || trigger.ScheduleIsRecurring != nil) {
+			invalid("%s: offset and recurrence only apply to scheduled triggers", location)
+		}
+		if trigger.ScheduleRecurringIntervalDays != nil && *trigger.ScheduleRecurringIntervalDays < 1 {
+			invalid("%s: recurring interval must be at least one day", location)
+		}
+	}
+
+	for idx, action := range b.request.Actions {
+		location := fmt.Sprintf("action %d", idx+1)
+		switch *action.Type {
+		case ActionEmail:
+			if action.Email.To == "" || action.Email.Subject == "" || action.Email.Body == "" {
+				invalid("%s: email requires recipients, subject and body", location)
+			}
+		case ActionWebhook:
+			if action.Webhook.Url == "" {
+				invalid("%s: webhook requires an url", location)
+			}
+			if action.Webhook.UseParams != nil && *action.Webhook.UseParams && action.Webhook.Body != nil {
+				invalid("%s: webhook sends either params or a body", location)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return WorkflowRequest{}, fmt.Errorf("%w '%s': %w", ErrInvalidWorkflow, b.request.Name, errors.Join(problems...))
+	}
+	return b.request, nil
+}
+
+// requiredFeatures lists the server features the workflow relies on.
+func (b *WorkflowBuilder) requiredFeatures() []Feature {
+	output := []Feature{FeatureWorkflows}
+	for _, action := range b.request.Actions {
+		switch *action.Type {
+		case ActionEmail:
+			output = append(output, FeatureWorkflowEmailAction)
+		case ActionWebhook:
+			output = append(output, FeatureWorkflowWebhookAction)
+		}
+	}
+	return output
+}
+
+// SaveWorkflow creates the workflow or replaces the triggers and actions of
+// the existing workflow with the same name. Triggers and actions are created
+// first; if anything fails the ones created so far are deleted again and
+// errors of that rollback are joined to the original error. If only deleting
+// the replaced triggers and actions fails, the saved workflow is returned
+// together with that error.
+func (x XClient) SaveWorkflow(ctx context.Context, builder *WorkflowBuilder) (*Workflow, error) {
+	request, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	for _, feature := range builder.requiredFeatures() {
+		err = x.RequireFeature(feature)
+		if err != nil {
+			return nil, err
+		}
+	}
+	existing, err := x.findWorkflow(ctx, request.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdTriggers, createdActions []int
+	rollback := func(cause error) error {
+		// best effort, the context may be the reason we failed
+		cleanupCtx := context.WithoutCancel(ctx)
+		for _, id := range createdTriggers {
+			if err := x.destroyWorkflowTrigger(cleanupCtx, id); err != nil {
+				cause = errors.Join(cause, fmt.Errorf("failed to roll back trigger %d: %w", id, err))
+			}
+		}
+		for _, id := range createdActions {
+			if err := x.destroyWorkflowAction(cleanupCtx, id); err != nil {
+				cause = errors.Join(cause, fmt.Errorf("failed to roll back action %d: %w", id, err))
+			}
+		}
+		return cause
+	}
+
+	for idx, trigger := range request.Triggers {
+		resp, err := x.WorkflowTriggersCreateWithResponse(ctx, trigger)
+		if err != nil {
+			return nil, rollback(fmt.Errorf("failed to create trigger: %w", err))
+		}
+		if resp.JSON201 == nil || resp.JSON201.Id == nil {
+			return nil, rollback(apiError("create workflow trigger", resp.StatusCode(), resp.Body))
+		}
+		createdTriggers = append(createdTriggers, *resp.JSON201.Id)
+		request.Triggers[idx].Id = resp.JSON201.Id
+	}
+	for idx, action := range request.Actions {
+		resp, err := x.WorkflowActionsCreateWithResponse(ctx, action)
+		if err != nil {
+			return nil, rollback(fmt.Errorf("failed to create action: %w", err))
+		}
+		if resp.JSON201 == nil || resp.JSON201.Id == nil {
+			return nil, rollback(apiError("create workflow action", resp.StatusCode(), resp.Body))
+		}
+		createdActions = append(createdActions, *resp.JSON201.Id)
+		request.Actions[idx].Id = resp.JSON201.Id
+	}
+
+	if existing == nil {
+		resp, err := x.WorkflowsCreateWithResponse(ctx, request)
+		if err != nil {
+			return nil, rollback(fmt.Errorf("failed to create workflow: %w", err))
+		}
+		if resp.JSON201 == nil {
+			return nil, rollback(apiError("create workflow", resp.StatusCode(), resp.Body))
+		}
+		return resp.JSON201, nil
+	}
+
+	resp, err := x.WorkflowsUpdateWithResponse(ctx, *existing.Id, request)
+	if err != nil {
+		return nil, rollback(fmt.Errorf("failed to update workflow: %w", err))
+	}
+	if resp.JSON200 == nil {
+		return nil, rollback(apiError("update workflow", resp.StatusCode(), resp.Body))
+	}
+	// the replaced triggers and actions are orphans now
+	var cleanup error
+	for _, trigger := range existing.Triggers {
+		if trigger.Id == nil {
+			continue
+		}
+		if err := x.destroyWorkflowTrigger(ctx, *trigger.Id); err != nil {
+			cleanup = errors.Join(cleanup, fmt.Errorf("failed to delete replaced trigger %d: %w", *trigger.Id, err))
+		}
+	}
+	for _, action := range existing.Actions {
+		if action.Id == nil {
+			continue
+		}
+		if err := x.destroyWorkflowAction(ctx, *action.Id); err != nil {
+			cleanup = errors.Join(cleanup, fmt.Errorf("failed to delete replaced action %d: %w", *action.Id, err))
+		}
+	}
+	return resp.JSON200, cleanup
+}
+
+func (x XClient) destroyWorkflowTrigger(ctx context.Context, id int) error {
+	resp, err := x.WorkflowTriggersDestroyWithResponse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent && resp.StatusCode() != http.StatusNotFound {
+		return apiError("delete workflow trigger", resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+func (x XClient) destroyWorkflowAction(ctx context.Context, id int) error {
+	resp, err := x.WorkflowActionsDestroyWithResponse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent && resp.StatusCode() != http.StatusNotFound {
+		return apiError("delete workflow action", resp.StatusCode(), resp.Body)
+	}
+	return nil
+}
+
+func (x XClient) findWorkflow(ctx context.Context, name string) (*Workflow, error) {
+	for page := 1; ; page++ {
+		resp, err := x.WorkflowsListWithResponse(ctx, &WorkflowsListParams{Page: P(page), PageSize: P(listPageSize)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflows: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, apiError("list workflows", resp.StatusCode(), resp.Body)
+		}
+		for _, workflow := range resp.JSON200.Results {
+			if workflow.Name == name {
+				return &workflow, nil
+			}
+		}
+		if resp.JSON200.Next == nil {
+			return nil, nil
+		}
+	}
+}
@@ -6,17 +6,43 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type XClient struct {
 	ClientWithResponsesInterface
+	// kept for requests to endpoints missing from the spec
+	endpoint   string
+	httpClient HttpRequestDoer
+	reqEditors []RequestEditorFn
+	server     *serverInfoStore
+	session    *statusSession
 }
 
 func NewXClient(endpoint string, reqEditors ...RequestEditorFn) (XClient, error) {
+	return NewXClientWithHTTPClient(endpoint, nil, reqEditors...)
+}
+
+// NewXClientWithHTTPClient creates a client which sends its requests through
+// httpClient, e.g. a *http.Client with a custom http.RoundTripper such as the
+// Recorder. A nil httpClient falls back to the default client.
+func NewXClientWithHTTPClient(endpoint string, httpClient HttpRequestDoer, reqEditors ...RequestEditorFn) (XClient, error) {
+	return newXClient(endpoint, httpClient, newServerInfoStore(), reqEditors...)
+}
+
+func newXClient(endpoint string, httpClient HttpRequestDoer, server *serverInfoStore, reqEditors ...RequestEditorFn) (XClient, error) {
 	var opts []ClientOption
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	httpClient = versionTrackingDoer{next: httpClient, store: server}
+	// last, so the negotiated version wins over explicit ones
+	reqEditors = append(reqEditors[:len(reqEditors):len(reqEditors)], server.negotiatedEditor())
+	opts = append(opts, WithHTTPClient(httpClient))
 	for _, editorFn := range reqEditors {
 		opts = append(opts, WithRequestEditorFn(editorFn))
 	}
@@ -28,22 +54,37 @@ func NewXClient(endpoint string, reqEditors ...RequestEditorFn) (XClient, error)
 		return XClient{}, fmt.Errorf("could not create client: %w", err)
 	}
 	return XClient{
-		client,
+		ClientWithResponsesInterface: client,
+		endpoint:                     strings.TrimSuffix(endpoint, "/"),
+		httpClient:                   httpClient,
+		reqEditors:                   reqEditors,
+		server:                       server,
+		session:                      &statusSession{},
 	}, nil
 }
 
+// NewXClientWithToken creates a client requesting the API version picked by
+// NegotiateVersion, the version the client was generated for until then.
 func NewXClientWithToken(endpoint, token string) (XClient, error) {
-	return NewXClient(
+	server := newServerInfoStore()
+	return newXClient(
 		endpoint,
-		MakeAPIVersionRequestEditor(defaultAPIVersion),
+		nil,
+		server,
+		server.requestEditor(),
 		MakeTokenAuthRequestEditor(token),
 	)
 }
 
+// NewXClientWithCredentials creates a client requesting the API version picked
+// by NegotiateVersion, the version the client was generated for until then.
 func NewXClientWithCredentials(endpoint, user, password string) (XClient, error) {
-	return NewXClient(
+	server := newServerInfoStore()
+	return newXClient(
 		endpoint,
-		MakeAPIVersionRequestEditor(defaultAPIVersion),
+		nil,
+		server,
+		server.requestEditor(),
 		MakeBasicAuthRequestEditor(user, password),
 	)
 }
@@ -52,6 +93,7 @@ type DocumentCreate struct {
 	Title               *string    `json:"title,omitempty"`
 	Created             *time.Time `json:"created,omitempty"`
 	DocumentType        *int       `json:"document_type,omitempty"`
+	Correspondent       *int       `json:"correspondent,omitempty"`
 	StoragePath         *int       `json:"storage_path,omitempty"`
 	Tags                []string   `json:"tags,omitempty"`
 	ArchiveSerialNumber *int       `json:"archive_serial_number,omitempty"`
@@ -68,6 +110,9 @@ func (d *DocumentCreate) Params() map[string]interface{} {
 	if d != nil && d.DocumentType != nil {
 		output["document_type"] = fmt.Sprintf("%d", *d.DocumentType)
 	}
+	if d != nil && d.Correspondent != nil {
+		output["correspondent"] = fmt.Sprintf("%d", *d.Correspondent)
+	}
 	if d != nil && d.StoragePath != nil {
 		output["storage_path"] = fmt.Sprintf("%d", *d.StoragePath)
 	}
@@ -89,7 +134,7 @@ func (x XClient) DocumentsPostDocumentCreateWithBodyWithResponse(
 	optionalData *DocumentCreate,
 	reqEditors ...RequestEditorFn,
 ) (*DocumentsPostDocumentCreateHTTPResponse, error) {
-	body, contentType, err := newFileMultipartBody("document", fullFilepath, optionalData.Params())
+	body, contentType, err := newFileMultipartBody("document", fullFilepath, "", optionalData.Params())
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +146,9 @@ func (x XClient) DocumentsPostDocumentCreateWithBodyWithResponse(
 	)
 }
 
-func newFileMultipartBody(paramName, path string, params map[string]interface{}) (io.Reader, string, error) {
+// newFileMultipartBody sends the file as filename, which defaults to the name
+// of the file.
+func newFileMultipartBody(paramName, path, filename string, params map[string]interface{}) (io.Reader, string, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, "", err
@@ -115,10 +162,13 @@ func newFileMultipartBody(paramName, path string, params map[string]interface{})
 		return nil, "", err
 	}
 	file.Close()
+	if filename == "" {
+		filename = fi.Name()
+	}
 
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(paramName, fi.Name())
+	part, err := writer.CreateFormFile(paramName, filename)
 	if err != nil {
 		return nil, "", err
 	}
@@ -200,7 +250,12 @@ func (x XClient) FetchTask(ctx context.Context, taskID string) (*TasksView, erro
 	return nil, fmt.Errorf("task not found")
 }
 
+// WaitForTask waits for the task via the status websocket, falling back to
+// polling every pollInterval (at least 2s) if the websocket is unavailable.
 func (x XClient) WaitForTask(ctx context.Context, taskID string, pollInterval time.Duration) error {
+	if finished, err := x.waitForTaskStatus(ctx, taskID); finished {
+		return err
+	}
 	if pollInterval < 2000*time.Millisecond {
 		pollInterval = 2000 * time.Millisecond
 	}
@@ -227,6 +282,36 @@ func (x XClient) WaitForTask(ctx context.Context, taskID string, pollInterval ti
 	}
 }
 
+// WaitForTaskDocument waits for a consumption task and returns the ID of the
+// document it created. The websocket reports success before paperless stores
+// the document on the task, so the task is polled until it has one.
+func (x XClient) WaitForTaskDocument(ctx context.Context, taskID string, pollInterval time.Duration) (int, error) {
+	err := x.WaitForTask(ctx, taskID, pollInterval)
+	if err != nil {
+		return 0, err
+	}
+	if pollInterval < 2000*time.Millisecond {
+		pollInterval = 2000 * time.Millisecond
+	}
+	task, err := x.FetchTask(ctx, taskID)
+	for err == nil && task.RelatedDocument == nil {
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("task with id '%s' has no related document", taskID)
+		case <-time.After(pollInterval):
+		}
+		task, err = x.FetchTask(ctx, taskID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(*task.RelatedDocument)
+	if err != nil {
+		return 0, fmt.Errorf("task with id '%s' has invalid related document '%s'", taskID, *task.RelatedDocument)
+	}
+	return id, nil
+}
+
 func (x XClient) GetAllDocuments(ctx context.Context) ([]Document, error) {
 	docResp, err := x.DocumentsListWithResponse(ctx, &DocumentsListParams{
 		PageSize: P(9999999),
@@ -239,3 +324,21 @@ func (x XClient) GetAllDocuments(ctx context.Context) ([]Document, error) {
 	}
 	return docResp.JSON200.Results, nil
 }
+
+// newRawRequest creates a request to an endpoint the generated client does
+// not know about, with all request editors of the client applied.
+func (x XClient) newRawRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	if x.httpClient == nil {
+		return nil, fmt.Errorf("client not created via NewXClient")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, x.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, editorFn := range x.reqEditors {
+		if err := editorFn(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}